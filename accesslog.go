@@ -0,0 +1,255 @@
+package wine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopub/environ"
+	"github.com/gopub/types"
+	"github.com/gopub/wine/httpvalue"
+)
+
+const (
+	maxLoggedErrorSize = 2048
+	redactedValue      = "***"
+	clfTimeLayout      = "02/Jan/2006:15:04:05 -0700"
+)
+
+// AccessLog is a structured record of a handled request
+type AccessLog struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id,omitempty"`
+	TraceID    string    `json:"trace_id,omitempty"`
+	UserID     int64     `json:"user_id,omitempty"`
+	RemoteAddr string    `json:"remote_addr"`
+	Method     string    `json:"method"`
+	// Route is the path pattern of matched endpoint, e.g. /items/{id}. It's empty if no endpoint is matched
+	Route string `json:"route,omitempty"`
+	// Path is url path without query
+	Path      string        `json:"path"`
+	Endpoint  string        `json:"endpoint,omitempty"`
+	Proto     string        `json:"proto"`
+	Status    int           `json:"status"`
+	BytesIn   int64         `json:"bytes_in"`
+	BytesOut  int64         `json:"bytes_out"`
+	Latency   time.Duration `json:"-"`
+	Referer   string        `json:"referer,omitempty"`
+	UserAgent string        `json:"user_agent,omitempty"`
+	// Params and Error are only logged for failed requests of non-sensitive endpoints
+	Params   types.M `json:"params,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Redacted bool    `json:"redacted,omitempty"`
+}
+
+// AccessLogFormatter formats access logs, e.g. JSON, Apache Combined Log Format
+type AccessLogFormatter interface {
+	FormatAccessLog(l *AccessLog) []byte
+}
+
+type AccessLogFormatterFunc func(l *AccessLog) []byte
+
+func (f AccessLogFormatterFunc) FormatAccessLog(l *AccessLog) []byte {
+	return f(l)
+}
+
+var (
+	JSONAccessLogFormatter     AccessLogFormatter = AccessLogFormatterFunc(formatJSONAccessLog)
+	CommonAccessLogFormatter   AccessLogFormatter = AccessLogFormatterFunc(formatCommonAccessLog)
+	CombinedAccessLogFormatter AccessLogFormatter = AccessLogFormatterFunc(formatCombinedAccessLog)
+)
+
+// GetAccessLogFormatter returns built-in formatter by name: json, common or combined
+func GetAccessLogFormatter(name string) AccessLogFormatter {
+	switch strings.ToLower(name) {
+	case "json":
+		return JSONAccessLogFormatter
+	case "common", "clf":
+		return CommonAccessLogFormatter
+	case "combined":
+		return CombinedAccessLogFormatter
+	default:
+		return nil
+	}
+}
+
+func formatJSONAccessLog(l *AccessLog) []byte {
+	b, err := json.Marshal(struct {
+		*AccessLog
+		LatencyMS float64 `json:"latency_ms"`
+	}{
+		AccessLog: l,
+		LatencyMS: float64(l.Latency.Microseconds()) / 1000,
+	})
+	if err != nil {
+		logger.Errorf("Marshal access log: %v", err)
+		return nil
+	}
+	return b
+}
+
+// formatCommonAccessLog formats l in Apache Common Log Format, with route pattern in place of request uri
+func formatCommonAccessLog(l *AccessLog) []byte {
+	host := l.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	user := "-"
+	if l.UserID > 0 {
+		user = strconv.FormatInt(l.UserID, 10)
+	}
+	uri := l.Route
+	if uri == "" {
+		uri = l.Path
+	}
+	size := "-"
+	if l.BytesOut > 0 {
+		size = strconv.FormatInt(l.BytesOut, 10)
+	}
+	return []byte(fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %s`,
+		clfValue(host), user, l.Time.Format(clfTimeLayout), l.Method, uri, l.Proto, l.Status, size))
+}
+
+// formatCombinedAccessLog formats l in Apache Combined Log Format, with route pattern in place of request uri
+func formatCombinedAccessLog(l *AccessLog) []byte {
+	return []byte(fmt.Sprintf(`%s "%s" "%s"`, formatCommonAccessLog(l), clfValue(l.Referer), clfValue(l.UserAgent)))
+}
+
+func clfValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(s, `"`, `\"`)
+}
+
+// AccessLogger writes access logs into an io.Writer
+type AccessLogger struct {
+	mu        sync.Mutex
+	w         io.Writer
+	formatter AccessLogFormatter
+
+	// SampleRate is the fraction of successful requests to be logged, from 0 to 1. Failed requests are always logged
+	SampleRate float64
+	// RedactedParams are params whose values are replaced in logs, case-insensitive
+	RedactedParams []string
+}
+
+// NewAccessLogger returns an access logger which logs all requests
+func NewAccessLogger(w io.Writer, formatter AccessLogFormatter) *AccessLogger {
+	if formatter == nil {
+		formatter = JSONAccessLogFormatter
+	}
+	return &AccessLogger{
+		w:              w,
+		formatter:      formatter,
+		SampleRate:     1,
+		RedactedParams: []string{"password", "token", "secret"},
+	}
+}
+
+// Log writes l
+func (l *AccessLogger) Log(entry *AccessLog) {
+	b := l.formatter.FormatAccessLog(entry)
+	if len(b) == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(append(b, '\n')); err != nil {
+		logger.Errorf("Write access log: %v", err)
+	}
+}
+
+func (l *AccessLogger) sample(status int) bool {
+	if status >= http.StatusBadRequest || l.SampleRate >= 1 {
+		return true
+	}
+	return rand.Float64() < l.SampleRate
+}
+
+func (l *AccessLogger) redact(params types.M) types.M {
+	m := make(types.M, len(params))
+	for k, v := range params {
+		m[k] = v
+		for _, r := range l.RedactedParams {
+			if strings.EqualFold(k, r) {
+				m[k] = redactedValue
+				break
+			}
+		}
+	}
+	return m
+}
+
+func newAccessLoggerFromEnv() *AccessLogger {
+	name := environ.String("wine.access_log.format", "")
+	if name == "" {
+		return nil
+	}
+	f := GetAccessLogFormatter(name)
+	if f == nil {
+		logger.Errorf("Unsupported access log format: %s", name)
+		return nil
+	}
+	return NewAccessLogger(os.Stdout, f)
+}
+
+func (s *Server) logAccess(req *Request, res *Result, startAt time.Time) {
+	l := s.AccessLogger
+	if l == nil || !l.sample(res.Status) {
+		return
+	}
+
+	r := req.request
+	entry := &AccessLog{
+		Time:       startAt,
+		RequestID:  r.Header.Get(httpvalue.RequestID),
+		UserID:     req.uid,
+		RemoteAddr: r.RemoteAddr,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Proto:      r.Proto,
+		Status:     res.Status,
+		BytesIn:    int64(len(req.body)),
+		BytesOut:   res.BytesOut,
+		Latency:    time.Since(startAt),
+		Referer:    r.Referer(),
+		UserAgent:  r.UserAgent(),
+	}
+	if id := legacyTraceID(r.Header); id != "" {
+		entry.TraceID = id
+	} else if req.span != nil {
+		entry.TraceID = req.span.Context().TraceID.String()
+	}
+	if entry.BytesIn == 0 && r.ContentLength > 0 {
+		entry.BytesIn = r.ContentLength
+	}
+	if e := req.endpoint; e != nil {
+		entry.Route = "/" + e.Path()
+		entry.Endpoint = e.Name()
+	}
+
+	if res.Status >= http.StatusBadRequest {
+		if req.sensitive {
+			entry.Redacted = true
+		} else {
+			if len(req.params) > 0 {
+				entry.Params = l.redact(req.params)
+			}
+			if len(res.Body) > maxLoggedErrorSize {
+				entry.Error = string(res.Body[:maxLoggedErrorSize])
+			} else {
+				entry.Error = string(res.Body)
+			}
+		}
+	}
+	l.Log(entry)
+}
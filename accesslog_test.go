@@ -0,0 +1,122 @@
+package wine_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine"
+	"github.com/stretchr/testify/require"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Split(strings.TrimSpace(b.buf.String()), "\n")
+}
+
+func TestAccessLogger(t *testing.T) {
+	s := wine.NewTestServer(t)
+	buf := new(syncBuffer)
+	s.AccessLogger = wine.NewAccessLogger(buf, wine.JSONAccessLogFormatter)
+	s.Get("items/{id}", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.Error(errors.NotFound("no item"))
+	}).SetName("GetItem")
+	s.Post("login", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.Status(http.StatusUnauthorized)
+	}).SetSensitive(true)
+	u := s.Run()
+
+	readLog := func(t *testing.T) *wine.AccessLog {
+		var lines []string
+		require.Eventually(t, func() bool {
+			lines = buf.Lines()
+			return lines[0] != ""
+		}, time.Second, 10*time.Millisecond)
+		buf.mu.Lock()
+		buf.buf.Reset()
+		buf.mu.Unlock()
+		require.Len(t, lines, 1)
+		var l wine.AccessLog
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &l))
+		return &l
+	}
+
+	t.Run("Failure", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, u+"/items/1?password=123&q=abc", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Request-Id", "req-1")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		l := readLog(t)
+		require.Equal(t, http.StatusNotFound, l.Status)
+		require.Equal(t, "/items/{id}", l.Route)
+		require.Equal(t, "GetItem", l.Endpoint)
+		require.Equal(t, "req-1", l.RequestID)
+		require.Equal(t, "***", l.Params["password"])
+		require.Equal(t, "abc", l.Params["q"])
+		require.Contains(t, l.Error, "no item")
+		require.NotZero(t, l.BytesOut)
+	})
+
+	t.Run("LegacyTraceID", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, u+"/items/1", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Wine-Trace-Id", "5f1c2f1e-3b0a-4c1e-9a57-6b1f7e0a9d2c")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		l := readLog(t)
+		require.Equal(t, "5f1c2f1e-3b0a-4c1e-9a57-6b1f7e0a9d2c", l.TraceID)
+	})
+
+	t.Run("Sensitive", func(t *testing.T) {
+		resp, err := http.PostForm(u+"/login", url.Values{"account": {"tom"}})
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		l := readLog(t)
+		require.Equal(t, http.StatusUnauthorized, l.Status)
+		require.True(t, l.Redacted)
+		require.Empty(t, l.Params)
+		require.NotZero(t, l.BytesIn)
+	})
+}
+
+func TestCombinedAccessLogFormatter(t *testing.T) {
+	l := &wine.AccessLog{
+		Time:       time.Date(2021, 5, 1, 8, 30, 0, 0, time.UTC),
+		UserID:     7,
+		RemoteAddr: "10.0.0.1:5678",
+		Method:     http.MethodGet,
+		Route:      "/items/{id}",
+		Path:       "/items/1",
+		Proto:      "HTTP/1.1",
+		Status:     http.StatusOK,
+		BytesOut:   12,
+		UserAgent:  "curl",
+	}
+	b := wine.CombinedAccessLogFormatter.FormatAccessLog(l)
+	require.Equal(t, `10.0.0.1 - 7 [01/May/2021:08:30:00 +0000] "GET /items/{id} HTTP/1.1" 200 12 "-" "curl"`, string(b))
+}
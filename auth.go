@@ -2,15 +2,25 @@ package wine
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/base64"
+	"fmt"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gopub/wine/ctxutil"
+	"github.com/gopub/wine/httpvalue"
 	"github.com/gopub/wine/internal/respond"
+	"github.com/gopub/wine/jwt"
+	"golang.org/x/crypto/bcrypt"
 )
 
-// NewBasicAuthHandler returns a basic auth interceptor
+const lockoutSweepInterval = time.Minute
+
+// NewBasicAuthHandler returns a basic auth interceptor with plaintext passwords.
+// Use NewHashedBasicAuthHandler to avoid keeping plaintext passwords
 func NewBasicAuthHandler(userToPassword map[string]string, realm string) HandlerFunc {
 	if len(userToPassword) == 0 {
 		logger.Panic("userToPassword is empty")
@@ -28,7 +38,7 @@ func NewBasicAuthHandler(userToPassword map[string]string, realm string) Handler
 	return func(ctx context.Context, req *Request) Responder {
 		a := req.Authorization()
 		for user, auth := range userToAuthorization {
-			if auth == a {
+			if subtle.ConstantTimeCompare([]byte(auth), []byte(a)) == 1 {
 				ctx = ctxutil.WithBasicUser(ctx, user)
 				return Next(ctx, req)
 			}
@@ -37,6 +47,146 @@ func NewBasicAuthHandler(userToPassword map[string]string, realm string) Handler
 	}
 }
 
+// BasicAuthOptions configures NewHashedBasicAuthHandler
+type BasicAuthOptions struct {
+	Realm string
+	// MaxAttempts is the number of consecutive failures of a user from an ip before being locked out.
+	// There is no lockout if it's not positive
+	MaxAttempts     int
+	LockoutDuration time.Duration
+}
+
+// NewHashedBasicAuthHandler returns a basic auth interceptor which verifies passwords against hashes in store.
+// Authenticated user is set in context by ctxutil.WithBasicUser
+func NewHashedBasicAuthHandler(store CredentialStore, options *BasicAuthOptions) HandlerFunc {
+	if store == nil {
+		logger.Panic("Credential store cannot be nil")
+	}
+	if options == nil {
+		options = &BasicAuthOptions{
+			MaxAttempts:     5,
+			LockoutDuration: 15 * time.Minute,
+		}
+	}
+	l := newLockout(options.MaxAttempts, options.LockoutDuration)
+	return func(ctx context.Context, req *Request) Responder {
+		user, password := req.BasicAccount()
+		if user == "" {
+			return RequireBasicAuth(options.Realm)
+		}
+		key := user + "|" + remoteIP(req.request.RemoteAddr)
+		if d := l.lockedFor(key); d > 0 {
+			header := make(http.Header)
+			header.Set(httpvalue.RetryAfter, ceilSeconds(d))
+			return withHeader(Text(http.StatusTooManyRequests, "Too many failed attempts"), header)
+		}
+		hash, ok, err := store.PasswordHash(ctx, user)
+		if err != nil {
+			logger.Errorf("Get password hash of %s: %v", user, err)
+			return Status(http.StatusInternalServerError)
+		}
+		if !ok {
+			// Take similar time as existing users
+			bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+			l.fail(key)
+			return RequireBasicAuth(options.Realm)
+		}
+		matched, err := VerifyPassword(hash, password)
+		if err != nil {
+			logger.Errorf("Verify password of %s: %v", user, err)
+		}
+		if !matched {
+			l.fail(key)
+			return RequireBasicAuth(options.Realm)
+		}
+		l.reset(key)
+		ctx = ctxutil.WithBasicUser(ctx, user)
+		return Next(ctx, req)
+	}
+}
+
+// lockout locks keys out after consecutive failures
+type lockout struct {
+	max      int
+	duration time.Duration
+
+	mu       sync.Mutex
+	failures map[string]*failure
+	sweptAt  time.Time
+}
+
+type failure struct {
+	count       int
+	lockedUntil time.Time
+	updatedAt   time.Time
+}
+
+func newLockout(max int, duration time.Duration) *lockout {
+	return &lockout{
+		max:      max,
+		duration: duration,
+		failures: make(map[string]*failure),
+		sweptAt:  time.Now(),
+	}
+}
+
+func (l *lockout) lockedFor(key string) time.Duration {
+	if l.max <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if f := l.failures[key]; f != nil {
+		if d := time.Until(f.lockedUntil); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func (l *lockout) fail(key string) {
+	if l.max <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.sweep(now)
+	f := l.failures[key]
+	if f == nil {
+		f = new(failure)
+		l.failures[key] = f
+	}
+	f.count++
+	f.updatedAt = now
+	if f.count >= l.max {
+		f.count = 0
+		f.lockedUntil = now.Add(l.duration)
+	}
+}
+
+func (l *lockout) reset(key string) {
+	if l.max <= 0 {
+		return
+	}
+	l.mu.Lock()
+	delete(l.failures, key)
+	l.mu.Unlock()
+}
+
+// sweep removes failures which don't matter anymore. l.mu must be held
+func (l *lockout) sweep(now time.Time) {
+	if now.Sub(l.sweptAt) < lockoutSweepInterval {
+		return
+	}
+	l.sweptAt = now
+	for k, f := range l.failures {
+		if now.After(f.lockedUntil) && now.Sub(f.updatedAt) > l.duration {
+			delete(l.failures, k)
+		}
+	}
+}
+
 func RequireBasicAuth(realm string) Responder {
 	return respond.Func(func(ctx context.Context, w http.ResponseWriter) {
 		a := "Basic realm=" + strconv.Quote(realm)
@@ -44,3 +194,40 @@ func RequireBasicAuth(realm string) Responder {
 		w.WriteHeader(http.StatusUnauthorized)
 	})
 }
+
+// NewJWTAuthHandler returns an interceptor which authenticates bearer tokens by v.
+// Claims of valid token are set as user in context, whose id is parsed from sub.
+// Requests without token are passed on, so that endpoints can require auth by Router.RequireAuth
+func NewJWTAuthHandler(v *jwt.Verifier) HandlerFunc {
+	if v == nil {
+		logger.Panic("Verifier cannot be nil")
+	}
+	return func(ctx context.Context, req *Request) Responder {
+		token := req.Bearer()
+		if token == "" {
+			return Next(ctx, req)
+		}
+		t, err := v.Verify(ctx, token)
+		if err != nil {
+			return RequireBearerAuth("invalid_token", err.Error())
+		}
+		ctx = ctxutil.WithUser(ctx, t.Claims)
+		if id := t.Claims.GetID(); id > 0 {
+			ctx = ctxutil.WithUserID(ctx, id)
+			req.SetUserID(id)
+		}
+		return Next(ctx, req)
+	}
+}
+
+// RequireBearerAuth responds 401 with bearer challenge, e.g. error=invalid_token
+func RequireBearerAuth(errCode, description string) Responder {
+	return respond.Func(func(ctx context.Context, w http.ResponseWriter) {
+		a := "Bearer"
+		if errCode != "" {
+			a = fmt.Sprintf("Bearer error=%s, error_description=%s", strconv.Quote(errCode), strconv.Quote(description))
+		}
+		w.Header().Set("WWW-Authenticate", a)
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,52 @@
+package wine_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/ctxutil"
+	"github.com/gopub/wine/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTAuthHandler(t *testing.T) {
+	secret := []byte("secret")
+	s := wine.NewTestServer(t)
+	r := s.Use(wine.NewJWTAuthHandler(jwt.NewVerifier(jwt.HMACKey(secret))))
+	r.Get("public", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.Text(http.StatusOK, fmt.Sprint(ctxutil.GetUserID(ctx)))
+	})
+	r.RequireAuth().Get("private", func(ctx context.Context, req *wine.Request) wine.Responder {
+		claims := ctxutil.GetUser(ctx).(jwt.Claims)
+		return wine.Text(http.StatusOK, claims.String("name"))
+	})
+	url := s.Run()
+
+	do := func(path, token string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, url+path, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	token, err := jwt.Sign(jwt.Claims{"sub": "7", "name": "tom", "exp": time.Now().Add(time.Minute).Unix()}, jwt.HS256, "", secret)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, do("/public", "").StatusCode)
+	require.Equal(t, http.StatusUnauthorized, do("/private", "").StatusCode)
+	require.Equal(t, http.StatusOK, do("/private", token).StatusCode)
+
+	expired, err := jwt.Sign(jwt.Claims{"sub": "7", "exp": time.Now().Add(-time.Hour).Unix()}, jwt.HS256, "", secret)
+	require.NoError(t, err)
+	resp := do("/public", expired)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Contains(t, resp.Header.Get("WWW-Authenticate"), `error="invalid_token"`)
+}
@@ -0,0 +1,120 @@
+package wine
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/types"
+	iopkg "github.com/gopub/wine/internal/io"
+)
+
+// ErrBodyTooLarge is returned while reading a request body which exceeds the max body size, and is responded with 413
+var ErrBodyTooLarge = errors.Format(http.StatusRequestEntityTooLarge, "request body too large")
+
+// SetMaxBodySize sets max body size of endpoints bound by r afterwards, which overrides server's Options.MaxBodySize.
+// Negative size means no limit
+func (r *Router) SetMaxBodySize(n types.ByteUnit) {
+	r.md.MaxBodySize = n
+}
+
+// SetMaxBodySize sets max body size of e, which overrides router's. Negative size means no limit
+func (e *Endpoint) SetMaxBodySize(n types.ByteUnit) *Endpoint {
+	e.metadata().MaxBodySize = n
+	return e
+}
+
+// MaxBodySize returns max body size set by SetMaxBodySize, 0 means server's Options.MaxBodySize applies
+func (e *Endpoint) MaxBodySize() types.ByteUnit {
+	return e.metadata().MaxBodySize
+}
+
+// SetStreamingBody makes the server leave body of e unread, so that handlers can read it from Request.BodyReader
+// or Request.MultipartReader without buffering, e.g. large uploads and proxies. Max body size still applies
+func (e *Endpoint) SetStreamingBody(b bool) *Endpoint {
+	e.metadata().StreamingBody = b
+	return e
+}
+
+// StreamingBody returns true if body of e is left unread
+func (e *Endpoint) StreamingBody() bool {
+	return e.metadata().StreamingBody
+}
+
+// BodyReader returns reader of request body. It reads the original body if endpoint's body is streaming,
+// otherwise it reads the buffered body
+func (r *Request) BodyReader() io.Reader {
+	if r.streaming {
+		return r.request.Body
+	}
+	return bytes.NewReader(r.body)
+}
+
+// MultipartReader returns reader to iterate parts of multipart/form-data or multipart/mixed body one by one.
+// It's only available if endpoint's body is streaming
+func (r *Request) MultipartReader() (*multipart.Reader, error) {
+	if !r.streaming {
+		return nil, errors.New("body is not streaming")
+	}
+	return r.request.MultipartReader()
+}
+
+func (s *Server) parseRequest(req *http.Request, e *Endpoint) (*Request, error) {
+	limit := s.MaxBodySize
+	streaming := false
+	if e != nil {
+		if n := e.MaxBodySize(); n != 0 {
+			limit = n
+		}
+		streaming = e.StreamingBody()
+	}
+
+	var lb *limitedBody
+	if limit > 0 && req.Body != nil && req.Body != http.NoBody {
+		if req.ContentLength > int64(limit) {
+			return nil, ErrBodyTooLarge
+		}
+		lb = &limitedBody{ReadCloser: req.Body, n: int64(limit)}
+		req.Body = lb
+	}
+
+	if streaming {
+		return newRequest(req, iopkg.ReadParams(req), nil, true), nil
+	}
+	params, body, err := iopkg.ReadRequest(req, s.ReqFormMem)
+	if err != nil {
+		// Errors of multipart and form parsers may not wrap the original error
+		if lb != nil && lb.exceeded {
+			return nil, ErrBodyTooLarge
+		}
+		return nil, fmt.Errorf("read request: %w", err)
+	}
+	return newRequest(req, params, body, false), nil
+}
+
+// limitedBody returns ErrBodyTooLarge once more than n bytes are read
+type limitedBody struct {
+	io.ReadCloser
+	n        int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+	// Read one more byte to tell if body is exactly n bytes
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.n {
+		b.exceeded = true
+		return int(b.n), ErrBodyTooLarge
+	}
+	b.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,128 @@
+package wine_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/httpvalue"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_MaxBodySize(t *testing.T) {
+	s := wine.NewTestServer(t)
+	s.MaxBodySize = 16
+	echo := func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.Text(http.StatusOK, req.Params().String("text"))
+	}
+	s.Post("echo", echo)
+	s.Post("unlimited", echo).SetMaxBodySize(-1)
+	large := s.Group("large")
+	large.SetMaxBodySize(64)
+	large.Post("echo", echo)
+	url := s.Run()
+
+	post := func(path string, body io.Reader) (int, string) {
+		resp, err := http.Post(url+path, httpvalue.FormURLEncoded, body)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(b)
+	}
+	text := "text=" + strings.Repeat("a", 32)
+
+	status, body := post("/echo", strings.NewReader("text=hello"))
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "hello", body)
+
+	status, _ = post("/echo", strings.NewReader(text))
+	require.Equal(t, http.StatusRequestEntityTooLarge, status)
+
+	t.Run("Chunked", func(t *testing.T) {
+		// Body without length is sent in chunks
+		status, _ := post("/echo", io.MultiReader(strings.NewReader(text)))
+		require.Equal(t, http.StatusRequestEntityTooLarge, status)
+	})
+
+	t.Run("Override", func(t *testing.T) {
+		status, _ := post("/unlimited", strings.NewReader(text))
+		require.Equal(t, http.StatusOK, status)
+		status, _ = post("/large/echo", strings.NewReader(text))
+		require.Equal(t, http.StatusOK, status)
+		status, _ = post("/large/echo", strings.NewReader(text+text))
+		require.Equal(t, http.StatusRequestEntityTooLarge, status)
+	})
+}
+
+func TestServer_StreamingBody(t *testing.T) {
+	s := wine.NewTestServer(t)
+	s.Post("raw", func(ctx context.Context, req *wine.Request) wine.Responder {
+		require.Nil(t, req.Body())
+		n, err := io.Copy(ioutil.Discard, req.BodyReader())
+		if err != nil {
+			return wine.Error(err)
+		}
+		return wine.Text(http.StatusOK, fmt.Sprint(n))
+	}).SetStreamingBody(true).SetMaxBodySize(1024)
+	s.Post("parts", func(ctx context.Context, req *wine.Request) wine.Responder {
+		mr, err := req.MultipartReader()
+		if err != nil {
+			return wine.Error(err)
+		}
+		var l []string
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return wine.Error(err)
+			}
+			n, err := io.Copy(ioutil.Discard, p)
+			if err != nil {
+				return wine.Error(err)
+			}
+			l = append(l, fmt.Sprintf("%s:%d", p.FormName(), n))
+		}
+		return wine.Text(http.StatusOK, strings.Join(l, ","))
+	}).SetStreamingBody(true)
+	url := s.Run()
+
+	post := func(path, contentType string, body io.Reader) (int, string) {
+		resp, err := http.Post(url+path, contentType, body)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(b)
+	}
+
+	status, body := post("/raw", httpvalue.OctetStream, bytes.NewReader(make([]byte, 1024)))
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "1024", body)
+
+	status, _ = post("/raw", httpvalue.OctetStream, io.MultiReader(bytes.NewReader(make([]byte, 1025))))
+	require.Equal(t, http.StatusRequestEntityTooLarge, status)
+
+	t.Run("Multipart", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		require.NoError(t, w.WriteField("title", "wine"))
+		fw, err := w.CreateFormFile("file", "a.bin")
+		require.NoError(t, err)
+		_, err = fw.Write(make([]byte, 100000))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		status, body := post("/parts", w.FormDataContentType(), &buf)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "title:4,file:100000", body)
+	})
+}
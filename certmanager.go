@@ -0,0 +1,268 @@
+package wine
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const defaultCertReloadInterval = time.Minute
+
+// CertPair is a pair of certificate and private key files in PEM format
+type CertPair struct {
+	CertFile string
+	KeyFile  string
+}
+
+// ACMEOptions configures obtaining certificates from an ACME CA, e.g. Let's Encrypt
+type ACMEOptions struct {
+	// Hosts which certificates can be obtained for
+	Hosts []string
+	Email string
+	// CacheDir stores account key and obtained certificates. Certificates are obtained at every start if it's empty
+	CacheDir string
+	// DirectoryURL of ACME CA, Let's Encrypt production is used by default
+	DirectoryURL string
+	// HTTPClient is used to communicate with ACME CA, e.g. one trusts CA of a local test server
+	HTTPClient *http.Client
+}
+
+// TLSOptions configures certificates of CertManager
+type TLSOptions struct {
+	Certs []CertPair
+	// ReloadInterval is the minimum interval of checking changes of cert files
+	ReloadInterval time.Duration
+	ACME           *ACMEOptions
+}
+
+// CertManager provides certificates for tls handshakes.
+// Certificates loaded from files are selected by SNI, and reloaded once files change.
+// Certificates of ACME hosts are obtained and renewed automatically
+type CertManager struct {
+	mu        sync.Mutex
+	certs     []*fileCert
+	interval  time.Duration
+	checkedAt time.Time
+	reloading bool
+
+	acme      *autocert.Manager
+	acmeHosts map[string]bool
+}
+
+// fileCert is a cert pair with its loaded certificate. cert and modTime are guarded by CertManager.mu
+type fileCert struct {
+	CertPair
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func NewCertManager(opts *TLSOptions) (*CertManager, error) {
+	if opts == nil || (len(opts.Certs) == 0 && opts.ACME == nil) {
+		return nil, errors.New("no certificate or acme options")
+	}
+	m := &CertManager{
+		interval:  opts.ReloadInterval,
+		checkedAt: time.Now(),
+	}
+	if m.interval <= 0 {
+		m.interval = defaultCertReloadInterval
+	}
+	for _, p := range opts.Certs {
+		cert, modTime, err := p.load()
+		if err != nil {
+			return nil, err
+		}
+		m.certs = append(m.certs, &fileCert{CertPair: p, cert: cert, modTime: modTime})
+	}
+
+	if o := opts.ACME; o != nil {
+		if len(o.Hosts) == 0 {
+			return nil, errors.New("no acme hosts")
+		}
+		m.acme = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(o.Hosts...),
+			Email:      o.Email,
+		}
+		if o.CacheDir != "" {
+			m.acme.Cache = autocert.DirCache(o.CacheDir)
+		}
+		if o.DirectoryURL != "" || o.HTTPClient != nil {
+			m.acme.Client = &acme.Client{
+				DirectoryURL: o.DirectoryURL,
+				HTTPClient:   o.HTTPClient,
+			}
+		}
+		m.acmeHosts = make(map[string]bool, len(o.Hosts))
+		for _, h := range o.Hosts {
+			m.acmeHosts[strings.ToLower(h)] = true
+		}
+	}
+	return m, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (m *CertManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if m.acme != nil && (m.acmeHosts[name] || isACMEChallenge(hello)) {
+		return m.acme.GetCertificate(hello)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Files are checked in background, so that handshakes never wait for disk
+	if !m.reloading && time.Since(m.checkedAt) >= m.interval {
+		m.reloading = true
+		m.checkedAt = time.Now()
+		go m.reloadChanged()
+	}
+	if len(m.certs) == 0 {
+		return nil, fmt.Errorf("no certificate for %s", hello.ServerName)
+	}
+	if name != "" {
+		for _, c := range m.certs {
+			if c.cert.Leaf.VerifyHostname(name) == nil {
+				return c.cert, nil
+			}
+		}
+	}
+	// Clients may not send SNI, e.g. connecting by ip
+	return m.certs[0].cert, nil
+}
+
+// TLSConfig returns tls config which gets certificates from m
+func (m *CertManager) TLSConfig() *tls.Config {
+	c := &tls.Config{
+		GetCertificate: m.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+		MinVersion:     tls.VersionTLS12,
+	}
+	if m.acme != nil {
+		c.NextProtos = append(c.NextProtos, acme.ALPNProto)
+	}
+	return c
+}
+
+// HTTPHandler handles ACME http-01 challenges and delegates other requests to fallback.
+// Requests are redirected to https if fallback is nil
+func (m *CertManager) HTTPHandler(fallback http.Handler) http.Handler {
+	if m.acme == nil {
+		if fallback == nil {
+			return http.HandlerFunc(redirectHTTPS)
+		}
+		return fallback
+	}
+	return m.acme.HTTPHandler(fallback)
+}
+
+// Reload reloads all cert files no matter whether they changed
+func (m *CertManager) Reload() error {
+	type loaded struct {
+		cert    *tls.Certificate
+		modTime time.Time
+	}
+	l := make([]loaded, len(m.certs))
+	for i, c := range m.certs {
+		cert, modTime, err := c.load()
+		if err != nil {
+			return err
+		}
+		l[i] = loaded{cert: cert, modTime: modTime}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkedAt = time.Now()
+	for i, c := range m.certs {
+		c.cert, c.modTime = l[i].cert, l[i].modTime
+	}
+	return nil
+}
+
+// reloadChanged reloads changed cert files without holding m.mu while reading them
+func (m *CertManager) reloadChanged() {
+	defer func() {
+		m.mu.Lock()
+		m.reloading = false
+		m.mu.Unlock()
+	}()
+	for _, c := range m.certs {
+		modTime, err := c.latestModTime()
+		if err != nil {
+			logger.Errorf("Check certificate %s: %v", c.CertFile, err)
+			continue
+		}
+		m.mu.Lock()
+		changed := !modTime.Equal(c.modTime)
+		m.mu.Unlock()
+		if !changed {
+			continue
+		}
+		cert, newModTime, err := c.load()
+		m.mu.Lock()
+		if err != nil {
+			// Keep serving the old one if new files are invalid, e.g. only one of them was written.
+			// Retry once files change again
+			c.modTime = modTime
+		} else {
+			c.cert, c.modTime = cert, newModTime
+		}
+		m.mu.Unlock()
+		if err != nil {
+			logger.Errorf("Reload certificate %s: %v", c.CertFile, err)
+			continue
+		}
+		logger.Infof("Reloaded certificate %s", c.CertFile)
+	}
+}
+
+func (p CertPair) load() (*tls.Certificate, time.Time, error) {
+	modTime, err := p.latestModTime()
+	if err != nil {
+		return nil, modTime, err
+	}
+	cert, err := tls.LoadX509KeyPair(p.CertFile, p.KeyFile)
+	if err != nil {
+		return nil, modTime, fmt.Errorf("load %s and %s: %w", p.CertFile, p.KeyFile, err)
+	}
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, modTime, fmt.Errorf("parse %s: %w", p.CertFile, err)
+	}
+	return &cert, modTime, nil
+}
+
+func (p CertPair) latestModTime() (time.Time, error) {
+	var t time.Time
+	for _, name := range []string{p.CertFile, p.KeyFile} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return t, fmt.Errorf("stat: %w", err)
+		}
+		if fi.ModTime().After(t) {
+			t = fi.ModTime()
+		}
+	}
+	return t, nil
+}
+
+func isACMEChallenge(hello *tls.ClientHelloInfo) bool {
+	for _, p := range hello.SupportedProtos {
+		if p == acme.ALPNProto {
+			return true
+		}
+	}
+	return false
+}
+
+func redirectHTTPS(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, "https://"+r.Host+r.URL.RequestURI(), http.StatusFound)
+}
@@ -0,0 +1,359 @@
+package wine_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gopub/wine"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+)
+
+func writeCertPair(t *testing.T, dir, host string, serial int64) wine.CertPair {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{host},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	p := wine.CertPair{
+		CertFile: filepath.Join(dir, host+".crt"),
+		KeyFile:  filepath.Join(dir, host+".key"),
+	}
+	err = os.WriteFile(p.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	require.NoError(t, err)
+	err = os.WriteFile(p.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	require.NoError(t, err)
+	return p
+}
+
+func TestCertManager(t *testing.T) {
+	dir := t.TempDir()
+	a := writeCertPair(t, dir, "a.example.com", 1)
+	b := writeCertPair(t, dir, "b.example.com", 2)
+	m, err := wine.NewCertManager(&wine.TLSOptions{
+		Certs:          []wine.CertPair{a, b},
+		ReloadInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	serialOf := func(serverName string) int64 {
+		c, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+		require.NoError(t, err)
+		return c.Leaf.SerialNumber.Int64()
+	}
+
+	t.Run("SNI", func(t *testing.T) {
+		require.EqualValues(t, 1, serialOf("a.example.com"))
+		require.EqualValues(t, 2, serialOf("b.example.com"))
+		require.EqualValues(t, 1, serialOf(""))
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		writeCertPair(t, dir, "b.example.com", 3)
+		// Make sure mod time changes on file systems with coarse time granularity
+		future := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(b.CertFile, future, future))
+		require.Eventually(t, func() bool {
+			return serialOf("b.example.com") == 3
+		}, time.Second, 20*time.Millisecond)
+		require.EqualValues(t, 1, serialOf("a.example.com"))
+	})
+
+	t.Run("InvalidFiles", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(a.KeyFile, []byte("invalid"), 0600))
+		time.Sleep(20 * time.Millisecond)
+		require.EqualValues(t, 1, serialOf("a.example.com"))
+		require.Error(t, m.Reload())
+	})
+}
+
+// fakeACME is a minimal ACME CA in the way of Pebble. It validates tls-alpn-01 challenges by connecting to Addr,
+// and issues certificates signed by its own root, but doesn't verify signatures of requests
+type fakeACME struct {
+	*httptest.Server
+	t      *testing.T
+	caKey  *ecdsa.PrivateKey
+	caCert *x509.Certificate
+
+	mu         sync.Mutex
+	addr       string
+	accountKey *ecdsa.PublicKey
+	domain     string
+	token      string
+	status     string // of the order and its authorization
+	certDER    []byte
+}
+
+func newFakeACME(t *testing.T) *fakeACME {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Fake ACME Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	ca := &fakeACME{t: t, caKey: key, token: "token-1", status: "pending"}
+	ca.caCert, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+	ca.Server = httptest.NewServer(http.HandlerFunc(ca.serveHTTP))
+	t.Cleanup(ca.Close)
+	return ca
+}
+
+// SetAddr sets address of the server which challenges are validated against
+func (ca *fakeACME) SetAddr(addr string) {
+	ca.mu.Lock()
+	ca.addr = addr
+	ca.mu.Unlock()
+}
+
+func (ca *fakeACME) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.caCert)
+	return pool
+}
+
+func (ca *fakeACME) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Replay-Nonce", fmt.Sprint(time.Now().UnixNano()))
+	if r.Method == http.MethodHead {
+		return
+	}
+	if r.URL.Path == "/directory" {
+		ca.writeJSON(w, http.StatusOK, map[string]string{
+			"newNonce":   ca.URL + "/nonce",
+			"newAccount": ca.URL + "/account",
+			"newOrder":   ca.URL + "/order",
+		})
+		return
+	}
+
+	var jws struct {
+		Protected string `json:"protected"`
+		Payload   string `json:"payload"`
+	}
+	require.NoError(ca.t, json.NewDecoder(r.Body).Decode(&jws))
+	payload, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+	require.NoError(ca.t, err)
+
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	switch r.URL.Path {
+	case "/account":
+		protected, err := base64.RawURLEncoding.DecodeString(jws.Protected)
+		require.NoError(ca.t, err)
+		var header struct {
+			JWK struct {
+				X string `json:"x"`
+				Y string `json:"y"`
+			} `json:"jwk"`
+		}
+		require.NoError(ca.t, json.Unmarshal(protected, &header))
+		x, err := base64.RawURLEncoding.DecodeString(header.JWK.X)
+		require.NoError(ca.t, err)
+		y, err := base64.RawURLEncoding.DecodeString(header.JWK.Y)
+		require.NoError(ca.t, err)
+		ca.accountKey = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		w.Header().Set("Location", ca.URL+"/account/1")
+		ca.writeJSON(w, http.StatusCreated, map[string]string{"status": "valid"})
+	case "/order":
+		var req struct {
+			Identifiers []struct{ Value string } `json:"identifiers"`
+		}
+		require.NoError(ca.t, json.Unmarshal(payload, &req))
+		ca.domain = req.Identifiers[0].Value
+		ca.writeOrder(w, http.StatusCreated)
+	case "/order/1":
+		ca.writeOrder(w, http.StatusOK)
+	case "/authz/1":
+		ca.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"status":     ca.status,
+			"identifier": map[string]string{"type": "dns", "value": ca.domain},
+			"challenges": []map[string]string{{
+				"type":   "tls-alpn-01",
+				"url":    ca.URL + "/challenge/1",
+				"token":  ca.token,
+				"status": ca.status,
+			}},
+		})
+	case "/challenge/1":
+		if err := ca.validate(); err != nil {
+			ca.t.Errorf("Validate challenge: %v", err)
+			ca.status = "invalid"
+		} else {
+			ca.status = "valid"
+		}
+		ca.writeJSON(w, http.StatusOK, map[string]string{"type": "tls-alpn-01", "url": ca.URL + "/challenge/1", "token": ca.token, "status": ca.status})
+	case "/finalize/1":
+		var req struct {
+			CSR string `json:"csr"`
+		}
+		require.NoError(ca.t, json.Unmarshal(payload, &req))
+		der, err := base64.RawURLEncoding.DecodeString(req.CSR)
+		require.NoError(ca.t, err)
+		csr, err := x509.ParseCertificateRequest(der)
+		require.NoError(ca.t, err)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(100),
+			Subject:      pkix.Name{CommonName: ca.domain},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+			DNSNames:     csr.DNSNames,
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+		ca.certDER, err = x509.CreateCertificate(rand.Reader, tmpl, ca.caCert, csr.PublicKey, ca.caKey)
+		require.NoError(ca.t, err)
+		ca.writeOrder(w, http.StatusOK)
+	case "/cert/1":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: ca.certDER})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// validate connects to server like a CA validating tls-alpn-01 challenge, see RFC 8737. ca.mu must be held
+func (ca *fakeACME) validate() error {
+	conn, err := tls.Dial("tcp", ca.addr, &tls.Config{
+		ServerName:         ca.domain,
+		NextProtos:         []string{acme.ALPNProto},
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	state := conn.ConnectionState()
+	if state.NegotiatedProtocol != acme.ALPNProto {
+		return fmt.Errorf("negotiated protocol %q", state.NegotiatedProtocol)
+	}
+	thumbprint, err := acme.JWKThumbprint(ca.accountKey)
+	if err != nil {
+		return err
+	}
+	expected := sha256.Sum256([]byte(ca.token + "." + thumbprint))
+	idPeACMEIdentifier := asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}
+	for _, ext := range state.PeerCertificates[0].Extensions {
+		if !ext.Id.Equal(idPeACMEIdentifier) {
+			continue
+		}
+		var digest []byte
+		if _, err = asn1.Unmarshal(ext.Value, &digest); err != nil {
+			return err
+		}
+		if !bytes.Equal(digest, expected[:]) {
+			return errors.New("mismatched key authorization")
+		}
+		return nil
+	}
+	return errors.New("missing acmeIdentifier extension")
+}
+
+// writeOrder writes the only order. ca.mu must be held
+func (ca *fakeACME) writeOrder(w http.ResponseWriter, code int) {
+	o := map[string]interface{}{
+		"status":         ca.status,
+		"identifiers":    []map[string]string{{"type": "dns", "value": ca.domain}},
+		"authorizations": []string{ca.URL + "/authz/1"},
+		"finalize":       ca.URL + "/finalize/1",
+	}
+	switch {
+	case ca.certDER != nil:
+		o["status"] = "valid"
+		o["certificate"] = ca.URL + "/cert/1"
+	case ca.status == "valid":
+		o["status"] = "ready"
+	}
+	w.Header().Set("Location", ca.URL+"/order/1")
+	ca.writeJSON(w, code, o)
+}
+
+func (ca *fakeACME) writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	require.NoError(ca.t, json.NewEncoder(w).Encode(v))
+}
+
+func TestServer_RunAutoTLS(t *testing.T) {
+	ca := newFakeACME(t)
+	s := wine.NewServer(nil)
+	s.Get("hello", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.Text(http.StatusOK, "hello")
+	})
+	errC := make(chan error, 1)
+	go func() {
+		errC <- s.RunAutoTLS("127.0.0.1:0", &wine.TLSOptions{
+			ACME: &wine.ACMEOptions{
+				Hosts:        []string{"a.example.com"},
+				CacheDir:     t.TempDir(),
+				DirectoryURL: ca.URL + "/directory",
+			},
+		})
+	}()
+	require.Eventually(t, func() bool {
+		return s.Addr() != ""
+	}, time.Second, 10*time.Millisecond)
+	ca.SetAddr(s.Addr())
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: ca.CertPool()},
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return new(net.Dialer).DialContext(ctx, network, s.Addr())
+			},
+		},
+	}
+	resp, err := client.Get("https://a.example.com/hello")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(body))
+	require.EqualValues(t, 100, resp.TLS.PeerCertificates[0].SerialNumber.Int64())
+
+	// Hosts out of ACME options are rejected
+	_, err = client.Get("https://b.example.com/hello")
+	require.Error(t, err)
+
+	client.CloseIdleConnections()
+	require.NoError(t, s.Shutdown(context.Background()))
+	require.NoError(t, <-errC)
+}
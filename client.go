@@ -16,8 +16,10 @@ import (
 	"github.com/gopub/conv"
 	"github.com/gopub/errors"
 	"github.com/gopub/log"
+	"github.com/gopub/wine/codec"
 	"github.com/gopub/wine/httpvalue"
 	iopkg "github.com/gopub/wine/internal/io"
+	"github.com/gopub/wine/trace"
 	"github.com/gopub/wine/urlutil"
 )
 
@@ -31,6 +33,9 @@ type Client struct {
 	HeaderBuilder  HeaderBuilder
 	RequestLogging bool
 	Decoder        func(resp *http.Response, result interface{}) error
+	// Tracer starts a client span for each request if it's not nil.
+	// Trace in request context is propagated via traceparent header in either case
+	Tracer *trace.Tracer
 
 	getServerTime *ClientEndpoint
 }
@@ -153,6 +158,16 @@ func (c *Client) Delete(ctx context.Context, endpoint string, query url.Values,
 // Do send http request 'req' and store response data into 'result'
 func (c *Client) Do(req *http.Request, result interface{}) error {
 	c.injectHeader(req)
+	var span *trace.Span
+	if c.Tracer != nil {
+		var ctx context.Context
+		ctx, span = c.Tracer.Start(req.Context(), req.Method+" "+req.URL.Path, trace.SpanKindClient)
+		defer span.End()
+		span.SetAttribute("http.method", req.Method)
+		span.SetAttribute("http.url", req.URL.String())
+		req = req.WithContext(ctx)
+	}
+	trace.Inject(req.Context(), req.Header)
 
 	if c.RequestLogging {
 		c.dumpRequest(req)
@@ -169,8 +184,14 @@ func (c *Client) Do(req *http.Request, result interface{}) error {
 				err = errors.Format(httpvalue.StatusTransportFailed, err.Error())
 			}
 		}
+		if span != nil {
+			span.SetError(err)
+		}
 		return fmt.Errorf("cannot send request: %w", err)
 	}
+	if span != nil {
+		span.SetAttribute("http.status_code", resp.StatusCode)
+	}
 	if w, ok := result.(io.Writer); ok {
 		_, err = io.Copy(w, resp.Body)
 		if err != nil {
@@ -212,10 +233,11 @@ func (c *Client) GetServerTime(ctx context.Context, serverURL string) (int64, er
 }
 
 type ClientEndpoint struct {
-	c      *Client
-	method string
-	url    *url.URL
-	header http.Header
+	c           *Client
+	method      string
+	url         *url.URL
+	header      http.Header
+	contentType string
 }
 
 func newClientEndpoint(c *Client, method string, urlStr string) (*ClientEndpoint, error) {
@@ -250,6 +272,12 @@ func (c *ClientEndpoint) SetBasicAuthorization(account, password string) {
 	c.header.Set(httpvalue.Authorization, "Basic "+base64.StdEncoding.EncodeToString(credential))
 }
 
+// SetContentType sets content type of request body, which is encoded by the codec registered in codec.Default, e.g. application/msgpack.
+// Body is encoded in json by default
+func (c *ClientEndpoint) SetContentType(contentType string) {
+	c.contentType = contentType
+}
+
 func (c *ClientEndpoint) Call(ctx context.Context, input interface{}, output interface{}) error {
 	input = conv.Indirect(input)
 	var body io.Reader
@@ -273,7 +301,16 @@ func (c *ClientEndpoint) Call(ctx context.Context, input interface{}, output int
 		break
 	default:
 		contentType = httpvalue.JsonUTF8
-		data, err := json.Marshal(input)
+		marshal := json.Marshal
+		if c.contentType != "" {
+			cc := codec.Get(c.contentType)
+			if cc == nil {
+				return fmt.Errorf("no codec for %s", c.contentType)
+			}
+			contentType = c.contentType
+			marshal = cc.Marshal
+		}
+		data, err := marshal(input)
 		if err != nil {
 			return fmt.Errorf("cannot marshal: %w", err)
 		}
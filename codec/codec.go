@@ -0,0 +1,105 @@
+// Package codec provides codecs of content types, which are used to negotiate responses and decode request bodies.
+package codec
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gopub/wine/httpvalue"
+)
+
+// Codec marshals and unmarshals values of a content type
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Checker is implemented by codecs which can only marshal some types, e.g. protobuf codec requires proto.Message.
+// Codecs which cannot marshal the value are skipped in negotiation
+type Checker interface {
+	CanMarshal(v interface{}) bool
+}
+
+// Registry keeps codecs by content types
+type Registry struct {
+	mu     sync.RWMutex
+	codecs map[string]Codec
+	types  []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		codecs: make(map[string]Codec),
+	}
+}
+
+// Register registers codec c for content types. Content types are offered in order of registration in negotiation
+func (r *Registry) Register(c Codec, contentTypes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range contentTypes {
+		t = normalize(t)
+		if _, ok := r.codecs[t]; !ok {
+			r.types = append(r.types, t)
+		}
+		r.codecs[t] = c
+	}
+}
+
+// Get returns codec of content type which may have parameters, e.g. application/json; charset=utf-8
+func (r *Registry) Get(contentType string) Codec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.codecs[normalize(contentType)]
+}
+
+// ContentTypes returns registered content types in order of registration
+func (r *Registry) ContentTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.types...)
+}
+
+// Offers returns content types whose codecs can marshal v
+func (r *Registry) Offers(v interface{}) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var l []string
+	for _, t := range r.types {
+		if c, ok := r.codecs[t].(Checker); ok && !c.CanMarshal(v) {
+			continue
+		}
+		l = append(l, t)
+	}
+	return l
+}
+
+func normalize(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// Default is the registry of builtin codecs. JSON is the first, which is preferred if requests accept any type
+var Default = NewRegistry()
+
+func init() {
+	Default.Register(JSON, httpvalue.JSON)
+	Default.Register(Protobuf, httpvalue.Protobuf)
+	Default.Register(XML, httpvalue.XML, httpvalue.XML2)
+	Default.Register(MsgPack, httpvalue.MsgPack, httpvalue.XMsgPack)
+	Default.Register(CSV, httpvalue.CSV)
+	Default.Register(CBOR, httpvalue.CBOR)
+	Default.Register(YAML, httpvalue.YAML, httpvalue.XYAML, "text/yaml")
+}
+
+// Register registers codec c into Default
+func Register(c Codec, contentTypes ...string) {
+	Default.Register(c, contentTypes...)
+}
+
+// Get returns codec of content type from Default
+func Get(contentType string) Codec {
+	return Default.Get(contentType)
+}
@@ -0,0 +1,105 @@
+package codec_test
+
+import (
+	"testing"
+
+	"github.com/gopub/wine/codec"
+	"github.com/gopub/wine/httpvalue"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type item struct {
+	ID    int64   `json:"id"`
+	Name  string  `json:"name"`
+	Price float64 `json:"price" csv:"unit_price"`
+}
+
+func TestRegistry(t *testing.T) {
+	require.Equal(t, codec.JSON, codec.Get("application/json; charset=utf-8"))
+	require.Equal(t, codec.MsgPack, codec.Get(httpvalue.XMsgPack))
+	require.Equal(t, codec.YAML, codec.Get("text/yaml"))
+	require.Nil(t, codec.Get("application/unknown"))
+
+	require.Equal(t, []string{httpvalue.JSON, httpvalue.MsgPack, httpvalue.XMsgPack, httpvalue.CBOR,
+		httpvalue.YAML, httpvalue.XYAML, "text/yaml"},
+		codec.Default.Offers(map[string]string{"k": "v"}))
+	require.Contains(t, codec.Default.Offers(wrapperspb.String("v")), httpvalue.Protobuf)
+	require.Contains(t, codec.Default.Offers([]*item{}), httpvalue.CSV)
+
+	r := codec.NewRegistry()
+	r.Register(codec.JSON, "application/vnd.wine+json")
+	require.Equal(t, []string{"application/vnd.wine+json"}, r.ContentTypes())
+}
+
+func TestCodecs(t *testing.T) {
+	in := &item{ID: 1, Name: "wine", Price: 9.9}
+	for _, ct := range []string{httpvalue.JSON, httpvalue.XML, httpvalue.MsgPack, httpvalue.CBOR, httpvalue.YAML} {
+		t.Run(ct, func(t *testing.T) {
+			c := codec.Get(ct)
+			b, err := c.Marshal(in)
+			require.NoError(t, err)
+			out := new(item)
+			require.NoError(t, c.Unmarshal(b, out))
+			require.Equal(t, in, out)
+		})
+	}
+
+	t.Run("MsgPackJSONTag", func(t *testing.T) {
+		b, err := codec.MsgPack.Marshal(in)
+		require.NoError(t, err)
+		var m map[string]interface{}
+		require.NoError(t, codec.MsgPack.Unmarshal(b, &m))
+		require.Equal(t, "wine", m["name"])
+	})
+
+	t.Run("YAMLJSONTag", func(t *testing.T) {
+		b, err := codec.YAML.Marshal(in)
+		require.NoError(t, err)
+		require.Equal(t, "id: 1\nname: wine\nprice: 9.9\n", string(b))
+		out := new(item)
+		require.NoError(t, codec.YAML.Unmarshal([]byte("id: 2\nname: \"123\"\n"), out))
+		require.Equal(t, &item{ID: 2, Name: "123"}, out)
+	})
+
+	t.Run("Protobuf", func(t *testing.T) {
+		b, err := codec.Protobuf.Marshal(wrapperspb.String("wine"))
+		require.NoError(t, err)
+		out := new(wrapperspb.StringValue)
+		require.NoError(t, codec.Protobuf.Unmarshal(b, out))
+		require.Equal(t, "wine", out.Value)
+		_, err = codec.Protobuf.Marshal(in)
+		require.Error(t, err)
+	})
+
+	t.Run("ProtoJSON", func(t *testing.T) {
+		b, err := codec.JSON.Marshal(wrapperspb.String("wine"))
+		require.NoError(t, err)
+		require.Equal(t, `"wine"`, string(b))
+		out := new(wrapperspb.StringValue)
+		require.NoError(t, codec.JSON.Unmarshal(b, out))
+		require.Equal(t, "wine", out.Value)
+	})
+
+	t.Run("CBORMap", func(t *testing.T) {
+		b, err := codec.CBOR.Marshal(map[string]interface{}{"name": "wine"})
+		require.NoError(t, err)
+		var v interface{}
+		require.NoError(t, codec.CBOR.Unmarshal(b, &v))
+		require.Equal(t, map[string]interface{}{"name": "wine"}, v)
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		b, err := codec.CSV.Marshal([]*item{in, {ID: 2, Name: "a,b"}})
+		require.NoError(t, err)
+		require.Equal(t, "id,name,unit_price\n1,wine,9.9\n2,\"a,b\",0\n", string(b))
+		var rows []map[string]string
+		require.NoError(t, codec.CSV.Unmarshal(b, &rows))
+		require.Equal(t, []map[string]string{
+			{"id": "1", "name": "wine", "unit_price": "9.9"},
+			{"id": "2", "name": "a,b", "unit_price": "0"},
+		}, rows)
+		_, err = codec.CSV.Marshal(in)
+		require.Error(t, err)
+	})
+}
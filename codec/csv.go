@@ -0,0 +1,126 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// CSV marshals [][]string, or slices of structs whose header row is made of names in csv or json tags.
+// It unmarshals into *[][]string, or *[]map[string]string keyed by the header row
+var CSV Codec = csvCodec{}
+
+type csvCodec struct{}
+
+func (csvCodec) Marshal(v interface{}) ([]byte, error) {
+	records, err := csvRecords(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err = w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (csvCodec) Unmarshal(data []byte, v interface{}) error {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return err
+	}
+	switch p := v.(type) {
+	case *[][]string:
+		*p = records
+	case *[]map[string]string:
+		*p = nil
+		if len(records) == 0 {
+			return nil
+		}
+		header := records[0]
+		for _, rec := range records[1:] {
+			m := make(map[string]string, len(header))
+			for i, name := range header {
+				if i < len(rec) {
+					m[name] = rec[i]
+				}
+			}
+			*p = append(*p, m)
+		}
+	default:
+		return fmt.Errorf("cannot unmarshal csv into %T", v)
+	}
+	return nil
+}
+
+func (csvCodec) CanMarshal(v interface{}) bool {
+	if _, ok := v.([][]string); ok {
+		return true
+	}
+	_, ok := structSliceType(v)
+	return ok
+}
+
+func csvRecords(v interface{}) ([][]string, error) {
+	if records, ok := v.([][]string); ok {
+		return records, nil
+	}
+	st, ok := structSliceType(v)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T into csv", v)
+	}
+
+	var fields []int
+	var header []string
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		for _, key := range []string{"csv", "json"} {
+			if tag := strings.Split(f.Tag.Get(key), ",")[0]; tag != "" {
+				name = tag
+				break
+			}
+		}
+		if name == "-" {
+			continue
+		}
+		fields = append(fields, i)
+		header = append(header, name)
+	}
+
+	sv := reflect.ValueOf(v)
+	records := make([][]string, 0, sv.Len()+1)
+	records = append(records, header)
+	for i := 0; i < sv.Len(); i++ {
+		ev := sv.Index(i)
+		for ev.Kind() == reflect.Ptr {
+			ev = ev.Elem()
+		}
+		rec := make([]string, len(fields))
+		if ev.IsValid() {
+			for j, fi := range fields {
+				rec[j] = fmt.Sprint(ev.Field(fi).Interface())
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func structSliceType(v interface{}) (reflect.Type, bool) {
+	t := reflect.TypeOf(v)
+	if t == nil || (t.Kind() != reflect.Slice && t.Kind() != reflect.Array) {
+		return nil, false
+	}
+	et := t.Elem()
+	for et.Kind() == reflect.Ptr {
+		et = et.Elem()
+	}
+	return et, et.Kind() == reflect.Struct
+}
@@ -0,0 +1,82 @@
+package codec
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gopub/conv"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// CBOR decodes maps into map[string]interface{} and falls back to json tags of structs
+	CBOR Codec = newCBORCodec()
+	// YAML encodes and decodes structs by json tags like MsgPack, unless they implement yaml.Marshaler or yaml.Unmarshaler
+	YAML Codec = yamlCodec{}
+)
+
+type cborCodec struct {
+	dec cbor.DecMode
+}
+
+func newCBORCodec() *cborCodec {
+	dec, err := cbor.DecOptions{
+		DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+	}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return &cborCodec{dec: dec}
+}
+
+func (c *cborCodec) Marshal(v interface{}) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+func (c *cborCodec) Unmarshal(data []byte, v interface{}) error {
+	return c.dec.Unmarshal(data, v)
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) Marshal(v interface{}) ([]byte, error) {
+	if _, ok := v.(yaml.Marshaler); ok {
+		return yaml.Marshal(v)
+	}
+	// JSON is valid YAML, so its node tree keeps names of json tags
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var n yaml.Node
+	if err = yaml.Unmarshal(data, &n); err != nil {
+		return nil, err
+	}
+	resetYAMLStyle(&n)
+	return yaml.Marshal(&n)
+}
+
+func (yamlCodec) Unmarshal(data []byte, v interface{}) error {
+	if _, ok := v.(yaml.Unmarshaler); ok || !isStructPtr(v) {
+		return yaml.Unmarshal(data, v)
+	}
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	return conv.Assign(v, m)
+}
+
+// resetYAMLStyle clears flow and quoted styles of JSON, strings are still quoted if they look like other types
+func resetYAMLStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		resetYAMLStyle(c)
+	}
+}
+
+func isStructPtr(v interface{}) bool {
+	t := reflect.TypeOf(v)
+	return t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct
+}
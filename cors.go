@@ -0,0 +1,235 @@
+package wine
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopub/wine/httpvalue"
+	"github.com/gopub/wine/internal/respond"
+)
+
+// CORS-safelisted request headers which are always allowed in preflight
+var safelistedHeaders = []string{"Accept", "Accept-Language", "Content-Language", "Content-Type"}
+
+// CORSPolicy controls cross-origin requests
+type CORSPolicy struct {
+	// AllowOrigins can be exact origins, e.g. https://example.com, wildcard subdomains, e.g. https://*.example.com,
+	// or * for any origin
+	AllowOrigins       []string
+	AllowOriginRegexps []*regexp.Regexp
+	// AllowMethods are methods allowed in preflight. All methods matched by router are allowed if it's empty
+	AllowMethods []string
+	// AllowHeaders are request headers allowed in preflight, case-insensitive. * allows any header
+	AllowHeaders  []string
+	ExposeHeaders []string
+	// AllowCredentials allows cookies and authorization. Request origin is responded instead of *
+	AllowCredentials bool
+	// MaxAge is the duration preflight results can be cached by browsers
+	MaxAge time.Duration
+}
+
+// AllowOrigin reports whether origin is allowed
+func (p *CORSPolicy) AllowOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, o := range p.AllowOrigins {
+		if o == "*" || strings.EqualFold(o, origin) || matchWildcardOrigin(o, origin) {
+			return true
+		}
+	}
+	for _, re := range p.AllowOriginRegexps {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *CORSPolicy) allowMethod(method string) bool {
+	if len(p.AllowMethods) == 0 {
+		return true
+	}
+	for _, m := range p.AllowMethods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *CORSPolicy) allowHeader(header string) bool {
+	for _, l := range [][]string{safelistedHeaders, p.AllowHeaders} {
+		for _, h := range l {
+			if h == "*" || strings.EqualFold(h, header) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *CORSPolicy) allowOriginValue(origin string) string {
+	if !p.AllowCredentials {
+		for _, o := range p.AllowOrigins {
+			if o == "*" {
+				return "*"
+			}
+		}
+	}
+	return origin
+}
+
+// writeHeader writes CORS headers of actual requests
+func (p *CORSPolicy) writeHeader(rw http.ResponseWriter, origin string) {
+	h := rw.Header()
+	h.Add(httpvalue.Vary, httpvalue.Origin)
+	if !p.AllowOrigin(origin) {
+		return
+	}
+	h.Set(httpvalue.ACLAllowOrigin, p.allowOriginValue(origin))
+	if p.AllowCredentials {
+		h.Set(httpvalue.ACLAllowCredentials, "true")
+	}
+	if len(p.ExposeHeaders) > 0 {
+		h.Set(httpvalue.ACLExposeHeaders, strings.Join(p.ExposeHeaders, ","))
+	}
+}
+
+// matchWildcardOrigin matches origin against pattern like https://*.example.com, which doesn't match https://example.com
+func matchWildcardOrigin(pattern, origin string) bool {
+	i := strings.Index(pattern, "*.")
+	if i < 0 {
+		return false
+	}
+	prefix, suffix := strings.ToLower(pattern[:i]), strings.ToLower(pattern[i+1:])
+	origin = strings.ToLower(origin)
+	if len(origin) <= len(prefix)+len(suffix) {
+		return false
+	}
+	return strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
+
+// SetCORS sets CORS policy of endpoints bound by r afterwards.
+// Policy of server's router applies to all endpoints without their own policies
+func (r *Router) SetCORS(p *CORSPolicy) {
+	r.md.CORS = p
+}
+
+// SetCORS sets CORS policy of e, which overrides router's
+func (e *Endpoint) SetCORS(p *CORSPolicy) *Endpoint {
+	e.metadata().CORS = p
+	return e
+}
+
+// CORS returns CORS policy of e
+func (e *Endpoint) CORS() *CORSPolicy {
+	return e.metadata().CORS
+}
+
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get(httpvalue.Origin) != "" &&
+		r.Header.Get(httpvalue.ACLRequestMethod) != ""
+}
+
+// preflightPolicy returns CORS policy of the endpoint which would handle the actual request of preflight req,
+// the endpoint, and methods matched by the path. Policy is nil if none applies
+func (s *Server) preflightPolicy(req *Request) (*CORSPolicy, *Endpoint, []string) {
+	np := req.NormalizedPath()
+	method := strings.ToUpper(req.request.Header.Get(httpvalue.ACLRequestMethod))
+	var matched []string
+	for _, m := range s.MatchScopes(np) {
+		if m == "" {
+			// Endpoints bound by Handle match any method
+			m = method
+		}
+		matched = append(matched, m)
+	}
+	if len(matched) == 0 {
+		return nil, nil, nil
+	}
+
+	p := s.md.CORS
+	e, _ := s.Match(method, np)
+	if e != nil && e.CORS() != nil {
+		p = e.CORS()
+	} else if e == nil {
+		// Reject by policy of the path, rather than falling back to server's
+		for _, m := range matched {
+			if pe, _ := s.Match(m, np); pe != nil && pe.CORS() != nil {
+				p = pe.CORS()
+				break
+			}
+		}
+	}
+	return p, e, matched
+}
+
+// hasPreflightPolicy reports whether preflight req is answered by a CORS policy.
+// Otherwise OPTIONS endpoints bound by apps handle it
+func (s *Server) hasPreflightPolicy(req *Request) bool {
+	p, _, _ := s.preflightPolicy(req)
+	return p != nil
+}
+
+// handlePreflight validates requested method and headers against policy of the endpoint which would handle
+// the actual request
+func (s *Server) handlePreflight(ctx context.Context, req *Request) Responder {
+	r := req.request
+	origin := r.Header.Get(httpvalue.Origin)
+	method := strings.ToUpper(r.Header.Get(httpvalue.ACLRequestMethod))
+	p, e, matched := s.preflightPolicy(req)
+	if len(matched) == 0 {
+		return Status(http.StatusNotFound)
+	}
+	if p == nil {
+		return s.handleOptions(ctx, req)
+	}
+
+	return respond.Func(func(ctx context.Context, rw http.ResponseWriter) {
+		h := rw.Header()
+		h.Add(httpvalue.Vary, httpvalue.Origin)
+		h.Add(httpvalue.Vary, httpvalue.ACLRequestMethod)
+		h.Add(httpvalue.Vary, httpvalue.ACLRequestHeaders)
+		if e == nil || !p.AllowOrigin(origin) || !p.allowMethod(method) {
+			rw.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var headers []string
+		for _, v := range strings.Split(r.Header.Get(httpvalue.ACLRequestHeaders), ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			if !p.allowHeader(v) {
+				rw.WriteHeader(http.StatusForbidden)
+				return
+			}
+			headers = append(headers, v)
+		}
+
+		var methods []string
+		for _, m := range matched {
+			if p.allowMethod(m) {
+				methods = append(methods, m)
+			}
+		}
+		h.Set(httpvalue.ACLAllowOrigin, p.allowOriginValue(origin))
+		h.Set(httpvalue.ACLAllowMethods, strings.Join(methods, ","))
+		if len(headers) > 0 {
+			h.Set(httpvalue.ACLAllowHeaders, strings.Join(headers, ","))
+		}
+		if p.AllowCredentials {
+			h.Set(httpvalue.ACLAllowCredentials, "true")
+		}
+		if p.MaxAge > 0 {
+			h.Set(httpvalue.ACLMaxAge, strconv.Itoa(int(p.MaxAge.Seconds())))
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,112 @@
+package wine_test
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/httpvalue"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_CORS(t *testing.T) {
+	s := wine.NewTestServer(t)
+	ok := func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.OK
+	}
+	api := s.Group("api")
+	api.SetCORS(&wine.CORSPolicy{
+		AllowOrigins:       []string{"https://example.com", "https://*.example.com"},
+		AllowOriginRegexps: []*regexp.Regexp{regexp.MustCompile(`^http://localhost:\d+$`)},
+		AllowHeaders:       []string{"X-Token"},
+		ExposeHeaders:      []string{"X-Total"},
+		AllowCredentials:   true,
+		MaxAge:             10 * time.Minute,
+	})
+	api.Get("items/{id}", ok)
+	api.Put("items/{id}", ok)
+	s.Get("private", ok)
+	s.Router.Options("private", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.Text(http.StatusOK, "options")
+	})
+	url := s.Run()
+
+	do := func(method, path string, header map[string]string) *http.Response {
+		req, err := http.NewRequest(method, url+path, nil)
+		require.NoError(t, err)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("Preflight", func(t *testing.T) {
+		resp := do(http.MethodOptions, "/api/items/1", map[string]string{
+			httpvalue.Origin:            "https://a.example.com",
+			httpvalue.ACLRequestMethod:  http.MethodPut,
+			httpvalue.ACLRequestHeaders: "x-token, content-type",
+		})
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		require.Equal(t, "https://a.example.com", resp.Header.Get(httpvalue.ACLAllowOrigin))
+		require.Equal(t, "true", resp.Header.Get(httpvalue.ACLAllowCredentials))
+		require.Equal(t, "x-token,content-type", resp.Header.Get(httpvalue.ACLAllowHeaders))
+		require.ElementsMatch(t, []string{"GET", "PUT"}, splitComma(resp.Header.Get(httpvalue.ACLAllowMethods)))
+		require.Equal(t, "600", resp.Header.Get(httpvalue.ACLMaxAge))
+		require.Contains(t, resp.Header.Values(httpvalue.Vary), httpvalue.Origin)
+	})
+
+	t.Run("PreflightRejected", func(t *testing.T) {
+		for _, h := range []map[string]string{
+			{httpvalue.Origin: "https://example.org", httpvalue.ACLRequestMethod: http.MethodPut},
+			{httpvalue.Origin: "https://example.com", httpvalue.ACLRequestMethod: http.MethodDelete},
+			{httpvalue.Origin: "https://example.com", httpvalue.ACLRequestMethod: http.MethodPut, httpvalue.ACLRequestHeaders: "X-Other"},
+		} {
+			resp := do(http.MethodOptions, "/api/items/1", h)
+			require.Equal(t, http.StatusForbidden, resp.StatusCode, h)
+			require.Empty(t, resp.Header.Get(httpvalue.ACLAllowOrigin))
+		}
+		resp := do(http.MethodOptions, "/api/unknown", map[string]string{
+			httpvalue.Origin:           "https://example.com",
+			httpvalue.ACLRequestMethod: http.MethodGet,
+		})
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("PreflightWithoutPolicy", func(t *testing.T) {
+		// OPTIONS endpoint bound by app handles it
+		resp := do(http.MethodOptions, "/private", map[string]string{
+			httpvalue.Origin:           "https://example.com",
+			httpvalue.ACLRequestMethod: http.MethodGet,
+		})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Empty(t, resp.Header.Get(httpvalue.ACLAllowOrigin))
+		require.Contains(t, resp.Header.Get(httpvalue.ContentType), "text/plain")
+	})
+
+	t.Run("ActualRequest", func(t *testing.T) {
+		resp := do(http.MethodGet, "/api/items/1", map[string]string{httpvalue.Origin: "http://localhost:3000"})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "http://localhost:3000", resp.Header.Get(httpvalue.ACLAllowOrigin))
+		require.Equal(t, "X-Total", resp.Header.Get(httpvalue.ACLExposeHeaders))
+		require.Equal(t, httpvalue.Origin, resp.Header.Get(httpvalue.Vary))
+
+		resp = do(http.MethodGet, "/api/items/1", map[string]string{httpvalue.Origin: "https://example.com.evil.org"})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Empty(t, resp.Header.Get(httpvalue.ACLAllowOrigin))
+
+		resp = do(http.MethodGet, "/private", map[string]string{httpvalue.Origin: "https://example.com"})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Empty(t, resp.Header.Get(httpvalue.ACLAllowOrigin))
+	})
+}
+
+func splitComma(s string) []string {
+	return strings.Split(s, ",")
+}
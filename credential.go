@@ -0,0 +1,204 @@
+package wine
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const defaultHTPasswdReloadInterval = 10 * time.Second
+
+// Parameters of argon2id hashes created by HashPasswordArgon2
+const (
+	argon2Time    = 1
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// dummyHash is compared with passwords of unknown users, so that they take similar time as known users
+var dummyHash = []byte("$2a$10$KoWA55D9tfdYynag.aa3ceqSWMCuCUxXS3MPGk4p2OTCXyCFL698K")
+
+// CredentialStore provides password hashes of users
+type CredentialStore interface {
+	// PasswordHash returns password hash of user, in bcrypt or argon2id format. ok is false if user doesn't exist
+	PasswordHash(ctx context.Context, user string) (hash string, ok bool, err error)
+}
+
+// MapCredentialStore maps users to password hashes
+type MapCredentialStore map[string]string
+
+func (m MapCredentialStore) PasswordHash(_ context.Context, user string) (string, bool, error) {
+	h, ok := m[user]
+	return h, ok, nil
+}
+
+// HashPassword hashes password with bcrypt
+func HashPassword(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("bcrypt: %w", err)
+	}
+	return string(b), nil
+}
+
+// HashPasswordArgon2 hashes password with argon2id, encoded as $argon2id$v=19$m=65536,t=1,p=4$salt$hash
+func HashPasswordArgon2(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// VerifyPassword reports whether password matches hash in bcrypt or argon2id format
+func VerifyPassword(hash, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return verifyArgon2(hash, password)
+	default:
+		return false, errors.New("unsupported password hash")
+	}
+}
+
+func verifyArgon2(hash, password string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, errors.New("invalid argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, fmt.Errorf("unsupported argon2 version %s", parts[2])
+	}
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false, fmt.Errorf("invalid argon2id params: %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, fmt.Errorf("decode salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, fmt.Errorf("decode key: %w", err)
+	}
+	k := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(k, key) == 1, nil
+}
+
+// HTPasswdStore loads users from htpasswd file, with bcrypt or argon2id hashes.
+// The file is reloaded once it changes
+type HTPasswdStore struct {
+	name string
+	// ReloadInterval is the minimum interval of checking changes of the file
+	ReloadInterval time.Duration
+
+	mu        sync.Mutex
+	users     map[string]string
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+var _ CredentialStore = (*HTPasswdStore)(nil)
+
+func NewHTPasswdStore(name string) (*HTPasswdStore, error) {
+	s := &HTPasswdStore{
+		name:           name,
+		ReloadInterval: defaultHTPasswdReloadInterval,
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *HTPasswdStore) PasswordHash(_ context.Context, user string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfNeeded()
+	h, ok := s.users[user]
+	return h, ok, nil
+}
+
+// Reload reloads the file no matter whether it changed
+func (s *HTPasswdStore) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkedAt = time.Now()
+	fi, err := os.Stat(s.name)
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+	return s.load(fi.ModTime())
+}
+
+// reloadIfNeeded reloads the file if it changed. s.mu must be held
+func (s *HTPasswdStore) reloadIfNeeded() {
+	if time.Since(s.checkedAt) < s.ReloadInterval {
+		return
+	}
+	s.checkedAt = time.Now()
+	fi, err := os.Stat(s.name)
+	if err != nil {
+		logger.Errorf("Check htpasswd %s: %v", s.name, err)
+		return
+	}
+	if fi.ModTime().Equal(s.modTime) {
+		return
+	}
+	if err = s.load(fi.ModTime()); err != nil {
+		// Keep the old users until the file changes again
+		s.modTime = fi.ModTime()
+		logger.Errorf("Reload htpasswd %s: %v", s.name, err)
+		return
+	}
+	logger.Infof("Reloaded htpasswd %s", s.name)
+}
+
+func (s *HTPasswdStore) load(modTime time.Time) error {
+	f, err := os.Open(s.name)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+	users := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		i := strings.IndexByte(line, ':')
+		if i <= 0 {
+			return fmt.Errorf("invalid line %d", n)
+		}
+		users[line[:i]] = line[i+1:]
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+	s.users = users
+	s.modTime = modTime
+	return nil
+}
@@ -0,0 +1,94 @@
+package wine_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/ctxutil"
+	"github.com/gopub/wine/httpvalue"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyPassword(t *testing.T) {
+	for _, hash := range []func(string) (string, error){wine.HashPassword, wine.HashPasswordArgon2} {
+		h, err := hash("secret")
+		require.NoError(t, err)
+		ok, err := wine.VerifyPassword(h, "secret")
+		require.NoError(t, err)
+		require.True(t, ok)
+		ok, err = wine.VerifyPassword(h, "Secret")
+		require.NoError(t, err)
+		require.False(t, ok)
+	}
+	_, err := wine.VerifyPassword("secret", "secret")
+	require.Error(t, err)
+}
+
+func TestHTPasswdStore(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "htpasswd")
+	h, err := wine.HashPassword("123")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(name, []byte("# users\ntom:"+h+"\n"), 0600))
+	s, err := wine.NewHTPasswdStore(name)
+	require.NoError(t, err)
+	s.ReloadInterval = 0
+	ctx := context.Background()
+	hash, ok, err := s.PasswordHash(ctx, "tom")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, h, hash)
+
+	require.NoError(t, ioutil.WriteFile(name, []byte("jerry:"+h+"\n"), 0600))
+	modTime := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(name, modTime, modTime))
+	_, ok, err = s.PasswordHash(ctx, "tom")
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, ok, err = s.PasswordHash(ctx, "jerry")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestHashedBasicAuthHandler(t *testing.T) {
+	h, err := wine.HashPasswordArgon2("123")
+	require.NoError(t, err)
+	s := wine.NewTestServer(t)
+	r := s.Use(wine.NewHashedBasicAuthHandler(wine.MapCredentialStore{"tom": h}, &wine.BasicAuthOptions{
+		Realm:           "wine",
+		MaxAttempts:     2,
+		LockoutDuration: time.Minute,
+	}))
+	r.Get("hello", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.Text(http.StatusOK, fmt.Sprintf("hello %s", ctxutil.GetBasicUser(ctx)))
+	})
+	url := s.Run()
+
+	do := func(user, password string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, url+"/hello", nil)
+		require.NoError(t, err)
+		req.SetBasicAuth(user, password)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	require.Equal(t, http.StatusOK, do("tom", "123").StatusCode)
+	resp := do("tom", "456")
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Equal(t, `Basic realm="wine"`, resp.Header.Get("WWW-Authenticate"))
+	require.Equal(t, http.StatusUnauthorized, do("jerry", "123").StatusCode)
+
+	// Locked out after consecutive failures, even with the correct password
+	require.Equal(t, http.StatusUnauthorized, do("tom", "789").StatusCode)
+	resp = do("tom", "123")
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.Equal(t, "60", resp.Header.Get(httpvalue.RetryAfter))
+}
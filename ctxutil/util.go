@@ -2,9 +2,11 @@ package ctxutil
 
 import (
 	"context"
+	htmltemplate "html/template"
 	"net/http"
 
 	"github.com/gopub/wine/httpvalue"
+	"github.com/gopub/wine/trace"
 
 	"github.com/gopub/log"
 	"github.com/gopub/wine/internal/template"
@@ -24,6 +26,7 @@ const (
 	KeySudo
 	KeyRequestHeader
 	KeyBasicUser
+	KeyTemplateFuncs
 
 	keyEnd
 )
@@ -41,11 +44,15 @@ func WithRequestHeader(ctx context.Context, h http.Header) context.Context {
 	return context.WithValue(ctx, KeyRequestHeader, h)
 }
 
+// GetTraceID returns trace id set by WithTraceID, or id of the current trace
 func GetTraceID(ctx context.Context) string {
 	id, ok := ctx.Value(KeyTraceID).(string)
 	if ok {
 		return id
 	}
+	if c := trace.SpanContextFromContext(ctx); c.TraceID.IsValid() {
+		return c.TraceID.String()
+	}
 	return GetRequestHeader(ctx).Get(httpvalue.CustomTraceID)
 }
 
@@ -65,6 +72,24 @@ func WithTemplateManager(ctx context.Context, m *template.Manager) context.Conte
 	return context.WithValue(ctx, KeyTemplateManager, m)
 }
 
+// GetTemplateFuncs returns template funcs of the request, e.g. csrfToken
+func GetTemplateFuncs(ctx context.Context) htmltemplate.FuncMap {
+	m, _ := ctx.Value(KeyTemplateFuncs).(htmltemplate.FuncMap)
+	return m
+}
+
+// WithTemplateFuncs adds template funcs which are only available in templates executed with ctx
+func WithTemplateFuncs(ctx context.Context, funcs htmltemplate.FuncMap) context.Context {
+	m := make(htmltemplate.FuncMap)
+	for k, v := range GetTemplateFuncs(ctx) {
+		m[k] = v
+	}
+	for k, v := range funcs {
+		m[k] = v
+	}
+	return context.WithValue(ctx, KeyTemplateFuncs, m)
+}
+
 func Detach(ctx context.Context) context.Context {
 	newCtx := context.Background()
 	if l := log.FromContext(ctx); l != nil {
@@ -75,6 +100,10 @@ func Detach(ctx context.Context) context.Context {
 			newCtx = context.WithValue(newCtx, k, v)
 		}
 	}
+	// Keep the trace, while span of ctx may end before work with newCtx
+	if c := trace.SpanContextFromContext(ctx); c.IsValid() {
+		newCtx = trace.ContextWithRemoteSpanContext(newCtx, c)
+	}
 	return newCtx
 }
 
@@ -3,6 +3,8 @@ package main
 import (
 	"flag"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/gopub/log"
 
@@ -21,6 +23,25 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	s.Bind(http.MethodPost, "/upload", storage.NewFileWriter(bucket))
+	fw := storage.NewFileWriter(bucket)
+	fw.Policy = &storage.UploadPolicy{
+		AllowedTypes: []string{"image/*", "application/pdf", "text/plain"},
+		MaxSize:      10 << 20,
+	}
+	s.Bind(http.MethodPost, "/upload", fw)
+
+	// Images are oriented and stripped of metadata, and resized on the fly by /img/{name}?w=200&h=200&crop=fill
+	iw := storage.NewImageWriter(bucket)
+	iw.Options = &storage.ImageOptions{}
+	s.Bind(http.MethodPost, "/images", iw)
+	s.Bind(http.MethodGet, "/img/{name}", storage.NewImageResizer(bucket, bucket))
+
+	// Resumable uploads by tus clients
+	tw, err := storage.NewTusWriter(bucket, filepath.Join(os.TempDir(), "wine-tus"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer tw.Close()
+	tw.Route(s.Router, "/files")
 	s.Run(*pAddr)
 }
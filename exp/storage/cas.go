@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/httpvalue"
+)
+
+// casRefsPrefix is prefix of reference records in the underlying bucket
+const casRefsPrefix = ".refs/"
+
+type casRecord struct {
+	Refs int64  `json:"refs"`
+	URL  string `json:"url"`
+	Type string `json:"type,omitempty"`
+}
+
+// ContentAddressedBucket names objects by hex encoded SHA-256 of their contents in the underlying bucket.
+// Identical uploads are stored once, and reference counted so that an object is deleted after all its references are.
+// Records of references are kept in the underlying bucket with prefix .refs/, and are guarded by a mutex,
+// so the underlying bucket must not be shared by multiple processes.
+// It isn't a Bucket as names are decided by contents rather than callers, e.g. it can't be cache of ImageResizer
+type ContentAddressedBucket struct {
+	b  Bucket
+	mu sync.Mutex
+}
+
+var _ Writer = (*ContentAddressedBucket)(nil)
+var _ Reader = (*ContentAddressedBucket)(nil)
+var _ wine.Handler = (*ContentAddressedBucket)(nil)
+
+func NewContentAddressedBucket(b Bucket) *ContentAddressedBucket {
+	return &ContentAddressedBucket{
+		b: b,
+	}
+}
+
+// Store writes content of r if it's new, otherwise adds a reference to the existing object.
+// Returned info is named by the SHA-256 of content
+func (c *ContentAddressedBucket) Store(ctx context.Context, r io.Reader, contentType string) (*ObjectInfo, string, error) {
+	// Stage content in a temporary file as name is known after it's read
+	f, err := ioutil.TempFile("", "wine-cas-*")
+	if err != nil {
+		return nil, "", fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		return nil, "", fmt.Errorf("read: %w", err)
+	}
+	if size == 0 {
+		return nil, "", errors.BadRequest("missing content")
+	}
+	name := hex.EncodeToString(h.Sum(nil))
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return nil, "", fmt.Errorf("seek: %w", err)
+	}
+	if contentType == "" {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(f, head)
+		contentType = httpvalue.DetectContentType(head[:n])
+		if _, err = f.Seek(0, io.SeekStart); err != nil {
+			return nil, "", fmt.Errorf("seek: %w", err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rec, err := c.getRecord(ctx, name)
+	if err != nil && !errors.IsNotExist(err) {
+		return nil, "", err
+	}
+	if rec == nil {
+		u, err := c.b.Put(ctx, name, f, contentType)
+		if err != nil {
+			return nil, "", err
+		}
+		rec = &casRecord{URL: u, Type: contentType}
+	}
+	rec.Refs++
+	if err = c.putRecord(ctx, name, rec); err != nil {
+		return nil, "", err
+	}
+	info, err := c.Stat(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	return info, rec.URL, nil
+}
+
+// Refs returns number of references of object
+func (c *ContentAddressedBucket) Refs(ctx context.Context, name string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rec, err := c.getRecord(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	return rec.Refs, nil
+}
+
+// Write stores content of o and returns its url, name of o is ignored. See Store
+func (c *ContentAddressedBucket) Write(ctx context.Context, o *Object) (string, error) {
+	_, u, err := c.Store(ctx, bytes.NewReader(o.Content), o.Type)
+	return u, err
+}
+
+func (c *ContentAddressedBucket) Read(ctx context.Context, name string) ([]byte, error) {
+	return c.b.Read(ctx, name)
+}
+
+// Stat returns info with the strong ETag of SHA-256 and the type detected while storing
+func (c *ContentAddressedBucket) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	info, err := c.b.Stat(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	info.ETag = `"` + name + `"`
+	if rec, err := c.getRecord(ctx, name); err == nil && rec.Type != "" {
+		info.Type = rec.Type
+	}
+	return info, nil
+}
+
+func (c *ContentAddressedBucket) Open(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error) {
+	return c.b.Open(ctx, name, offset, length)
+}
+
+// Delete removes a reference, and deletes the object once it has no references
+func (c *ContentAddressedBucket) Delete(ctx context.Context, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rec, err := c.getRecord(ctx, name)
+	if err != nil {
+		if errors.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if rec.Refs--; rec.Refs > 0 {
+		return c.putRecord(ctx, name, rec)
+	}
+	if err = c.b.Delete(ctx, name); err != nil {
+		return err
+	}
+	return c.b.Delete(ctx, casRefsPrefix+name)
+}
+
+// List lists objects without reference records, so a page may contain less than limit objects
+func (c *ContentAddressedBucket) List(ctx context.Context, prefix, cursor string, limit int) ([]*ObjectInfo, string, error) {
+	l, next, err := c.b.List(ctx, prefix, cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	objects := l[:0]
+	for _, o := range l {
+		if !strings.HasPrefix(o.Name, casRefsPrefix) {
+			o.ETag = `"` + o.Name + `"`
+			objects = append(objects, o)
+		}
+	}
+	return objects, next, nil
+}
+
+func (c *ContentAddressedBucket) SignURL(ctx context.Context, method, name string, expires time.Duration) (string, error) {
+	return c.b.SignURL(ctx, method, name, expires)
+}
+
+// HandleRequest serves objects named by the last path segment with strong ETags.
+// Objects never change, so they can be cached forever
+func (c *ContentAddressedBucket) HandleRequest(ctx context.Context, req *wine.Request) wine.Responder {
+	r := req.Request()
+	name := path.Base(r.URL.Path)
+	if len(name) != sha256.Size*2 {
+		return wine.Status(http.StatusNotFound)
+	}
+	if _, err := hex.DecodeString(name); err != nil {
+		return wine.Status(http.StatusNotFound)
+	}
+	info, err := c.Stat(ctx, name)
+	if err != nil {
+		return wine.Error(err)
+	}
+	content, err := c.b.Read(ctx, name)
+	if err != nil {
+		return wine.Error(err)
+	}
+	return wine.ResponderFunc(func(ctx context.Context, w http.ResponseWriter) {
+		h := w.Header()
+		// http.ServeContent checks If-None-Match and If-Range against ETag
+		h.Set("ETag", info.ETag)
+		h.Set("Cache-Control", "public, max-age=31536000, immutable")
+		if info.Type != "" {
+			h.Set(httpvalue.ContentType, info.Type)
+		}
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(content))
+	})
+}
+
+func (c *ContentAddressedBucket) getRecord(ctx context.Context, name string) (*casRecord, error) {
+	b, err := c.b.Read(ctx, casRefsPrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	rec := new(casRecord)
+	if err = json.Unmarshal(b, rec); err != nil {
+		return nil, fmt.Errorf("unmarshal record: %w", err)
+	}
+	return rec, nil
+}
+
+func (c *ContentAddressedBucket) putRecord(ctx context.Context, name string, rec *casRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal record: %w", err)
+	}
+	if _, err = c.b.Put(ctx, casRefsPrefix+name, bytes.NewReader(b), httpvalue.JSON); err != nil {
+		return fmt.Errorf("put record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package storage_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/exp/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBucket(t *testing.T) {
+	ctx := context.Background()
+	b := storage.NewMemoryBucket()
+	u, err := b.Write(ctx, &storage.Object{Name: "a.txt", Content: []byte("hello")})
+	require.NoError(t, err)
+	require.Equal(t, "mem://a.txt", u)
+
+	info, err := b.Stat(ctx, "a.txt")
+	require.NoError(t, err)
+	require.Equal(t, int64(5), info.Size)
+	require.True(t, strings.HasPrefix(info.Type, "text/plain"))
+
+	r, err := b.Open(ctx, "a.txt", 1, 3)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "ell", string(data))
+
+	_, err = b.Put(ctx, "b.txt", strings.NewReader("world"), "")
+	require.NoError(t, err)
+	l, next, err := b.List(ctx, "", "", 1)
+	require.NoError(t, err)
+	require.Equal(t, "a.txt", l[0].Name)
+	l, next, err = b.List(ctx, "", next, 1)
+	require.NoError(t, err)
+	require.Equal(t, "b.txt", l[0].Name)
+	require.Empty(t, next)
+
+	require.NoError(t, b.Delete(ctx, "a.txt"))
+	_, err = b.Read(ctx, "a.txt")
+	require.True(t, errors.IsNotExist(err))
+}
+
+func TestContentAddressedBucket(t *testing.T) {
+	ctx := context.Background()
+	disk, err := storage.NewDiskBucket(t.TempDir())
+	require.NoError(t, err)
+	for name, underlying := range map[string]storage.Bucket{"Memory": storage.NewMemoryBucket(), "Disk": disk} {
+		t.Run(name, func(t *testing.T) {
+			c := storage.NewContentAddressedBucket(underlying)
+			sum := sha256.Sum256([]byte("avatar"))
+			hash := hex.EncodeToString(sum[:])
+
+			info, u1, err := c.Store(ctx, strings.NewReader("avatar"), "image/png")
+			require.NoError(t, err)
+			require.Equal(t, hash, info.Name)
+			require.Equal(t, `"`+hash+`"`, info.ETag)
+			require.Equal(t, "image/png", info.Type)
+			u2, err := c.Write(ctx, &storage.Object{Name: "ignored", Content: []byte("avatar")})
+			require.NoError(t, err)
+			require.Equal(t, u1, u2)
+			refs, err := c.Refs(ctx, hash)
+			require.NoError(t, err)
+			require.Equal(t, int64(2), refs)
+
+			l, _, err := c.List(ctx, "", "", 0)
+			require.NoError(t, err)
+			require.Len(t, l, 1)
+
+			require.NoError(t, c.Delete(ctx, hash))
+			_, err = c.Stat(ctx, hash)
+			require.NoError(t, err)
+			require.NoError(t, c.Delete(ctx, hash))
+			_, err = c.Stat(ctx, hash)
+			require.True(t, errors.IsNotExist(err))
+			_, err = c.Refs(ctx, hash)
+			require.True(t, errors.IsNotExist(err))
+		})
+	}
+}
+
+func TestContentAddressedBucket_HandleRequest(t *testing.T) {
+	c := storage.NewContentAddressedBucket(storage.NewMemoryBucket())
+	info, _, err := c.Store(context.Background(), strings.NewReader("<svg></svg>"), "image/svg+xml")
+	require.NoError(t, err)
+	s := wine.NewTestServer(t)
+	s.Bind(http.MethodGet, "objects/{name}", c)
+	url := s.Run() + "/objects/" + info.Name
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "<svg></svg>", string(data))
+	require.Equal(t, "image/svg+xml", resp.Header.Get("Content-Type"))
+	etag := resp.Header.Get("ETag")
+	require.Equal(t, `"`+info.Name+`"`, etag)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, err = http.Get(s.URL + "/objects/unknown")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
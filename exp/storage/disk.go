@@ -2,16 +2,38 @@ package storage
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine"
 )
 
-//DiskBucket implements reader/writer based on local file system
+// DiskBucket implements Bucket based on local file system
 type DiskBucket struct {
 	dir string
+
+	// BaseURL and SignKey are required by SignURL. Signed urls are served by HandleSignedRequest bound to BaseURL
+	BaseURL string
+	SignKey []byte
 }
 
+var _ Bucket = (*DiskBucket)(nil)
+
 func NewDiskBucket(dir string) (*DiskBucket, error) {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, err
@@ -22,10 +44,14 @@ func NewDiskBucket(dir string) (*DiskBucket, error) {
 }
 
 func (b *DiskBucket) Write(ctx context.Context, o *Object) (string, error) {
-	name := filepath.Join(b.dir, o.Name)
+	name := b.path(o.Name)
 	errC := make(chan error, 1)
 	go func() {
 		defer close(errC)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			errC <- err
+			return
+		}
 		errC <- ioutil.WriteFile(name, o.Content, 0644)
 	}()
 	select {
@@ -40,5 +66,216 @@ func (b *DiskBucket) Write(ctx context.Context, o *Object) (string, error) {
 }
 
 func (b *DiskBucket) Read(ctx context.Context, name string) ([]byte, error) {
-	return ioutil.ReadFile(filepath.Join(b.dir, name))
+	data, err := ioutil.ReadFile(b.path(name))
+	return data, notExist(err)
+}
+
+func (b *DiskBucket) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	fi, err := os.Stat(b.path(name))
+	if err != nil {
+		return nil, notExist(err)
+	}
+	if fi.IsDir() {
+		return nil, fmt.Errorf("%s is a directory: %w", name, errors.NotExist)
+	}
+	return b.info(name, fi), nil
+}
+
+func (b *DiskBucket) Open(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error) {
+	f, err := os.Open(b.path(name))
+	if err != nil {
+		return nil, notExist(err)
+	}
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("seek: %w", err)
+	}
+	if length < 0 {
+		return f, nil
+	}
+	return &limitedFile{Reader: io.LimitReader(f, length), Closer: f}, nil
+}
+
+// Put writes into a temporary file which is renamed to name once r is drained, so that readers never see partial objects
+func (b *DiskBucket) Put(ctx context.Context, name string, r io.Reader, contentType string) (string, error) {
+	p := b.path(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return "", err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(p), ".put-*")
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = io.Copy(f, &ctxReader{ctx: ctx, r: r})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("write: %w", err)
+	}
+	if err = os.Chmod(f.Name(), 0644); err != nil {
+		return "", err
+	}
+	if err = os.Rename(f.Name(), p); err != nil {
+		return "", fmt.Errorf("rename: %w", err)
+	}
+	return p, nil
+}
+
+func (b *DiskBucket) Delete(ctx context.Context, name string) error {
+	if err := os.Remove(b.path(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (b *DiskBucket) List(ctx context.Context, prefix, cursor string, limit int) ([]*ObjectInfo, string, error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	var l []*ObjectInfo
+	err := filepath.Walk(b.dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".put-") {
+			return nil
+		}
+		rel, err := filepath.Rel(b.dir, p)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); strings.HasPrefix(name, prefix) && name > cursor {
+			l = append(l, b.info(name, fi))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	// Walk visits files in lexical order of paths, which differs from order of names, e.g. a/b and a-b
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].Name < l[j].Name
+	})
+	if len(l) > limit {
+		l = l[:limit]
+		return l, l[limit-1].Name, nil
+	}
+	return l, "", nil
+}
+
+// SignURL returns BaseURL/name with expiration and signature of method in query
+func (b *DiskBucket) SignURL(ctx context.Context, method, name string, expires time.Duration) (string, error) {
+	if b.BaseURL == "" || len(b.SignKey) == 0 {
+		return "", errors.New("missing BaseURL or SignKey")
+	}
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	exp := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", exp)
+	q.Set("signature", b.sign(method, name, exp))
+	return strings.TrimSuffix(b.BaseURL, "/") + "/" + name + "?" + q.Encode(), nil
+}
+
+// HandleSignedRequest serves GET/HEAD with range support, PUT and DELETE of urls signed by SignURL.
+// It should be bound to path of BaseURL with a wildcard, e.g. files/*
+func (b *DiskBucket) HandleSignedRequest(ctx context.Context, req *wine.Request) wine.Responder {
+	r := req.Request()
+	name := r.URL.Path
+	if u, err := url.Parse(b.BaseURL); err == nil {
+		name = strings.TrimPrefix(name, strings.TrimSuffix(u.Path, "/"))
+	}
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	method := r.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	exp := r.URL.Query().Get("expires")
+	sig, err := hex.DecodeString(r.URL.Query().Get("signature"))
+	if err != nil || !hmac.Equal(sig, b.mac(method, name, exp)) {
+		return wine.Error(errors.Forbidden("invalid signature"))
+	}
+	if t, err := strconv.ParseInt(exp, 10, 64); err != nil || time.Now().Unix() > t {
+		return wine.Error(errors.Forbidden("expired"))
+	}
+
+	switch method {
+	case http.MethodGet:
+		f, err := os.Open(b.path(name))
+		if err != nil {
+			return wine.Error(notExist(err))
+		}
+		fi, err := f.Stat()
+		if err != nil || fi.IsDir() {
+			f.Close()
+			return wine.Error(errors.NotFound("%s not found", name))
+		}
+		return wine.ResponderFunc(func(ctx context.Context, w http.ResponseWriter) {
+			defer f.Close()
+			http.ServeContent(w, r, name, fi.ModTime(), f)
+		})
+	case http.MethodPut:
+		if _, err := b.Put(ctx, name, req.BodyReader(), req.ContentType()); err != nil {
+			return wine.Error(err)
+		}
+		return wine.Status(http.StatusCreated)
+	case http.MethodDelete:
+		return wine.Error(b.Delete(ctx, name))
+	default:
+		return wine.Status(http.StatusMethodNotAllowed)
+	}
+}
+
+func (b *DiskBucket) sign(method, name, expires string) string {
+	return hex.EncodeToString(b.mac(method, name, expires))
+}
+
+func (b *DiskBucket) mac(method, name, expires string) []byte {
+	h := hmac.New(sha256.New, b.SignKey)
+	h.Write([]byte(strings.ToUpper(method) + "\n" + name + "\n" + expires))
+	return h.Sum(nil)
+}
+
+// path returns file path of name, which cannot escape dir
+func (b *DiskBucket) path(name string) string {
+	return filepath.Join(b.dir, filepath.FromSlash(path.Clean("/"+name)))
+}
+
+func (b *DiskBucket) info(name string, fi os.FileInfo) *ObjectInfo {
+	return &ObjectInfo{
+		Name:         name,
+		Size:         fi.Size(),
+		Type:         mime.TypeByExtension(filepath.Ext(name)),
+		ETag:         fmt.Sprintf("%x-%x", fi.ModTime().UnixNano(), fi.Size()),
+		LastModified: fi.ModTime(),
+	}
+}
+
+func notExist(err error) error {
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%v: %w", err, errors.NotExist)
+	}
+	return err
+}
+
+type limitedFile struct {
+	io.Reader
+	io.Closer
+}
+
+// ctxReader stops reading once ctx is done
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
 }
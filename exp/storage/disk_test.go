@@ -0,0 +1,127 @@
+package storage_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/exp/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskBucket(t *testing.T) {
+	ctx := context.Background()
+	b, err := storage.NewDiskBucket(t.TempDir())
+	require.NoError(t, err)
+	for _, name := range []string{"a/b.txt", "a-b.txt", "a/c.txt", "d.txt"} {
+		_, err := b.Put(ctx, name, strings.NewReader("content of "+name), "")
+		require.NoError(t, err)
+	}
+
+	t.Run("Stat", func(t *testing.T) {
+		info, err := b.Stat(ctx, "a/b.txt")
+		require.NoError(t, err)
+		require.Equal(t, int64(len("content of a/b.txt")), info.Size)
+		require.True(t, strings.HasPrefix(info.Type, "text/plain"))
+		_, err = b.Stat(ctx, "missing")
+		require.True(t, errors.IsNotExist(err))
+	})
+
+	t.Run("Open", func(t *testing.T) {
+		r, err := b.Open(ctx, "d.txt", 3, 4)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(r)
+		require.NoError(t, r.Close())
+		require.NoError(t, err)
+		require.Equal(t, "tent", string(data))
+
+		r, err = b.Open(ctx, "d.txt", 11, -1)
+		require.NoError(t, err)
+		data, err = ioutil.ReadAll(r)
+		require.NoError(t, r.Close())
+		require.NoError(t, err)
+		require.Equal(t, "d.txt", string(data))
+	})
+
+	t.Run("List", func(t *testing.T) {
+		l, next, err := b.List(ctx, "a", "", 2)
+		require.NoError(t, err)
+		require.Len(t, l, 2)
+		require.Equal(t, "a-b.txt", l[0].Name)
+		require.Equal(t, "a/b.txt", l[1].Name)
+		l, next, err = b.List(ctx, "a", next, 2)
+		require.NoError(t, err)
+		require.Len(t, l, 1)
+		require.Equal(t, "a/c.txt", l[0].Name)
+		require.Empty(t, next)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, b.Delete(ctx, "a/c.txt"))
+		require.NoError(t, b.Delete(ctx, "a/c.txt"))
+		_, err := b.Read(ctx, "a/c.txt")
+		require.True(t, errors.IsNotExist(err))
+	})
+
+	t.Run("Escape", func(t *testing.T) {
+		_, err := b.Put(ctx, "../../escaped.txt", strings.NewReader("x"), "")
+		require.NoError(t, err)
+		data, err := b.Read(ctx, "escaped.txt")
+		require.NoError(t, err)
+		require.Equal(t, "x", string(data))
+	})
+}
+
+func TestDiskBucket_SignURL(t *testing.T) {
+	ctx := context.Background()
+	b, err := storage.NewDiskBucket(t.TempDir())
+	require.NoError(t, err)
+	_, err = b.SignURL(ctx, http.MethodGet, "a.txt", time.Minute)
+	require.Error(t, err)
+
+	s := wine.NewTestServer(t)
+	s.Handle("files/*", b.HandleSignedRequest)
+	url := s.Run()
+	b.BaseURL = url + "/files"
+	b.SignKey = []byte("key")
+
+	u, err := b.SignURL(ctx, http.MethodPut, "a.txt", time.Minute)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPut, u, strings.NewReader("hello"))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// PUT signature doesn't grant GET
+	resp, err = http.Get(u)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	u, err = b.SignURL(ctx, http.MethodGet, "a.txt", time.Minute)
+	require.NoError(t, err)
+	req, err = http.NewRequest(http.MethodGet, u, nil)
+	require.NoError(t, err)
+	req.Header.Set("Range", "bytes=1-3")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	require.Equal(t, "ell", string(data))
+
+	u, err = b.SignURL(ctx, http.MethodGet, "a.txt", -time.Minute)
+	require.NoError(t, err)
+	resp, err = http.Get(u)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
@@ -41,6 +41,9 @@ func (r *FileReader) HandleRequest(ctx context.Context, req *wine.Request) wine.
 
 type FileWriter struct {
 	w Writer
+
+	// Policy validates objects before they are written, nil accepts all non-empty objects
+	Policy *UploadPolicy
 }
 
 var _ wine.Handler = (*FileWriter)(nil)
@@ -59,11 +62,7 @@ func (w *FileWriter) HandleRequest(ctx context.Context, req *wine.Request) wine.
 }
 
 func (w *FileWriter) saveBody(ctx context.Context, body []byte) wine.Responder {
-	o, err := NewObject(body)
-	if err != nil {
-		return wine.Error(err)
-	}
-	url, err := w.w.Write(ctx, o)
+	url, err := w.write(ctx, &Object{Content: body})
 	if err != nil {
 		return wine.Error(err)
 	}
@@ -83,11 +82,11 @@ func (w *FileWriter) saveMultipart(ctx context.Context, form *multipart.Form) wi
 			if err != nil {
 				return wine.Error(err)
 			}
-			o, err := NewObject(b)
-			if err != nil {
-				return wine.Error(err)
+			o := &Object{Content: b}
+			if w.Policy != nil && w.Policy.KeepFilename {
+				o.Name = fh.Filename
 			}
-			url, err := w.w.Write(ctx, o)
+			url, err := w.write(ctx, o)
 			if err != nil {
 				return wine.Error(err)
 			}
@@ -99,3 +98,15 @@ func (w *FileWriter) saveMultipart(ctx context.Context, form *multipart.Form) wi
 	}
 	return wine.JSON(http.StatusOK, urls)
 }
+
+func (w *FileWriter) write(ctx context.Context, o *Object) (string, error) {
+	if w.Policy != nil {
+		if err := w.Policy.Check(ctx, o); err != nil {
+			return "", err
+		}
+	}
+	if err := o.Validate(); err != nil {
+		return "", err
+	}
+	return w.w.Write(ctx, o)
+}
@@ -2,11 +2,18 @@ package gcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"path/filepath"
+	"time"
 
 	"cloud.google.com/go/storage"
+	winerrors "github.com/gopub/errors"
 	winestorage "github.com/gopub/wine/exp/storage"
+	"google.golang.org/api/iterator"
 )
 
 var DefaultACL = []storage.ACLRule{{
@@ -20,9 +27,13 @@ type Bucket struct {
 	acl          []storage.ACLRule
 	cacheControl string
 	baseURL      string
+
+	// GoogleAccessID and PrivateKey of a service account are required by SignURL
+	GoogleAccessID string
+	PrivateKey     []byte
 }
 
-var _ winestorage.Writer = (*Bucket)(nil)
+var _ winestorage.Bucket = (*Bucket)(nil)
 
 func NewBucket(name, baseURL, cacheControl string, handle *storage.BucketHandle, acl []storage.ACLRule) *Bucket {
 	return &Bucket{
@@ -52,3 +63,106 @@ func (b *Bucket) Write(ctx context.Context, obj *winestorage.Object) (string, er
 	}
 	return filepath.Join(b.baseURL, obj.Name), nil
 }
+
+func (b *Bucket) Read(ctx context.Context, name string) ([]byte, error) {
+	r, err := b.Open(ctx, name, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func (b *Bucket) Stat(ctx context.Context, name string) (*winestorage.ObjectInfo, error) {
+	attrs, err := b.handle.Object(name).Attrs(ctx)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return toObjectInfo(attrs), nil
+}
+
+func (b *Bucket) Open(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error) {
+	if length < 0 {
+		length = -1
+	}
+	r, err := b.handle.Object(name).NewRangeReader(ctx, offset, length)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return r, nil
+}
+
+func (b *Bucket) Put(ctx context.Context, name string, r io.Reader, contentType string) (string, error) {
+	wc := b.handle.Object(name).NewWriter(ctx)
+	wc.ACL = b.acl
+	wc.ContentType = contentType
+	wc.CacheControl = b.cacheControl
+	if _, err := io.Copy(wc, r); err != nil {
+		wc.Close()
+		return "", fmt.Errorf("write: %w", err)
+	}
+	if err := wc.Close(); err != nil {
+		return "", fmt.Errorf("close: %w", err)
+	}
+	return filepath.Join(b.baseURL, name), nil
+}
+
+func (b *Bucket) Delete(ctx context.Context, name string) error {
+	if err := b.handle.Object(name).Delete(ctx); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (b *Bucket) List(ctx context.Context, prefix, cursor string, limit int) ([]*winestorage.ObjectInfo, string, error) {
+	if limit <= 0 {
+		limit = winestorage.DefaultListLimit
+	}
+	it := b.handle.Objects(ctx, &storage.Query{Prefix: prefix})
+	var attrs []*storage.ObjectAttrs
+	next, err := iterator.NewPager(it, limit, cursor).NextPage(&attrs)
+	if err != nil {
+		return nil, "", err
+	}
+	l := make([]*winestorage.ObjectInfo, len(attrs))
+	for i, a := range attrs {
+		l[i] = toObjectInfo(a)
+	}
+	return l, next, nil
+}
+
+// SignURL signs GET, PUT or DELETE requests with GoogleAccessID and PrivateKey
+func (b *Bucket) SignURL(ctx context.Context, method, name string, expires time.Duration) (string, error) {
+	switch method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+	default:
+		return "", fmt.Errorf("cannot sign method %s", method)
+	}
+	if b.GoogleAccessID == "" || len(b.PrivateKey) == 0 {
+		return "", errors.New("missing GoogleAccessID or PrivateKey")
+	}
+	return storage.SignedURL(b.name, name, &storage.SignedURLOptions{
+		GoogleAccessID: b.GoogleAccessID,
+		PrivateKey:     b.PrivateKey,
+		Method:         method,
+		Expires:        time.Now().Add(expires),
+		Scheme:         storage.SigningSchemeV4,
+	})
+}
+
+func toObjectInfo(attrs *storage.ObjectAttrs) *winestorage.ObjectInfo {
+	return &winestorage.ObjectInfo{
+		Name:         attrs.Name,
+		Size:         attrs.Size,
+		Type:         attrs.ContentType,
+		ETag:         attrs.Etag,
+		LastModified: attrs.Updated,
+	}
+}
+
+func notExist(err error) error {
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("%v: %w", err, winerrors.NotExist)
+	}
+	return err
+}
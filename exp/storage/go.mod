@@ -8,11 +8,13 @@ require (
 	github.com/gopub/errors v0.1.7
 	github.com/gopub/wine v1.38.1
 	github.com/gopub/wine/httpvalue v0.1.4
+	github.com/stretchr/testify v1.6.1
 	golang.org/x/image v0.0.0-20201208152932-35266b937fa6
 )
 
-//replace (
-//	github.com/gopub/wine => ../../
-//	github.com/gopub/wine/httpvalue => ../../httpvalue
-//	github.com/gopub/wine/router => ../../router
-//)
+replace (
+	github.com/gopub/wine => ../../
+	github.com/gopub/wine/httpvalue => ../../httpvalue
+	github.com/gopub/wine/router => ../../router
+	github.com/gopub/wine/urlutil => ../../urlutil
+)
@@ -42,16 +42,33 @@ func (t *ThumbnailOption) Validate() error {
 	return nil
 }
 
+type imageVariant struct {
+	suffix  string
+	options *ImageOptions
+}
+
 type ImageWriter struct {
 	w          Writer
 	thumbnails []*ThumbnailOption
+	variants   []*imageVariant
+
+	// Options processes originals before they are written. Nil writes originals as they are, while
+	// &ImageOptions{} corrects orientation of EXIF and strips metadata e.g. GPS by re-encoding them
+	Options *ImageOptions
+
+	// Policy validates uploads before they are decoded, nil accepts all images
+	Policy *UploadPolicy
+
+	// MaxPixels limits width x height of uploads, DefaultMaxImagePixels by default, negative means no limit
+	MaxPixels int
 }
 
 var _ wine.Handler = (*ImageWriter)(nil)
 
 func NewImageWriter(w Writer) *ImageWriter {
 	return &ImageWriter{
-		w: w,
+		w:         w,
+		MaxPixels: DefaultMaxImagePixels,
 	}
 }
 
@@ -67,22 +84,49 @@ func (w *ImageWriter) AddThumbnailOptions(thumbnails ...*ThumbnailOption) error
 	return err
 }
 
+// AddVariant writes a derivative processed with o for each image, which is named by image name and suffix,
+// e.g. avatar-square for suffix square
+func (w *ImageWriter) AddVariant(suffix string, o *ImageOptions) error {
+	if suffix = strings.TrimSpace(suffix); suffix == "" {
+		return errors.New("missing suffix")
+	}
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	w.variants = append(w.variants, &imageVariant{suffix: suffix, options: o})
+	return nil
+}
+
 func (w *ImageWriter) Write(ctx context.Context, name string, data []byte) (string, error) {
 	if name = strings.TrimSpace(name); name == "" {
 		name = uuid.NewString()
 	}
-	img, format, err := image.Decode(bytes.NewReader(data))
-	if err != nil {
-		if errors.Is(err, image.ErrFormat) {
-			return "", errors.BadRequest("decode: %v", err)
+	if w.Policy != nil {
+		upload := &Object{Name: name, Content: data}
+		if err := w.Policy.Check(ctx, upload); err != nil {
+			return "", err
 		}
-		return "", fmt.Errorf("decode: %w", err)
+		name = upload.Name
+	}
+	// Decode once for the original, thumbnails and variants
+	img, format, err := decodeImage(data, w.MaxPixels)
+	if err != nil {
+		return "", err
 	}
 	o := &Object{
 		Name:    name,
 		Content: data,
 		Type:    mime.TypeByExtension("." + format),
 	}
+	if w.Options != nil {
+		if err = w.Options.Validate(); err != nil {
+			return "", err
+		}
+		o.Content, o.Type, err = processImage(img, format, w.Options)
+		if err != nil {
+			return "", fmt.Errorf("process: %w", err)
+		}
+	}
 	if err = o.Validate(); err != nil {
 		return "", fmt.Errorf("validate: %w", err)
 	}
@@ -96,6 +140,20 @@ func (w *ImageWriter) Write(ctx context.Context, name string, data []byte) (stri
 			return "", fmt.Errorf("thumbnail: %#v, %w", t, err)
 		}
 	}
+	for _, v := range w.variants {
+		content, typ, err := processImage(img, format, v.options)
+		if err != nil {
+			return "", fmt.Errorf("variant %s: %w", v.suffix, err)
+		}
+		vo := &Object{
+			Name:    o.Name + "-" + v.suffix,
+			Content: content,
+			Type:    typ,
+		}
+		if _, err = w.w.Write(ctx, vo); err != nil {
+			return "", fmt.Errorf("variant %s: %w", v.suffix, err)
+		}
+	}
 	return url, nil
 }
 
@@ -181,12 +239,9 @@ func getImageThumbnail(img image.Image, t *ThumbnailOption) ([]byte, error) {
 }
 
 func GenerateThumbnail(origin []byte, w, h int) ([]byte, error) {
-	img, _, err := image.Decode(bytes.NewReader(origin))
+	img, _, err := decodeImage(origin, 0)
 	if err != nil {
-		if errors.Is(err, image.ErrFormat) {
-			return nil, errors.BadRequest("decode: %v", err)
-		}
-		return nil, fmt.Errorf("decode: %w", err)
+		return nil, err
 	}
 	return getImageThumbnail(img, &ThumbnailOption{Width: w, Height: h, Quality: 100})
 }
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine/httpvalue"
+)
+
+type memoryObject struct {
+	content  []byte
+	typ      string
+	modified time.Time
+}
+
+// MemoryBucket implements Bucket in memory, which is useful in tests
+type MemoryBucket struct {
+	mu      sync.RWMutex
+	objects map[string]*memoryObject
+
+	// BaseURL is prefix of urls returned by Write, Put and SignURL
+	BaseURL string
+}
+
+var _ Bucket = (*MemoryBucket)(nil)
+
+func NewMemoryBucket() *MemoryBucket {
+	return &MemoryBucket{
+		objects: make(map[string]*memoryObject),
+		BaseURL: "mem://",
+	}
+}
+
+func (b *MemoryBucket) Write(ctx context.Context, o *Object) (string, error) {
+	return b.Put(ctx, o.Name, bytes.NewReader(o.Content), o.Type)
+}
+
+func (b *MemoryBucket) Read(ctx context.Context, name string) ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	o, ok := b.objects[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, errors.NotExist)
+	}
+	return append([]byte(nil), o.content...), nil
+}
+
+func (b *MemoryBucket) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	o, ok := b.objects[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, errors.NotExist)
+	}
+	return o.info(name), nil
+}
+
+func (b *MemoryBucket) Open(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error) {
+	data, err := b.Read(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	data = data[offset:]
+	if length >= 0 && length < int64(len(data)) {
+		data = data[:length]
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (b *MemoryBucket) Put(ctx context.Context, name string, r io.Reader, contentType string) (string, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("read: %w", err)
+	}
+	if contentType == "" {
+		contentType = httpvalue.DetectContentType(content)
+	}
+	b.mu.Lock()
+	b.objects[name] = &memoryObject{
+		content:  content,
+		typ:      contentType,
+		modified: time.Now(),
+	}
+	b.mu.Unlock()
+	return b.BaseURL + name, nil
+}
+
+func (b *MemoryBucket) Delete(ctx context.Context, name string) error {
+	b.mu.Lock()
+	delete(b.objects, name)
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *MemoryBucket) List(ctx context.Context, prefix, cursor string, limit int) ([]*ObjectInfo, string, error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var names []string
+	for name := range b.objects {
+		if strings.HasPrefix(name, prefix) && name > cursor {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var next string
+	if len(names) > limit {
+		names = names[:limit]
+		next = names[limit-1]
+	}
+	l := make([]*ObjectInfo, len(names))
+	for i, name := range names {
+		l[i] = b.objects[name].info(name)
+	}
+	return l, next, nil
+}
+
+// SignURL returns url with method and expiration in query, which is not really signed
+func (b *MemoryBucket) SignURL(ctx context.Context, method, name string, expires time.Duration) (string, error) {
+	q := url.Values{}
+	q.Set("method", method)
+	q.Set("expires", strconv.FormatInt(time.Now().Add(expires).Unix(), 10))
+	return b.BaseURL + name + "?" + q.Encode(), nil
+}
+
+func (o *memoryObject) info(name string) *ObjectInfo {
+	sum := sha256.Sum256(o.content)
+	return &ObjectInfo{
+		Name:         name,
+		Size:         int64(len(o.content)),
+		Type:         o.typ,
+		ETag:         hex.EncodeToString(sum[:]),
+		LastModified: o.modified,
+	}
+}
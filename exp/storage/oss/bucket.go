@@ -3,8 +3,14 @@ package oss
 import (
 	"bytes"
 	"context"
+	stderrors "errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/gopub/errors"
@@ -23,7 +29,7 @@ type Bucket struct {
 	baseURL      string
 }
 
-var _ storage.Writer = (*Bucket)(nil)
+var _ storage.Bucket = (*Bucket)(nil)
 
 func NewBucket(endpoint, keyID, keySecret, name string) (*Bucket, error) {
 	if endpoint == "" {
@@ -44,18 +50,18 @@ func NewBucket(endpoint, keyID, keySecret, name string) (*Bucket, error) {
 
 	u, err := url.Parse(endpoint)
 	if err != nil {
-		return nil, errors.BadRequest("invalid endpoint: %w", err)
+		return nil, errors.BadRequest("invalid endpoint: %v", err)
 	}
 
 	u.Host = fmt.Sprintf("%s.%s", name, u.Host)
 	client, err := oss.New(endpoint, keyID, keySecret)
 	if err != nil {
-		fmt.Errorf("create oss client: %w", err)
+		return nil, fmt.Errorf("create oss client: %w", err)
 	}
 
 	b, err := client.Bucket(name)
 	if err != nil {
-		fmt.Errorf("create oss bucket: %w", err)
+		return nil, fmt.Errorf("create oss bucket: %w", err)
 	}
 
 	return &Bucket{
@@ -103,3 +109,136 @@ func (b *Bucket) Write(ctx context.Context, obj *storage.Object) (string, error)
 		return urlutil.Join(b.baseURL, obj.Name), nil
 	}
 }
+
+func (b *Bucket) Read(ctx context.Context, name string) ([]byte, error) {
+	r, err := b.Open(ctx, name, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func (b *Bucket) Stat(ctx context.Context, name string) (*storage.ObjectInfo, error) {
+	var h http.Header
+	err := do(ctx, func() (err error) {
+		h, err = b.bucket.GetObjectDetailedMeta(name)
+		return err
+	})
+	if err != nil {
+		return nil, notExist(err)
+	}
+	size, _ := strconv.ParseInt(h.Get(oss.HTTPHeaderContentLength), 10, 64)
+	modified, _ := http.ParseTime(h.Get(oss.HTTPHeaderLastModified))
+	return &storage.ObjectInfo{
+		Name:         name,
+		Size:         size,
+		Type:         h.Get(oss.HTTPHeaderContentType),
+		ETag:         h.Get(oss.HTTPHeaderEtag),
+		LastModified: modified,
+	}, nil
+}
+
+func (b *Bucket) Open(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error) {
+	var options []oss.Option
+	if length < 0 && offset > 0 {
+		// oss.Range requires end of range
+		info, err := b.Stat(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		length = info.Size - offset
+	}
+	if length >= 0 {
+		options = append(options, oss.Range(offset, offset+length-1))
+	}
+	var r io.ReadCloser
+	err := do(ctx, func() (err error) {
+		r, err = b.bucket.GetObject(name, options...)
+		return err
+	})
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return r, nil
+}
+
+func (b *Bucket) Put(ctx context.Context, name string, r io.Reader, contentType string) (string, error) {
+	options := []oss.Option{oss.ObjectACL(b.ACL), oss.CacheControl(b.CacheControl)}
+	if contentType != "" {
+		options = append(options, oss.ContentType(contentType))
+	}
+	err := do(ctx, func() error {
+		return b.bucket.PutObject(name, r, options...)
+	})
+	if err != nil {
+		return "", fmt.Errorf("put object: %w", err)
+	}
+	return urlutil.Join(b.baseURL, name), nil
+}
+
+func (b *Bucket) Delete(ctx context.Context, name string) error {
+	return do(ctx, func() error {
+		return b.bucket.DeleteObject(name)
+	})
+}
+
+func (b *Bucket) List(ctx context.Context, prefix, cursor string, limit int) ([]*storage.ObjectInfo, string, error) {
+	if limit <= 0 {
+		limit = storage.DefaultListLimit
+	}
+	var res oss.ListObjectsResult
+	err := do(ctx, func() (err error) {
+		res, err = b.bucket.ListObjects(oss.Prefix(prefix), oss.Marker(cursor), oss.MaxKeys(limit))
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	l := make([]*storage.ObjectInfo, len(res.Objects))
+	for i, o := range res.Objects {
+		l[i] = &storage.ObjectInfo{
+			Name:         o.Key,
+			Size:         o.Size,
+			ETag:         o.ETag,
+			LastModified: o.LastModified,
+		}
+	}
+	if !res.IsTruncated {
+		return l, "", nil
+	}
+	return l, res.NextMarker, nil
+}
+
+// SignURL signs GET, PUT or DELETE requests
+func (b *Bucket) SignURL(ctx context.Context, method, name string, expires time.Duration) (string, error) {
+	switch method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+		return b.bucket.SignURL(name, oss.HTTPMethod(method), int64(expires/time.Second))
+	default:
+		return "", fmt.Errorf("cannot sign method %s", method)
+	}
+}
+
+// do runs f in another goroutine as oss sdk doesn't support context
+func do(ctx context.Context, f func() error) error {
+	errC := make(chan error, 1)
+	go func() {
+		defer close(errC)
+		errC <- f()
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errC:
+		return err
+	}
+}
+
+func notExist(err error) error {
+	var se oss.ServiceError
+	if stderrors.As(err, &se) && se.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%v: %w", err, errors.NotExist)
+	}
+	return err
+}
@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/disintegration/imaging"
+	"github.com/gopub/errors"
+	"github.com/gopub/wine/httpvalue"
+)
+
+// CropMode decides how an image is resized into both width and height
+type CropMode string
+
+const (
+	// CropFit scales image to fit within the box keeping aspect ratio, images are never upscaled
+	CropFit CropMode = "fit"
+	// CropFill scales image to cover the box and crops the center
+	CropFill CropMode = "fill"
+	// CropSmart is similar with CropFill, but crops the area with most details, e.g. edges of faces and objects
+	CropSmart CropMode = "smart"
+)
+
+// ImageFormat is format of processed images
+type ImageFormat string
+
+const (
+	JPEG ImageFormat = "jpeg"
+	PNG  ImageFormat = "png"
+	// GIF keeps the first frame only
+	GIF ImageFormat = "gif"
+)
+
+const (
+	// DefaultImageQuality is the JPEG quality if it's not specified
+	DefaultImageQuality = 85
+	// DefaultMaxImagePixels is the default limit of width x height of source images, which takes 160MB after decoding
+	DefaultMaxImagePixels = 40 << 20
+)
+
+var imageFormats = map[ImageFormat]struct {
+	format      imaging.Format
+	contentType string
+}{
+	JPEG: {imaging.JPEG, httpvalue.JPEG},
+	PNG:  {imaging.PNG, httpvalue.PNG},
+	GIF:  {imaging.GIF, httpvalue.GIF},
+}
+
+// ImageOptions declares how an image is processed. Orientation of EXIF is always corrected, and metadata e.g. GPS
+// is always stripped as images are re-encoded
+type ImageOptions struct {
+	// Width and Height are bounds of processed image, zero means it's scaled by the other one keeping aspect ratio.
+	// Image is not resized if both are zero
+	Width  int
+	Height int
+	// Crop applies if both Width and Height are set, CropFit by default
+	Crop CropMode
+	// Format is format of processed image. Empty format keeps JPEG, PNG or GIF, and converts others into JPEG
+	Format ImageFormat
+	// Quality of JPEG in range (0, 100], DefaultImageQuality by default
+	Quality int
+	// MaxPixels limits width x height of source images, which are read from headers so that decompression bombs
+	// are rejected before decoding. DefaultMaxImagePixels by default, negative means no limit
+	MaxPixels int
+}
+
+func (o *ImageOptions) Validate() error {
+	if o.Width < 0 || o.Height < 0 {
+		return errors.BadRequest("invalid size %dx%d", o.Width, o.Height)
+	}
+	switch o.Crop {
+	case "", CropFit, CropFill, CropSmart:
+	default:
+		return errors.BadRequest("invalid crop mode %s", o.Crop)
+	}
+	if _, ok := imageFormats[o.Format]; !ok && o.Format != "" {
+		return errors.BadRequest("invalid format %s", o.Format)
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return errors.BadRequest("invalid quality %d, expected (0, 100]", o.Quality)
+	}
+	return nil
+}
+
+// ProcessImage decodes data, processes it with o, and returns the encoded image and its content type
+func ProcessImage(data []byte, o *ImageOptions) ([]byte, string, error) {
+	if err := o.Validate(); err != nil {
+		return nil, "", err
+	}
+	img, srcFormat, err := decodeImage(data, o.MaxPixels)
+	if err != nil {
+		return nil, "", err
+	}
+	return processImage(img, srcFormat, o)
+}
+
+// processImage processes img decoded from srcFormat, so that an upload is decoded once for all derivatives
+func processImage(img image.Image, srcFormat string, o *ImageOptions) ([]byte, string, error) {
+	format := o.Format
+	if format == "" {
+		format = JPEG
+		if _, ok := imageFormats[ImageFormat(srcFormat)]; ok {
+			format = ImageFormat(srcFormat)
+		}
+	}
+	quality := o.Quality
+	if quality == 0 {
+		quality = DefaultImageQuality
+	}
+
+	img = resizeImage(img, o)
+	var buf bytes.Buffer
+	f := imageFormats[format]
+	if err := imaging.Encode(&buf, img, f.format, imaging.JPEGQuality(quality)); err != nil {
+		return nil, "", fmt.Errorf("encode %s: %w", format, err)
+	}
+	return buf.Bytes(), f.contentType, nil
+}
+
+// decodeImage decodes data with orientation of EXIF corrected, and returns its format, e.g. jpeg.
+// Images larger than maxPixels are rejected by their headers, zero means DefaultMaxImagePixels
+func decodeImage(data []byte, maxPixels int) (image.Image, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", errors.BadRequest("decode: %v", err)
+	}
+	if maxPixels == 0 {
+		maxPixels = DefaultMaxImagePixels
+	}
+	if maxPixels > 0 && int64(cfg.Width)*int64(cfg.Height) > int64(maxPixels) {
+		return nil, "", errors.BadRequest("image size %dx%d exceeds %d pixels", cfg.Width, cfg.Height, maxPixels)
+	}
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, "", errors.BadRequest("decode: %v", err)
+	}
+	return img, format, nil
+}
+
+func resizeImage(img image.Image, o *ImageOptions) image.Image {
+	dx, dy := img.Bounds().Dx(), img.Bounds().Dy()
+	switch {
+	case o.Width == 0 && o.Height == 0:
+		return img
+	case o.Width == 0 || o.Height == 0:
+		if (o.Width == 0 || dx <= o.Width) && (o.Height == 0 || dy <= o.Height) {
+			return img
+		}
+		return imaging.Resize(img, o.Width, o.Height, imaging.Lanczos)
+	}
+	switch o.Crop {
+	case CropFill:
+		return imaging.Fill(img, o.Width, o.Height, imaging.Center, imaging.Lanczos)
+	case CropSmart:
+		return smartCrop(img, o.Width, o.Height)
+	default:
+		return imaging.Fit(img, o.Width, o.Height, imaging.Lanczos)
+	}
+}
+
+// smartCrop scales img to cover w x h, then crops the window whose edges are strongest
+func smartCrop(img image.Image, w, h int) image.Image {
+	dx, dy := img.Bounds().Dx(), img.Bounds().Dy()
+	horizontal := dx*h > dy*w
+	var scaled *image.NRGBA
+	if horizontal {
+		scaled = imaging.Resize(img, 0, h, imaging.Lanczos)
+	} else {
+		scaled = imaging.Resize(img, w, 0, imaging.Lanczos)
+	}
+	sw, sh := scaled.Bounds().Dx(), scaled.Bounds().Dy()
+	gray := imaging.Grayscale(scaled)
+	lum := func(x, y int) int {
+		return int(color.GrayModel.Convert(gray.At(x, y)).(color.Gray).Y)
+	}
+
+	// energy of columns or rows along the axis to crop
+	n, size := sh, h
+	if horizontal {
+		n, size = sw, w
+	}
+	energy := make([]int, n+1)
+	for x := 0; x < sw-1; x++ {
+		for y := 0; y < sh-1; y++ {
+			v := lum(x, y)
+			e := abs(lum(x+1, y)-v) + abs(lum(x, y+1)-v)
+			if horizontal {
+				energy[x+1] += e
+			} else {
+				energy[y+1] += e
+			}
+		}
+	}
+	for i := 1; i <= n; i++ {
+		energy[i] += energy[i-1]
+	}
+	best, bestEnergy := (n-size)/2, -1
+	for i := 0; i+size <= n; i++ {
+		if e := energy[i+size] - energy[i]; e > bestEnergy {
+			best, bestEnergy = i, e
+		}
+	}
+	if horizontal {
+		return imaging.Crop(scaled, image.Rect(best, 0, best+w, sh))
+	}
+	return imaging.Crop(scaled, image.Rect(0, best, sw, best+h))
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
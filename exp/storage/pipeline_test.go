@@ -0,0 +1,255 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/exp/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// newImage returns a w x h image whose right quarter is a checkerboard and the rest is plain gray
+func newImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			c := color.NRGBA{R: 128, G: 128, B: 128, A: 255}
+			if x >= w*3/4 && (x/4+y/4)%2 == 0 {
+				c = color.NRGBA{A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// encodeRotatedJPEG returns JPEG with EXIF orientation 6, i.e. it should be rotated 90 degrees clockwise
+func encodeRotatedJPEG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	var tiff bytes.Buffer
+	tiff.WriteString("MM\x00\x2a")
+	for _, v := range []interface{}{
+		uint32(8),                            // offset of IFD
+		uint16(1),                            // number of entries
+		uint16(0x0112), uint16(3), uint32(1), // orientation, SHORT, count
+		uint16(6), uint16(0), // value
+		uint32(0), // no next IFD
+	} {
+		require.NoError(t, binary.Write(&tiff, binary.BigEndian, v))
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	app1 := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(payload)+2))
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), append(app1, payload...)...), data[2:]...)
+}
+
+// encodeBomb returns PNG whose header claims w x h, but which has no valid pixels
+func encodeBomb(t *testing.T, w, h int) []byte {
+	data := encodePNG(t, newImage(1, 1))
+	// IHDR follows 8 bytes of signature: length, type, width, height, ..., crc
+	ihdr := data[8 : 8+4+4+13+4]
+	binary.BigEndian.PutUint32(ihdr[8:], uint32(w))
+	binary.BigEndian.PutUint32(ihdr[12:], uint32(h))
+	binary.BigEndian.PutUint32(ihdr[21:], crc32.ChecksumIEEE(ihdr[4:21]))
+	return data
+}
+
+func decodeSize(t *testing.T, data []byte) (int, int, string) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	return cfg.Width, cfg.Height, format
+}
+
+func TestProcessImage(t *testing.T) {
+	src := encodePNG(t, newImage(400, 200))
+
+	t.Run("Fit", func(t *testing.T) {
+		data, typ, err := storage.ProcessImage(src, &storage.ImageOptions{Width: 100, Height: 100})
+		require.NoError(t, err)
+		require.Equal(t, "image/png", typ)
+		w, h, _ := decodeSize(t, data)
+		require.Equal(t, []int{100, 50}, []int{w, h})
+	})
+
+	t.Run("Fill", func(t *testing.T) {
+		data, _, err := storage.ProcessImage(src, &storage.ImageOptions{Width: 100, Height: 100, Crop: storage.CropFill})
+		require.NoError(t, err)
+		w, h, _ := decodeSize(t, data)
+		require.Equal(t, []int{100, 100}, []int{w, h})
+	})
+
+	t.Run("Smart", func(t *testing.T) {
+		data, _, err := storage.ProcessImage(src, &storage.ImageOptions{Width: 100, Height: 100, Crop: storage.CropSmart})
+		require.NoError(t, err)
+		img, err := png.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+		require.Equal(t, image.Rect(0, 0, 100, 100), img.Bounds())
+		// window is moved to the checkerboard on the right, while center of the image is plain
+		var details int
+		for x := 0; x < 100; x++ {
+			if r, _, _, _ := img.At(x, 0).RGBA(); r < 0x6000 {
+				details++
+			}
+		}
+		require.NotZero(t, details)
+	})
+
+	t.Run("Format", func(t *testing.T) {
+		data, typ, err := storage.ProcessImage(src, &storage.ImageOptions{Width: 40, Format: storage.JPEG, Quality: 60})
+		require.NoError(t, err)
+		require.Equal(t, "image/jpeg", typ)
+		w, h, format := decodeSize(t, data)
+		require.Equal(t, []int{40, 20}, []int{w, h})
+		require.Equal(t, "jpeg", format)
+
+		var buf bytes.Buffer
+		require.NoError(t, gif.EncodeAll(&buf, &gif.GIF{
+			Image: []*image.Paletted{
+				image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.White}),
+				image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.Black}),
+			},
+			Delay: []int{10, 10},
+		}))
+		data, typ, err = storage.ProcessImage(buf.Bytes(), &storage.ImageOptions{Format: storage.PNG})
+		require.NoError(t, err)
+		require.Equal(t, "image/png", typ)
+		img, err := png.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+		r, _, _, _ := img.At(0, 0).RGBA()
+		require.Equal(t, uint32(0xffff), r)
+	})
+
+	t.Run("Orientation", func(t *testing.T) {
+		src := encodeRotatedJPEG(t, newImage(40, 20))
+		require.True(t, bytes.Contains(src, []byte("Exif")))
+		data, typ, err := storage.ProcessImage(src, &storage.ImageOptions{})
+		require.NoError(t, err)
+		require.Equal(t, "image/jpeg", typ)
+		require.False(t, bytes.Contains(data, []byte("Exif")))
+		w, h, _ := decodeSize(t, data)
+		require.Equal(t, []int{20, 40}, []int{w, h})
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, _, err := storage.ProcessImage(src, &storage.ImageOptions{Crop: "stretch"})
+		require.Equal(t, http.StatusBadRequest, errors.GetCode(err))
+		_, _, err = storage.ProcessImage([]byte("not an image"), &storage.ImageOptions{})
+		require.Equal(t, http.StatusBadRequest, errors.GetCode(err))
+	})
+
+	t.Run("MaxPixels", func(t *testing.T) {
+		_, _, err := storage.ProcessImage(encodeBomb(t, 50000, 50000), &storage.ImageOptions{})
+		require.Equal(t, http.StatusBadRequest, errors.GetCode(err))
+		require.Contains(t, err.Error(), "exceeds")
+		_, _, err = storage.ProcessImage(src, &storage.ImageOptions{MaxPixels: 100})
+		require.Equal(t, http.StatusBadRequest, errors.GetCode(err))
+		_, _, err = storage.ProcessImage(src, &storage.ImageOptions{MaxPixels: -1})
+		require.NoError(t, err)
+	})
+}
+
+func TestImageWriter_Variant(t *testing.T) {
+	ctx := context.Background()
+	b := storage.NewMemoryBucket()
+	w := storage.NewImageWriter(b)
+	require.NoError(t, w.AddVariant("square", &storage.ImageOptions{Width: 10, Height: 10, Crop: storage.CropFill, Format: storage.PNG}))
+	require.Error(t, w.AddVariant("", &storage.ImageOptions{}))
+
+	src := encodeRotatedJPEG(t, newImage(40, 20))
+	_, err := w.Write(ctx, "photo", src)
+	require.NoError(t, err)
+	// Originals are written as they are by default
+	data, err := b.Read(ctx, "photo")
+	require.NoError(t, err)
+	require.Equal(t, src, data)
+
+	data, err = b.Read(ctx, "photo-square")
+	require.NoError(t, err)
+	width, height, format := decodeSize(t, data)
+	require.Equal(t, []int{10, 10}, []int{width, height})
+	require.Equal(t, "png", format)
+
+	w.Options = &storage.ImageOptions{}
+	_, err = w.Write(ctx, "stripped", src)
+	require.NoError(t, err)
+	data, err = b.Read(ctx, "stripped")
+	require.NoError(t, err)
+	require.False(t, bytes.Contains(data, []byte("Exif")))
+	width, height, _ = decodeSize(t, data)
+	require.Equal(t, []int{20, 40}, []int{width, height})
+
+	_, err = w.Write(ctx, "bomb", encodeBomb(t, 50000, 50000))
+	require.Equal(t, http.StatusBadRequest, errors.GetCode(err))
+	w.MaxPixels = 100
+	_, err = w.Write(ctx, "large", src)
+	require.Equal(t, http.StatusBadRequest, errors.GetCode(err))
+}
+
+func TestImageResizer(t *testing.T) {
+	ctx := context.Background()
+	b := storage.NewMemoryBucket()
+	_, err := b.Put(ctx, "a.png", bytes.NewReader(encodePNG(t, newImage(400, 200))), "")
+	require.NoError(t, err)
+	z := storage.NewImageResizer(b, b)
+	z.MaxWidth = 1000
+	s := wine.NewTestServer(t)
+	s.Bind(http.MethodGet, "img/{name}", z)
+	url := s.Run() + "/img/a.png"
+
+	get := func(query string) (*http.Response, []byte) {
+		resp, err := http.Get(url + query)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		return resp, data
+	}
+
+	resp, data := get("?w=50&h=50&crop=fill&format=jpg")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "image/jpeg", resp.Header.Get("Content-Type"))
+	require.NotEmpty(t, resp.Header.Get("ETag"))
+	w, h, _ := decodeSize(t, data)
+	require.Equal(t, []int{50, 50}, []int{w, h})
+
+	name := storage.DerivativeName("a.png", &storage.ImageOptions{Width: 50, Height: 50, Crop: storage.CropFill, Format: storage.JPEG})
+	cached, err := b.Read(ctx, name)
+	require.NoError(t, err)
+	require.Equal(t, data, cached)
+
+	// served from cache once original is gone
+	require.NoError(t, b.Delete(ctx, "a.png"))
+	resp, data = get("?w=50&h=50&crop=fill&format=jpeg")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, cached, data)
+
+	resp, _ = get("?w=100")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = get("?w=2000")
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	_, err = b.Put(ctx, "bomb.png", bytes.NewReader(encodeBomb(t, 50000, 50000)), "")
+	require.NoError(t, err)
+	_, _, err = z.Resize(ctx, "bomb.png", &storage.ImageOptions{Width: 100})
+	require.Equal(t, http.StatusBadRequest, errors.GetCode(err))
+}
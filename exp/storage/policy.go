@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/gopub/errors"
+	"github.com/gopub/wine/httpvalue"
+)
+
+// MaxFilenameLength is max length in bytes of sanitized filenames
+const MaxFilenameLength = 255
+
+// UploadPolicy validates objects before FileWriter and ImageWriter write them
+type UploadPolicy struct {
+	// AllowedTypes are MIME types, or wildcards like image/*, which are matched against the type detected from
+	// magic bytes rather than the type claimed by clients. Empty allows all types
+	AllowedTypes []string
+	// MaxSize is max size in bytes, zero means no limit
+	MaxSize int64
+	// MaxWidth and MaxHeight limit dimensions of images, which are read from headers so that decompression bombs
+	// are rejected before decoding. Zero means no limit
+	MaxWidth  int
+	MaxHeight int
+	// KeepFilename names objects uploaded by multipart forms with sanitized filenames instead of random names.
+	// Objects with the same filename overwrite each other
+	KeepFilename bool
+	// Scanner inspects objects after other checks, e.g. ClamdScanner
+	Scanner Scanner
+	// Quarantine keeps objects with verdict Quarantine, which are rejected as well. Nil discards them
+	Quarantine Writer
+}
+
+// Check validates o, sanitizes its name and sets its type to the detected one
+func (p *UploadPolicy) Check(ctx context.Context, o *Object) error {
+	if len(o.Content) == 0 {
+		return errors.BadRequest("missing content")
+	}
+	if p.MaxSize > 0 && int64(len(o.Content)) > p.MaxSize {
+		return errors.Format(http.StatusRequestEntityTooLarge, "size %d exceeds %d", len(o.Content), p.MaxSize)
+	}
+
+	typ := httpvalue.DetectContentType(o.Content)
+	if !p.allows(typ) {
+		return errors.Format(http.StatusUnsupportedMediaType, "type %s is not allowed", typ)
+	}
+	o.Type = typ
+
+	if strings.HasPrefix(typ, "image/") && (p.MaxWidth > 0 || p.MaxHeight > 0) {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(o.Content))
+		switch {
+		case err == nil:
+			if (p.MaxWidth > 0 && cfg.Width > p.MaxWidth) || (p.MaxHeight > 0 && cfg.Height > p.MaxHeight) {
+				return errors.BadRequest("image size %dx%d exceeds %dx%d", cfg.Width, cfg.Height, p.MaxWidth, p.MaxHeight)
+			}
+		case errors.Is(err, image.ErrFormat):
+			// Formats without decoders, e.g. svg, have no dimensions
+		default:
+			return errors.BadRequest("decode image: %v", err)
+		}
+	}
+
+	if o.Name != "" {
+		if o.Name = SanitizeFilename(o.Name); o.Name == "" {
+			return errors.BadRequest("invalid name")
+		}
+	}
+
+	if p.Scanner == nil {
+		return nil
+	}
+	res, err := p.Scanner.Scan(ctx, o)
+	if err != nil {
+		// Fail closed as content is unknown
+		return fmt.Errorf("scan: %w", err)
+	}
+	if res == nil || res.Verdict == Clean {
+		return nil
+	}
+	if res.Verdict == Quarantine && p.Quarantine != nil {
+		// Names are assigned after checks, e.g. by FileWriter without KeepFilename
+		q := *o
+		if q.Name == "" {
+			q.Name = uuid.NewString()
+		}
+		if _, err = p.Quarantine.Write(ctx, &q); err != nil {
+			return fmt.Errorf("quarantine: %w", err)
+		}
+	}
+	return errors.Format(http.StatusUnprocessableEntity, "rejected by scanner: %s", res.Reason)
+}
+
+func (p *UploadPolicy) allows(typ string) bool {
+	if len(p.AllowedTypes) == 0 {
+		return true
+	}
+	if mt, _, err := mime.ParseMediaType(typ); err == nil {
+		typ = mt
+	}
+	for _, t := range p.AllowedTypes {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == typ || t == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(typ, t[:len(t)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// SanitizeFilename returns base name of name without directories, control characters and characters which are
+// unsafe in urls or file systems. Leading dots are removed so that results are never hidden files.
+// Empty string is returned if nothing is left
+func SanitizeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r == utf8.RuneError || r < 0x20 || r == 0x7f:
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			b.WriteRune(r)
+		case r > 0x7f && unicode.IsPrint(r) && !unicode.Is(unicode.Cf, r):
+			// Keep letters of other languages, but not spaces or format characters e.g. bidi overrides
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	name = strings.TrimLeft(b.String(), ".")
+	for len(name) > MaxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
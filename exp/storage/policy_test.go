@@ -0,0 +1,224 @@
+package storage_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/exp/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	for name, expected := range map[string]string{
+		"photo.jpg":              "photo.jpg",
+		"../../etc/passwd":       "passwd",
+		`C:\Users\a\report.pdf`:  "report.pdf",
+		".htaccess":              "htaccess",
+		"my file?.txt":           "my_file_.txt",
+		"a\x00b\nc.txt":          "abc.txt",
+		"résumé.pdf":             "résumé.pdf",
+		"evil\u202egpj.exe":      "evil_gpj.exe",
+		"..":                     "",
+		strings.Repeat("a", 300): strings.Repeat("a", storage.MaxFilenameLength),
+	} {
+		require.Equal(t, expected, storage.SanitizeFilename(name), name)
+	}
+}
+
+func TestUploadPolicy(t *testing.T) {
+	ctx := context.Background()
+	png := encodePNG(t, newImage(40, 20))
+
+	t.Run("Type", func(t *testing.T) {
+		p := &storage.UploadPolicy{AllowedTypes: []string{"image/*", "application/pdf"}}
+		o := &storage.Object{Content: png, Type: "text/plain"}
+		require.NoError(t, p.Check(ctx, o))
+		require.Equal(t, "image/png", o.Type)
+
+		// Claimed type is ignored
+		o = &storage.Object{Content: []byte("<script>alert(1)</script>"), Type: "image/png"}
+		require.Equal(t, http.StatusUnsupportedMediaType, errors.GetCode(p.Check(ctx, o)))
+	})
+
+	t.Run("Size", func(t *testing.T) {
+		p := &storage.UploadPolicy{MaxSize: 10}
+		err := p.Check(ctx, &storage.Object{Content: png})
+		require.Equal(t, http.StatusRequestEntityTooLarge, errors.GetCode(err))
+		err = p.Check(ctx, &storage.Object{})
+		require.Equal(t, http.StatusBadRequest, errors.GetCode(err))
+
+		p = &storage.UploadPolicy{MaxWidth: 30}
+		err = p.Check(ctx, &storage.Object{Content: png})
+		require.Equal(t, http.StatusBadRequest, errors.GetCode(err))
+		p = &storage.UploadPolicy{MaxWidth: 40, MaxHeight: 20}
+		require.NoError(t, p.Check(ctx, &storage.Object{Content: png}))
+	})
+
+	t.Run("Name", func(t *testing.T) {
+		p := &storage.UploadPolicy{}
+		o := &storage.Object{Name: "../a b.png", Content: png}
+		require.NoError(t, p.Check(ctx, o))
+		require.Equal(t, "a_b.png", o.Name)
+		err := p.Check(ctx, &storage.Object{Name: "..", Content: png})
+		require.Equal(t, http.StatusBadRequest, errors.GetCode(err))
+	})
+
+	t.Run("Scanner", func(t *testing.T) {
+		quarantine := storage.NewMemoryBucket()
+		p := &storage.UploadPolicy{
+			Scanner: storage.ScannerFunc(func(ctx context.Context, o *storage.Object) (*storage.ScanResult, error) {
+				switch string(o.Content) {
+				case "virus":
+					return &storage.ScanResult{Verdict: storage.Reject, Reason: "virus"}, nil
+				case "suspicious":
+					return &storage.ScanResult{Verdict: storage.Quarantine, Reason: "suspicious"}, nil
+				case "error":
+					return nil, errors.New("unavailable")
+				}
+				return &storage.ScanResult{Verdict: storage.Clean}, nil
+			}),
+			Quarantine: quarantine,
+		}
+		require.NoError(t, p.Check(ctx, &storage.Object{Name: "a", Content: []byte("hello")}))
+		err := p.Check(ctx, &storage.Object{Name: "b", Content: []byte("virus")})
+		require.Equal(t, http.StatusUnprocessableEntity, errors.GetCode(err))
+		require.Error(t, p.Check(ctx, &storage.Object{Name: "c", Content: []byte("error")}))
+
+		err = p.Check(ctx, &storage.Object{Name: "d", Content: []byte("suspicious")})
+		require.Equal(t, http.StatusUnprocessableEntity, errors.GetCode(err))
+		data, err := quarantine.Read(ctx, "d")
+		require.NoError(t, err)
+		require.Equal(t, "suspicious", string(data))
+		_, err = quarantine.Read(ctx, "b")
+		require.True(t, errors.IsNotExist(err))
+
+		// Unnamed objects are quarantined by random names
+		for i := 0; i < 2; i++ {
+			err = p.Check(ctx, &storage.Object{Content: []byte("suspicious")})
+			require.Equal(t, http.StatusUnprocessableEntity, errors.GetCode(err))
+		}
+		l, _, err := quarantine.List(ctx, "", "", 0)
+		require.NoError(t, err)
+		require.Len(t, l, 3)
+		for _, o := range l {
+			require.NotEmpty(t, o.Name)
+		}
+	})
+}
+
+// serveClamd serves INSTREAM command like clamd, and finds content containing EICAR
+func serveClamd(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				cmd, err := r.ReadString(0)
+				if err != nil || cmd != "zINSTREAM\x00" {
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+					return
+				}
+				var content bytes.Buffer
+				for {
+					var size uint32
+					if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					if _, err := io.CopyN(&content, r, int64(size)); err != nil {
+						return
+					}
+				}
+				if bytes.Contains(content.Bytes(), []byte("EICAR")) {
+					conn.Write([]byte("stream: Eicar-Signature FOUND\x00"))
+				} else {
+					conn.Write([]byte("stream: OK\x00"))
+				}
+			}(conn)
+		}
+	}()
+	return l
+}
+
+func TestClamdScanner(t *testing.T) {
+	ctx := context.Background()
+	l := serveClamd(t)
+	s := storage.NewClamdScanner("tcp", l.Addr().String())
+
+	res, err := s.Scan(ctx, &storage.Object{Content: bytes.Repeat([]byte("a"), 200<<10)})
+	require.NoError(t, err)
+	require.Equal(t, storage.Clean, res.Verdict)
+
+	content := append(bytes.Repeat([]byte("a"), 100<<10), "EICAR"...)
+	res, err = s.Scan(ctx, &storage.Object{Content: content})
+	require.NoError(t, err)
+	require.Equal(t, storage.Reject, res.Verdict)
+	require.Equal(t, "Eicar-Signature", res.Reason)
+
+	l.Close()
+	_, err = s.Scan(ctx, &storage.Object{Content: []byte("a")})
+	require.Error(t, err)
+}
+
+func TestFileWriter_Policy(t *testing.T) {
+	b := storage.NewMemoryBucket()
+	fw := storage.NewFileWriter(b)
+	fw.Policy = &storage.UploadPolicy{
+		AllowedTypes: []string{"text/plain"},
+		KeepFilename: true,
+		Scanner:      storage.NewClamdScanner("tcp", serveClamd(t).Addr().String()),
+	}
+	s := wine.NewTestServer(t)
+	s.Bind(http.MethodPost, "upload", fw)
+	url := s.Run() + "/upload"
+
+	upload := func(filename, content string) *http.Response {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fileWriter, err := mw.CreateFormFile("file", filename)
+		require.NoError(t, err)
+		_, err = fileWriter.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, mw.Close())
+		resp, err := http.Post(url, mw.FormDataContentType(), &body)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := upload("../notes 1.txt", "hello")
+	var urls []string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&urls))
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []string{"mem://notes_1.txt"}, urls)
+
+	resp = upload("a.txt", "\x89PNG\r\n\x1a\n")
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	resp = upload("eicar.txt", "EICAR test")
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	_, err := b.Read(context.Background(), "eicar.txt")
+	require.True(t, errors.IsNotExist(err))
+}
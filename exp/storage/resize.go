@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/httpvalue"
+)
+
+// DefaultMaxImageSize is default MaxWidth and MaxHeight of ImageResizer
+const DefaultMaxImageSize = 4096
+
+// ImageResizer resizes images on the fly, e.g. GET /img/{name}?w=200&h=200&crop=fill&format=png&q=80.
+// Derivatives are written into cache and served from it afterwards, so every size is processed once.
+// Cache could be the same bucket with originals as derivatives are named like avatar.jpg@200x200,fill,q80.png
+type ImageResizer struct {
+	r     Reader
+	cache Bucket
+
+	// MaxWidth and MaxHeight limit sizes of derivatives so that clients can't exhaust cpu and cache
+	MaxWidth  int
+	MaxHeight int
+	// MaxPixels limits width x height of originals, see ImageOptions.MaxPixels
+	MaxPixels int
+}
+
+var _ wine.Handler = (*ImageResizer)(nil)
+
+func NewImageResizer(r Reader, cache Bucket) *ImageResizer {
+	return &ImageResizer{
+		r:         r,
+		cache:     cache,
+		MaxWidth:  DefaultMaxImageSize,
+		MaxHeight: DefaultMaxImageSize,
+		MaxPixels: DefaultMaxImagePixels,
+	}
+}
+
+// Resize returns derivative of image named name, and its content type
+func (z *ImageResizer) Resize(ctx context.Context, name string, o *ImageOptions) ([]byte, string, error) {
+	if err := o.Validate(); err != nil {
+		return nil, "", err
+	}
+	if o.Width > z.MaxWidth || o.Height > z.MaxHeight {
+		return nil, "", errors.BadRequest("size %dx%d exceeds %dx%d", o.Width, o.Height, z.MaxWidth, z.MaxHeight)
+	}
+	key := DerivativeName(name, o)
+	if data, err := z.cache.Read(ctx, key); err == nil {
+		return data, httpvalue.DetectContentType(data), nil
+	} else if !errors.IsNotExist(err) {
+		return nil, "", fmt.Errorf("read cache: %w", err)
+	}
+
+	origin, err := z.r.Read(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	po := *o
+	po.MaxPixels = z.MaxPixels
+	data, typ, err := ProcessImage(origin, &po)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err = z.cache.Put(ctx, key, bytes.NewReader(data), typ); err != nil {
+		return nil, "", fmt.Errorf("write cache: %w", err)
+	}
+	return data, typ, nil
+}
+
+// HandleRequest serves derivative of image named by path parameter name or the last path segment.
+// Options are parsed from query parameters w, h, crop, format and q
+func (z *ImageResizer) HandleRequest(ctx context.Context, req *wine.Request) wine.Responder {
+	p := req.Params()
+	name := p.String("name")
+	if name == "" {
+		name = path.Base(req.Request().URL.Path)
+	}
+	o := &ImageOptions{
+		Width:   p.Int("w"),
+		Height:  p.Int("h"),
+		Crop:    CropMode(strings.ToLower(p.String("crop"))),
+		Format:  ImageFormat(strings.ToLower(p.String("format"))),
+		Quality: p.Int("q"),
+	}
+	if o.Format == "jpg" {
+		o.Format = JPEG
+	}
+	data, typ, err := z.Resize(ctx, name, o)
+	if err != nil {
+		if errors.IsNotExist(err) {
+			return wine.Status(http.StatusNotFound)
+		}
+		return wine.Error(err)
+	}
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	r := req.Request()
+	return wine.ResponderFunc(func(ctx context.Context, w http.ResponseWriter) {
+		h := w.Header()
+		h.Set("ETag", etag)
+		h.Set(httpvalue.ContentType, typ)
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+	})
+}
+
+// DerivativeName returns name of derivative of image name processed with o
+func DerivativeName(name string, o *ImageOptions) string {
+	crop := o.Crop
+	if crop == "" {
+		crop = CropFit
+	}
+	quality := o.Quality
+	if quality == 0 {
+		quality = DefaultImageQuality
+	}
+	s := fmt.Sprintf("%s@%dx%d,%s,q%d", name, o.Width, o.Height, crop, quality)
+	if o.Format != "" {
+		s += "." + string(o.Format)
+	}
+	return s
+}
@@ -4,13 +4,15 @@ go 1.15
 
 require (
 	github.com/aws/aws-sdk-go v1.36.31
+	github.com/gopub/errors v0.1.7
 	github.com/gopub/wine v1.38.2 // indirect
 	github.com/gopub/wine/exp/storage v0.1.6
 )
 
-//replace (
-//	github.com/gopub/wine => ../../../
-//	github.com/gopub/wine/exp/storage => ../
-//	github.com/gopub/wine/httpvalue => ../../../httpvalue
-//	github.com/gopub/wine/router => ../../../router
-//)
+replace (
+	github.com/gopub/wine => ../../../
+	github.com/gopub/wine/exp/storage => ../
+	github.com/gopub/wine/httpvalue => ../../../httpvalue
+	github.com/gopub/wine/router => ../../../router
+	github.com/gopub/wine/urlutil => ../../../urlutil
+)
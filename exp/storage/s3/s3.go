@@ -4,10 +4,19 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	winerrors "github.com/gopub/errors"
 	"github.com/gopub/wine/exp/storage"
 )
 
@@ -30,17 +39,20 @@ const (
 
 type Bucket struct {
 	name         string
+	client       *s3.S3
 	uploader     *s3manager.Uploader
 	ACL          *string
 	CacheControl string
 }
 
-var _ storage.Writer = (*Bucket)(nil)
+var _ storage.Bucket = (*Bucket)(nil)
 
 func NewBucket(name string) *Bucket {
+	client := s3.New(session.Must(session.NewSession()))
 	return &Bucket{
 		name:         name,
-		uploader:     s3manager.NewUploader(session.Must(session.NewSession())),
+		client:       client,
+		uploader:     s3manager.NewUploaderWithClient(client),
 		ACL:          aws.String(string(S3PublicRead)),
 		CacheControl: cacheControl,
 	}
@@ -82,3 +94,131 @@ func (s *Bucket) Write(ctx context.Context, o *storage.Object) (string, error) {
 		}
 	}
 }
+
+func (s *Bucket) Read(ctx context.Context, name string) ([]byte, error) {
+	r, err := s.Open(ctx, name, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func (s *Bucket) Stat(ctx context.Context, name string) (*storage.ObjectInfo, error) {
+	out, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.name),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return &storage.ObjectInfo{
+		Name:         name,
+		Size:         aws.Int64Value(out.ContentLength),
+		Type:         aws.StringValue(out.ContentType),
+		ETag:         aws.StringValue(out.ETag),
+		LastModified: aws.TimeValue(out.LastModified),
+	}, nil
+}
+
+func (s *Bucket) Open(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.name),
+		Key:    aws.String(name),
+	}
+	if length >= 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+	}
+	out, err := s.client.GetObjectWithContext(ctx, input)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return out.Body, nil
+}
+
+func (s *Bucket) Put(ctx context.Context, name string, r io.Reader, contentType string) (string, error) {
+	input := &s3manager.UploadInput{
+		Bucket:       aws.String(s.name),
+		Key:          aws.String(name),
+		Body:         r,
+		ACL:          s.ACL,
+		CacheControl: aws.String(s.CacheControl),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	out, err := s.uploader.UploadWithContext(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	return out.Location, nil
+}
+
+func (s *Bucket) Delete(ctx context.Context, name string) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.name),
+		Key:    aws.String(name),
+	})
+	return err
+}
+
+func (s *Bucket) List(ctx context.Context, prefix, cursor string, limit int) ([]*storage.ObjectInfo, string, error) {
+	if limit <= 0 {
+		limit = storage.DefaultListLimit
+	}
+	input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(s.name),
+		Prefix:  aws.String(prefix),
+		MaxKeys: aws.Int64(int64(limit)),
+	}
+	if cursor != "" {
+		input.ContinuationToken = aws.String(cursor)
+	}
+	out, err := s.client.ListObjectsV2WithContext(ctx, input)
+	if err != nil {
+		return nil, "", err
+	}
+	l := make([]*storage.ObjectInfo, len(out.Contents))
+	for i, o := range out.Contents {
+		l[i] = &storage.ObjectInfo{
+			Name:         aws.StringValue(o.Key),
+			Size:         aws.Int64Value(o.Size),
+			ETag:         aws.StringValue(o.ETag),
+			LastModified: aws.TimeValue(o.LastModified),
+		}
+	}
+	if !aws.BoolValue(out.IsTruncated) {
+		return l, "", nil
+	}
+	return l, aws.StringValue(out.NextContinuationToken), nil
+}
+
+// SignURL presigns GET, PUT or DELETE requests
+func (s *Bucket) SignURL(ctx context.Context, method, name string, expires time.Duration) (string, error) {
+	var req *request.Request
+	switch method {
+	case http.MethodGet:
+		req, _ = s.client.GetObjectRequest(&s3.GetObjectInput{Bucket: aws.String(s.name), Key: aws.String(name)})
+	case http.MethodPut:
+		req, _ = s.client.PutObjectRequest(&s3.PutObjectInput{Bucket: aws.String(s.name), Key: aws.String(name)})
+	case http.MethodDelete:
+		req, _ = s.client.DeleteObjectRequest(&s3.DeleteObjectInput{Bucket: aws.String(s.name), Key: aws.String(name)})
+	default:
+		return "", fmt.Errorf("cannot sign method %s", method)
+	}
+	req.SetContext(ctx)
+	return req.Presign(expires)
+}
+
+func notExist(err error) error {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		// HeadObject returns NotFound without body
+		if code := aerr.Code(); code == s3.ErrCodeNoSuchKey || code == "NotFound" {
+			return fmt.Errorf("%v: %w", err, winerrors.NotExist)
+		}
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Verdict is result of scanning an object
+type Verdict int
+
+const (
+	// Clean objects are written
+	Clean Verdict = iota
+	// Reject objects are discarded
+	Reject
+	// Quarantine objects are written into UploadPolicy.Quarantine instead
+	Quarantine
+)
+
+// ScanResult is returned by Scanner
+type ScanResult struct {
+	Verdict Verdict
+	// Reason is returned to clients if object is not clean, e.g. name of the virus
+	Reason string
+}
+
+// Scanner inspects objects before they are written, e.g. for viruses
+type Scanner interface {
+	Scan(ctx context.Context, o *Object) (*ScanResult, error)
+}
+
+type ScannerFunc func(ctx context.Context, o *Object) (*ScanResult, error)
+
+func (f ScannerFunc) Scan(ctx context.Context, o *Object) (*ScanResult, error) {
+	return f(ctx, o)
+}
+
+const clamdChunkSize = 64 << 10
+
+// ClamdScanner scans objects by clamd with INSTREAM command, or any service speaking the same protocol
+type ClamdScanner struct {
+	// Network and Address of clamd, e.g. unix /var/run/clamav/clamd.ctl or tcp 127.0.0.1:3310
+	Network string
+	Address string
+	// Timeout of a scan, 30s by default
+	Timeout time.Duration
+	// Found is verdict of infected objects, Reject by default
+	Found Verdict
+}
+
+var _ Scanner = (*ClamdScanner)(nil)
+
+func NewClamdScanner(network, address string) *ClamdScanner {
+	return &ClamdScanner{
+		Network: network,
+		Address: address,
+		Timeout: 30 * time.Second,
+		Found:   Reject,
+	}
+}
+
+func (s *ClamdScanner) Scan(ctx context.Context, o *Object) (*ScanResult, error) {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, s.Network, s.Address)
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			return nil, fmt.Errorf("set deadline: %w", err)
+		}
+	}
+
+	w := bufio.NewWriter(conn)
+	w.WriteString("zINSTREAM\x00")
+	size := make([]byte, 4)
+	for content := o.Content; len(content) > 0; {
+		n := len(content)
+		if n > clamdChunkSize {
+			n = clamdChunkSize
+		}
+		binary.BigEndian.PutUint32(size, uint32(n))
+		w.Write(size)
+		w.Write(content[:n])
+		content = content[n:]
+	}
+	binary.BigEndian.PutUint32(size, 0)
+	w.Write(size)
+	if err = w.Flush(); err != nil {
+		return nil, fmt.Errorf("write: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && len(reply) == 0 {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	// Replies are like "stream: OK", "stream: Eicar-Signature FOUND" or "INSTREAM size limit exceeded. ERROR"
+	r := string(bytes.TrimRight(reply, "\x00\n"))
+	r = strings.TrimPrefix(r, "stream: ")
+	switch {
+	case r == "OK":
+		return &ScanResult{Verdict: Clean}, nil
+	case strings.HasSuffix(r, " FOUND"):
+		return &ScanResult{Verdict: s.Found, Reason: strings.TrimSuffix(r, " FOUND")}, nil
+	default:
+		return nil, fmt.Errorf("clamd: %s", r)
+	}
+}
@@ -1,6 +1,10 @@
 package storage
 
-import "context"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 type Reader interface {
 	Read(ctx context.Context, name string) ([]byte, error)
@@ -9,3 +13,36 @@ type Reader interface {
 type Writer interface {
 	Write(ctx context.Context, o *Object) (url string, err error)
 }
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Name         string    `json:"name"`
+	Size         int64     `json:"size"`
+	Type         string    `json:"type,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// Bucket is a full object storage, implemented by DiskBucket and adapters of s3, oss and gcp.
+// Errors of missing objects satisfy errors.IsNotExist
+type Bucket interface {
+	Reader
+	Writer
+
+	// Stat returns info of object
+	Stat(ctx context.Context, name string) (*ObjectInfo, error)
+	// Open reads length bytes of object since offset. Negative length reads to the end
+	Open(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error)
+	// Put writes object from r without buffering it in memory. Type is detected by backends if contentType is empty
+	Put(ctx context.Context, name string, r io.Reader, contentType string) (url string, err error)
+	// Delete deletes object, it's not an error if object doesn't exist
+	Delete(ctx context.Context, name string) error
+	// List returns at most limit objects whose names start with prefix, and cursor to list next page.
+	// Empty cursor starts from the first page, and empty next cursor means there are no more objects
+	List(ctx context.Context, prefix, cursor string, limit int) (objects []*ObjectInfo, next string, err error)
+	// SignURL returns url which grants method, e.g. GET, PUT or DELETE, on object until it expires
+	SignURL(ctx context.Context, method, name string, expires time.Duration) (string, error)
+}
+
+// DefaultListLimit is used if limit of List isn't positive
+const DefaultListLimit = 1000
@@ -0,0 +1,519 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gopub/errors"
+	"github.com/gopub/wine"
+)
+
+// Headers and values of tus protocol, see https://tus.io/protocols/resumable-upload.html
+const (
+	TusResumable           = "Tus-Resumable"
+	TusVersion             = "Tus-Version"
+	TusExtension           = "Tus-Extension"
+	TusMaxSize             = "Tus-Max-Size"
+	UploadLength           = "Upload-Length"
+	UploadOffset           = "Upload-Offset"
+	UploadMetadata         = "Upload-Metadata"
+	UploadExpires          = "Upload-Expires"
+	TusProtocolVersion     = "1.0.0"
+	OffsetOctetStream      = "application/offset+octet-stream"
+	tusMethodOverride      = "X-HTTP-Method-Override"
+	tusSupportedExtensions = "creation,creation-with-upload,expiration,termination"
+)
+
+const (
+	// DefaultTusExpiration is the duration to keep incomplete uploads
+	DefaultTusExpiration = 24 * time.Hour
+
+	tusCleanupInterval = time.Minute
+	tusInfoExt         = ".info"
+	tusDataExt         = ".bin"
+)
+
+// Upload is the state of a resumable upload
+type Upload struct {
+	ID        string            `json:"id"`
+	Length    int64             `json:"length"`
+	Offset    int64             `json:"offset"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	// URL is returned by Writer once upload is complete
+	URL string `json:"url,omitempty"`
+}
+
+func (u *Upload) expired() bool {
+	return u.URL == "" && time.Now().After(u.ExpiresAt)
+}
+
+// TusWriter implements tus 1.0 resumable uploads. Chunks are staged in a local directory,
+// and the object is written into Writer once all bytes are received, by streaming if Writer is a Bucket.
+// Use Route to bind it
+type TusWriter struct {
+	w   Writer
+	dir string
+
+	// MaxSize limits Upload-Length, zero means no limit
+	MaxSize int64
+	// Expiration is the duration to keep incomplete uploads since creation
+	Expiration time.Duration
+	// OnComplete is called after the object is written into Writer
+	OnComplete func(ctx context.Context, u *Upload)
+
+	// mu guards info files and busy
+	mu        sync.Mutex
+	busy      map[string]bool
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ wine.Handler = (*TusWriter)(nil)
+
+// NewTusWriter creates a TusWriter staging chunks in dir, whose janitor removes expired uploads until it's closed
+func NewTusWriter(w Writer, dir string) (*TusWriter, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("make dir: %w", err)
+	}
+	t := &TusWriter{
+		w:          w,
+		dir:        dir,
+		Expiration: DefaultTusExpiration,
+		busy:       make(map[string]bool),
+		done:       make(chan struct{}),
+	}
+	go t.sweep()
+	return t, nil
+}
+
+// Route binds t to path for creation, and path/{id} for other requests. Bodies are streamed into staging files
+func (t *TusWriter) Route(r *wine.Router, path string) {
+	path = strings.TrimSuffix(path, "/")
+	r.Bind("", path, t).SetStreamingBody(true).SetMaxBodySize(-1)
+	r.Bind("", path+"/{id}", t).SetStreamingBody(true).SetMaxBodySize(-1)
+}
+
+// Close stops the janitor
+func (t *TusWriter) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.done)
+	})
+	return nil
+}
+
+func (t *TusWriter) HandleRequest(ctx context.Context, req *wine.Request) wine.Responder {
+	method := req.Request().Method
+	if m := req.Header(tusMethodOverride); m != "" && method == http.MethodPost {
+		method = strings.ToUpper(m)
+	}
+	if method == http.MethodOptions {
+		return t.respond(http.StatusNoContent, nil, func(h http.Header) {
+			h.Set(TusVersion, TusProtocolVersion)
+			h.Set(TusExtension, tusSupportedExtensions)
+			if t.MaxSize > 0 {
+				h.Set(TusMaxSize, fmt.Sprint(t.MaxSize))
+			}
+		})
+	}
+	if req.Header(TusResumable) != TusProtocolVersion {
+		return t.respond(http.StatusPreconditionFailed, nil, func(h http.Header) {
+			h.Set(TusVersion, TusProtocolVersion)
+		})
+	}
+
+	id := req.GroupedParams().PathParams.String("id")
+	if id == "" {
+		if method != http.MethodPost {
+			return wine.Status(http.StatusMethodNotAllowed)
+		}
+		return t.create(ctx, req)
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return wine.Status(http.StatusNotFound)
+	}
+	switch method {
+	case http.MethodHead:
+		return t.head(id)
+	case http.MethodPatch:
+		return t.patch(ctx, req, id)
+	case http.MethodDelete:
+		return t.terminate(id)
+	default:
+		return wine.Status(http.StatusMethodNotAllowed)
+	}
+}
+
+func (t *TusWriter) create(ctx context.Context, req *wine.Request) wine.Responder {
+	length, err := strconv.ParseInt(req.Header(UploadLength), 10, 64)
+	if err != nil || length < 0 {
+		return errors.BadRequest("invalid %s", UploadLength)
+	}
+	if t.MaxSize > 0 && length > t.MaxSize {
+		return wine.Status(http.StatusRequestEntityTooLarge)
+	}
+	metadata, err := parseUploadMetadata(req.Header(UploadMetadata))
+	if err != nil {
+		return errors.BadRequest("invalid %s: %v", UploadMetadata, err)
+	}
+	u := &Upload{
+		ID:        uuid.NewString(),
+		Length:    length,
+		Metadata:  metadata,
+		ExpiresAt: time.Now().Add(t.Expiration),
+	}
+	if err = ioutil.WriteFile(t.dataFile(u.ID), nil, 0600); err != nil {
+		return wine.Error(fmt.Errorf("create data file: %w", err))
+	}
+	if err = t.save(u); err != nil {
+		return wine.Error(err)
+	}
+
+	location := path.Join(req.Request().URL.Path, u.ID)
+	if req.ContentType() == OffsetOctetStream {
+		// creation-with-upload
+		t.mu.Lock()
+		t.busy[u.ID] = true
+		t.mu.Unlock()
+		err = t.receive(ctx, u, req.BodyReader())
+		t.mu.Lock()
+		delete(t.busy, u.ID)
+		t.mu.Unlock()
+		if err != nil {
+			return wine.Error(err)
+		}
+	}
+	return t.respond(http.StatusCreated, u, func(h http.Header) {
+		h.Set("Location", location)
+	})
+}
+
+func (t *TusWriter) head(id string) wine.Responder {
+	t.mu.Lock()
+	u, err := t.load(id)
+	t.mu.Unlock()
+	if err != nil {
+		return wine.Error(err)
+	}
+	return t.respond(http.StatusOK, u, func(h http.Header) {
+		h.Set(UploadLength, fmt.Sprint(u.Length))
+		h.Set("Cache-Control", "no-store")
+		if len(u.Metadata) > 0 {
+			h.Set(UploadMetadata, formatUploadMetadata(u.Metadata))
+		}
+	})
+}
+
+func (t *TusWriter) patch(ctx context.Context, req *wine.Request, id string) wine.Responder {
+	if req.ContentType() != OffsetOctetStream {
+		return wine.Status(http.StatusUnsupportedMediaType)
+	}
+	offset, err := strconv.ParseInt(req.Header(UploadOffset), 10, 64)
+	if err != nil || offset < 0 {
+		return errors.BadRequest("invalid %s", UploadOffset)
+	}
+
+	t.mu.Lock()
+	u, err := t.load(id)
+	if err == nil && t.busy[id] {
+		err = errors.Conflict("upload is in progress")
+	}
+	if err == nil && offset != u.Offset {
+		err = errors.Conflict("mismatched offset %d, expected %d", offset, u.Offset)
+	}
+	if err != nil {
+		t.mu.Unlock()
+		return wine.Error(err)
+	}
+	t.busy[id] = true
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.busy, id)
+		t.mu.Unlock()
+	}()
+
+	if err = t.receive(ctx, u, req.BodyReader()); err != nil {
+		return wine.Error(err)
+	}
+	return t.respond(http.StatusNoContent, u, nil)
+}
+
+// receive appends r to staging file of u, and writes the object into Writer once it's complete
+func (t *TusWriter) receive(ctx context.Context, u *Upload, r io.Reader) error {
+	n, err := t.append(u, r)
+	// Keep received bytes, so that client can resume from the new offset
+	u.Offset += n
+	if saveErr := t.save(u); saveErr != nil {
+		return saveErr
+	}
+	if err != nil {
+		return err
+	}
+	if u.Offset == u.Length && u.URL == "" {
+		return t.commit(ctx, u)
+	}
+	return nil
+}
+
+func (t *TusWriter) append(u *Upload, r io.Reader) (int64, error) {
+	f, err := os.OpenFile(t.dataFile(u.ID), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("open data file: %w", err)
+	}
+	defer f.Close()
+	remaining := u.Length - u.Offset
+	n, err := io.Copy(f, io.LimitReader(r, remaining))
+	if err != nil {
+		return n, err
+	}
+	// Body must not exceed Upload-Length
+	if n == remaining {
+		if m, _ := r.Read(make([]byte, 1)); m > 0 {
+			return n, wine.ErrBodyTooLarge
+		}
+	}
+	return n, nil
+}
+
+func (t *TusWriter) commit(ctx context.Context, u *Upload) error {
+	name := u.ID + filepath.Ext(SanitizeFilename(u.Metadata["filename"]))
+	var err error
+	if b, ok := t.w.(Bucket); ok {
+		u.URL, err = t.put(ctx, b, name, u)
+	} else {
+		u.URL, err = t.write(ctx, name, u)
+	}
+	if err != nil {
+		return err
+	}
+	if err = t.save(u); err != nil {
+		return err
+	}
+	os.Remove(t.dataFile(u.ID))
+	if t.OnComplete != nil {
+		t.OnComplete(ctx, u)
+	}
+	return nil
+}
+
+// put streams the data file into b, so that large uploads are never read into memory
+func (t *TusWriter) put(ctx context.Context, b Bucket, name string, u *Upload) (string, error) {
+	if u.Length == 0 {
+		return "", errors.BadRequest("missing content")
+	}
+	f, err := os.Open(t.dataFile(u.ID))
+	if err != nil {
+		return "", fmt.Errorf("open data file: %w", err)
+	}
+	defer f.Close()
+	url, err := b.Put(ctx, name, f, u.Metadata["filetype"])
+	if err != nil {
+		return "", fmt.Errorf("put: %w", err)
+	}
+	return url, nil
+}
+
+// write reads the data file into memory for writers which only accept objects
+func (t *TusWriter) write(ctx context.Context, name string, u *Upload) (string, error) {
+	content, err := ioutil.ReadFile(t.dataFile(u.ID))
+	if err != nil {
+		return "", fmt.Errorf("read data file: %w", err)
+	}
+	o := &Object{
+		Name:    name,
+		Content: content,
+		Type:    u.Metadata["filetype"],
+	}
+	if err = o.Validate(); err != nil {
+		return "", err
+	}
+	url, err := t.w.Write(ctx, o)
+	if err != nil {
+		return "", fmt.Errorf("write: %w", err)
+	}
+	return url, nil
+}
+
+func (t *TusWriter) terminate(id string) wine.Responder {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.load(id); err != nil {
+		return wine.Error(err)
+	}
+	if t.busy[id] {
+		return errors.Conflict("upload is in progress")
+	}
+	t.remove(id)
+	return t.respond(http.StatusNoContent, nil, nil)
+}
+
+// Cleanup removes expired incomplete uploads, and completed uploads whose expiration has passed
+func (t *TusWriter) Cleanup() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	files, err := filepath.Glob(filepath.Join(t.dir, "*"+tusInfoExt))
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, f := range files {
+		id := strings.TrimSuffix(filepath.Base(f), tusInfoExt)
+		u, err := t.read(id)
+		if err != nil || (now.After(u.ExpiresAt) && !t.busy[id]) {
+			t.remove(id)
+		}
+	}
+}
+
+func (t *TusWriter) sweep() {
+	ticker := time.NewTicker(tusCleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.Cleanup()
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// load reads upload of id, and removes it if it has expired
+func (t *TusWriter) load(id string) (*Upload, error) {
+	u, err := t.read(id)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NotFound("upload %s not found", id)
+		}
+		return nil, err
+	}
+	if u.expired() && !t.busy[id] {
+		t.remove(id)
+		return nil, errors.Format(http.StatusGone, "upload %s expired", id)
+	}
+	return u, nil
+}
+
+func (t *TusWriter) read(id string) (*Upload, error) {
+	b, err := ioutil.ReadFile(t.infoFile(id))
+	if err != nil {
+		return nil, err
+	}
+	u := new(Upload)
+	if err = json.Unmarshal(b, u); err != nil {
+		return nil, fmt.Errorf("unmarshal upload: %w", err)
+	}
+	return u, nil
+}
+
+func (t *TusWriter) save(u *Upload) error {
+	b, err := json.Marshal(u)
+	if err != nil {
+		return fmt.Errorf("marshal upload: %w", err)
+	}
+	// Write atomically in case of crash
+	tmp := t.infoFile(u.ID) + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return fmt.Errorf("write info file: %w", err)
+	}
+	if err = os.Rename(tmp, t.infoFile(u.ID)); err != nil {
+		return fmt.Errorf("rename info file: %w", err)
+	}
+	return nil
+}
+
+func (t *TusWriter) remove(id string) {
+	os.Remove(t.infoFile(id))
+	os.Remove(t.dataFile(id))
+}
+
+func (t *TusWriter) infoFile(id string) string {
+	return filepath.Join(t.dir, id+tusInfoExt)
+}
+
+func (t *TusWriter) dataFile(id string) string {
+	return filepath.Join(t.dir, id+tusDataExt)
+}
+
+// tusResponse writes status and tus headers of upload
+type tusResponse struct {
+	status int
+	upload *Upload
+	header func(h http.Header)
+}
+
+func (t *TusWriter) respond(status int, u *Upload, header func(h http.Header)) wine.Responder {
+	return &tusResponse{
+		status: status,
+		upload: u,
+		header: header,
+	}
+}
+
+func (r *tusResponse) Respond(ctx context.Context, w http.ResponseWriter) {
+	h := w.Header()
+	h.Set(TusResumable, TusProtocolVersion)
+	if u := r.upload; u != nil {
+		h.Set(UploadOffset, fmt.Sprint(u.Offset))
+		if u.URL != "" {
+			// Location of the written object
+			h.Set("Content-Location", u.URL)
+		} else {
+			h.Set(UploadExpires, u.ExpiresAt.UTC().Format(http.TimeFormat))
+		}
+	}
+	if r.header != nil {
+		r.header(h)
+	}
+	w.WriteHeader(r.status)
+}
+
+// parseUploadMetadata parses pairs of key and base64 encoded value separated by comma, e.g. filename d2luZS5wbmc=,is_confidential
+func parseUploadMetadata(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	m := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.Fields(pair)
+		switch len(kv) {
+		case 1:
+			m[kv[0]] = ""
+		case 2:
+			v, err := base64.StdEncoding.DecodeString(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("decode %s: %w", kv[0], err)
+			}
+			m[kv[0]] = string(v)
+		default:
+			return nil, fmt.Errorf("invalid pair %q", pair)
+		}
+	}
+	return m, nil
+}
+
+func formatUploadMetadata(m map[string]string) string {
+	l := make([]string, 0, len(m))
+	for k, v := range m {
+		if v == "" {
+			l = append(l, k)
+		} else {
+			l = append(l, k+" "+base64.StdEncoding.EncodeToString([]byte(v)))
+		}
+	}
+	return strings.Join(l, ",")
+}
@@ -0,0 +1,179 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/exp/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTusWriter(t *testing.T) {
+	bucket, err := storage.NewDiskBucket(t.TempDir())
+	require.NoError(t, err)
+	tw, err := storage.NewTusWriter(bucket, t.TempDir())
+	require.NoError(t, err)
+	defer tw.Close()
+	tw.MaxSize = 1 << 20
+	var completed *storage.Upload
+	tw.OnComplete = func(ctx context.Context, u *storage.Upload) {
+		completed = u
+	}
+	s := wine.NewTestServer(t)
+	tw.Route(s.Router, "files")
+	url := s.Run()
+
+	do := func(method, u string, header map[string]string, body []byte) *http.Response {
+		req, err := http.NewRequest(method, u, bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set(storage.TusResumable, storage.TusProtocolVersion)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+	create := func(length int, body []byte) string {
+		header := map[string]string{
+			storage.UploadLength:   fmt.Sprint(length),
+			storage.UploadMetadata: "filename " + base64.StdEncoding.EncodeToString([]byte("a.txt")) + ",public",
+		}
+		if body != nil {
+			header["Content-Type"] = storage.OffsetOctetStream
+		}
+		resp := do(http.MethodPost, url+"/files", header, body)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.NotEmpty(t, resp.Header.Get(storage.UploadExpires))
+		return url + resp.Header.Get("Location")
+	}
+	patch := func(u string, offset int, body []byte) *http.Response {
+		return do(http.MethodPatch, u, map[string]string{
+			"Content-Type":       storage.OffsetOctetStream,
+			storage.UploadOffset: fmt.Sprint(offset),
+		}, body)
+	}
+	content := bytes.Repeat([]byte("wine"), 1000)
+
+	t.Run("Resume", func(t *testing.T) {
+		u := create(len(content), nil)
+		resp := patch(u, 0, content[:1000])
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		require.Equal(t, "1000", resp.Header.Get(storage.UploadOffset))
+
+		resp = patch(u, 0, content[:1000])
+		require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		resp = do(http.MethodHead, u, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "1000", resp.Header.Get(storage.UploadOffset))
+		require.Equal(t, fmt.Sprint(len(content)), resp.Header.Get(storage.UploadLength))
+
+		resp = patch(u, 1000, content[1000:])
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		location := resp.Header.Get("Content-Location")
+		require.Equal(t, ".txt", filepath.Ext(location))
+		require.NotNil(t, completed)
+		require.Equal(t, location, completed.URL)
+		require.Equal(t, "", completed.Metadata["public"])
+		b, err := ioutil.ReadFile(location)
+		require.NoError(t, err)
+		require.Equal(t, content, b)
+	})
+
+	t.Run("CreationWithUpload", func(t *testing.T) {
+		u := create(len(content), content[:10])
+		resp := do(http.MethodHead, u, nil, nil)
+		require.Equal(t, "10", resp.Header.Get(storage.UploadOffset))
+	})
+
+	t.Run("ExceedLength", func(t *testing.T) {
+		u := create(10, nil)
+		resp := patch(u, 0, content[:11])
+		require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+		resp = do(http.MethodPost, url+"/files", map[string]string{storage.UploadLength: fmt.Sprint(tw.MaxSize + 1)}, nil)
+		require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	})
+
+	t.Run("Termination", func(t *testing.T) {
+		u := create(len(content), nil)
+		resp := do(http.MethodDelete, u, nil, nil)
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		resp = do(http.MethodHead, u, nil, nil)
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Expiration", func(t *testing.T) {
+		tw.Expiration = -time.Second
+		defer func() {
+			tw.Expiration = storage.DefaultTusExpiration
+		}()
+		u := create(len(content), nil)
+		resp := patch(u, 0, content[:10])
+		require.Equal(t, http.StatusGone, resp.StatusCode)
+		resp = do(http.MethodHead, u, nil, nil)
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodOptions, url+"/files", nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		require.Contains(t, resp.Header.Get(storage.TusExtension), "termination")
+
+		resp = do(http.MethodPost, url+"/files", map[string]string{storage.TusResumable: "0.2.0"}, nil)
+		require.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+		require.Equal(t, storage.TusProtocolVersion, resp.Header.Get(storage.TusVersion))
+	})
+}
+
+// writerOnly hides Put of bucket, so that TusWriter falls back to Write
+type writerOnly struct {
+	storage.Writer
+}
+
+func TestTusWriter_Writer(t *testing.T) {
+	bucket := storage.NewMemoryBucket()
+	for name, w := range map[string]storage.Writer{"Bucket": bucket, "Writer": writerOnly{bucket}} {
+		t.Run(name, func(t *testing.T) {
+			tw, err := storage.NewTusWriter(w, t.TempDir())
+			require.NoError(t, err)
+			defer tw.Close()
+			s := wine.NewTestServer(t)
+			tw.Route(s.Router, "files")
+			url := s.Run()
+
+			content := bytes.Repeat([]byte("wine"), 1000)
+			req, err := http.NewRequest(http.MethodPost, url+"/files", bytes.NewReader(content))
+			require.NoError(t, err)
+			req.Header.Set(storage.TusResumable, storage.TusProtocolVersion)
+			req.Header.Set(storage.UploadLength, fmt.Sprint(len(content)))
+			req.Header.Set("Content-Type", storage.OffsetOctetStream)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+			name := filepath.Base(resp.Header.Get("Location"))
+			require.Equal(t, "mem://"+name, resp.Header.Get("Content-Location"))
+			info, err := bucket.Stat(context.Background(), name)
+			require.NoError(t, err)
+			require.Equal(t, "text/plain; charset=utf-8", info.Type)
+			b, err := bucket.Read(context.Background(), name)
+			require.NoError(t, err)
+			require.Equal(t, content, b)
+		})
+	}
+}
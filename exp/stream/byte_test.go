@@ -2,8 +2,6 @@ package stream_test
 
 import (
 	"context"
-	"fmt"
-	"math/rand"
 	"net/http"
 	"testing"
 	"time"
@@ -31,12 +29,11 @@ func TestByteStream(t *testing.T) {
 			require.NoError(t, err)
 		}()
 	})
-	host := "localhost:" + fmt.Sprint(rand.Int()%1000+8000)
-	s := wine.NewServer(nil)
+	s := wine.NewTestServer(t)
 	s.Bind(http.MethodGet, "/", h)
-	go s.Run(host)
+	url := s.Run()
 
-	req, err := http.NewRequest(http.MethodGet, "http://"+host, nil)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	require.NoError(t, err)
 	r, err := stream.NewByteReader(http.DefaultClient, req)
 	require.NoError(t, err)
@@ -50,7 +47,7 @@ func TestByteStream(t *testing.T) {
 		}
 		res = append(res, p)
 	}
-	err = s.Shutdown()
+	err = s.Shutdown(context.Background())
 	assert.NoError(t, err)
 	require.Equal(t, packets, res)
 }
@@ -2,8 +2,6 @@ package stream_test
 
 import (
 	"context"
-	"fmt"
-	"math/rand"
 	"net/http"
 	"testing"
 	"time"
@@ -47,12 +45,11 @@ func TestJSONStream(t *testing.T) {
 		err := w.Close()
 		require.NoError(t, err)
 	})
-	host := "localhost:" + fmt.Sprint(rand.Int()%1000+8000)
-	s := wine.NewServer(nil)
+	s := wine.NewTestServer(t)
 	s.Bind(http.MethodGet, "/", h)
-	go s.Run(host)
+	url := s.Run()
 
-	req, err := http.NewRequest(http.MethodGet, "http://"+host, nil)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	require.NoError(t, err)
 	r, err := stream.NewJSONReader(http.DefaultClient, req)
 	require.NoError(t, err)
@@ -67,7 +64,7 @@ func TestJSONStream(t *testing.T) {
 		}
 		res = append(res, p)
 	}
-	err = s.Shutdown()
+	err = s.Shutdown(context.Background())
 	assert.NoError(t, err)
 	require.Empty(t, cmp.Diff(packets, res))
 }
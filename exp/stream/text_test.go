@@ -2,8 +2,6 @@ package stream_test
 
 import (
 	"context"
-	"fmt"
-	"math/rand"
 	"net/http"
 	"testing"
 
@@ -27,12 +25,11 @@ func TestTextStream(t *testing.T) {
 		err := w.Close()
 		require.NoError(t, err)
 	})
-	host := "localhost:" + fmt.Sprint(rand.Int()%1000+8000)
-	s := wine.NewServer(nil)
+	s := wine.NewTestServer(t)
 	s.Bind(http.MethodGet, "/", h)
-	go s.Run(host)
+	url := s.Run()
 
-	req, err := http.NewRequest(http.MethodGet, "http://"+host, nil)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	require.NoError(t, err)
 	r, err := stream.NewTextReader(http.DefaultClient, req)
 	require.NoError(t, err)
@@ -46,7 +43,7 @@ func TestTextStream(t *testing.T) {
 		}
 		res = append(res, s)
 	}
-	err = s.Shutdown()
+	err = s.Shutdown(context.Background())
 	assert.NoError(t, err)
 	require.Equal(t, packets, res)
 }
@@ -16,6 +16,9 @@ const (
 	versionPath  = "_wine/version"
 	endpointPath = "_wine/endpoints"
 	echoPath     = "_wine/echo"
+	openAPIPath  = "_wine/openapi.json"
+	readyPath    = "_wine/ready"
+	metricsPath  = "_wine/metrics"
 )
 
 func handleEcho(_ context.Context, req *Request) Responder {
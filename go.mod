@@ -4,6 +4,7 @@ go 1.16
 
 require (
 	github.com/gabriel-vasile/mimetype v1.2.0 // indirect
+	github.com/fxamacker/cbor/v2 v2.5.0
 	github.com/golang/geo v0.0.0-20210211234256-740aa86cb551 // indirect
 	github.com/golang/protobuf v1.5.2
 	github.com/google/go-cmp v0.5.5
@@ -23,13 +24,16 @@ require (
 	github.com/pelletier/go-toml v1.9.0 // indirect
 	github.com/spf13/afero v1.6.0 // indirect
 	github.com/stretchr/testify v1.6.1
-	golang.org/x/sys v0.0.0-20210403161142-5e06dd20ab57 // indirect
-	golang.org/x/text v0.3.6 // indirect
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	golang.org/x/crypto v0.23.0
+	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/text v0.15.0 // indirect
 	google.golang.org/protobuf v1.26.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
-//replace (
-//	github.com/gopub/wine/httpvalue => ./httpvalue
-//	github.com/gopub/wine/router => ./router
-//	github.com/gopub/wine/urlutil => ./urlutil
-//)
+replace (
+	github.com/gopub/wine/httpvalue => ./httpvalue
+	github.com/gopub/wine/router => ./router
+	github.com/gopub/wine/urlutil => ./urlutil
+)
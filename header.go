@@ -34,6 +34,7 @@ func (h *Header) Clone() *Header {
 	return c
 }
 
+// AllowOrigins sets raw CORS header. Use Router.SetCORS to validate origins and handle preflight requests
 func (h *Header) AllowOrigins(origins ...string) {
 	h.Header[httpvalue.ACLAllowOrigin] = origins
 }
@@ -0,0 +1,90 @@
+package httpvalue
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MediaRange is an element of Accept header, e.g. text/*;q=0.8
+type MediaRange struct {
+	Type string
+	Q    float64
+}
+
+// ParseAccept parses value of Accept header into media ranges sorted by q in descending order.
+// Parameters except q are ignored
+func ParseAccept(s string) []MediaRange {
+	var l []MediaRange
+	for _, part := range strings.Split(s, ",") {
+		params := strings.Split(part, ";")
+		r := MediaRange{
+			Type: strings.ToLower(strings.TrimSpace(params[0])),
+			Q:    1,
+		}
+		if r.Type == "" {
+			continue
+		}
+		if r.Type == "*" {
+			r.Type = "*/*"
+		}
+		for _, p := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+			if len(kv) != 2 || strings.ToLower(strings.TrimSpace(kv[0])) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil && q >= 0 && q <= 1 {
+				r.Q = q
+			}
+		}
+		l = append(l, r)
+	}
+	sort.SliceStable(l, func(i, j int) bool {
+		return l[i].Q > l[j].Q
+	})
+	return l
+}
+
+// NegotiateContentType returns the offer which is preferred most by Accept header in h.
+// Offers with equal quality are chosen in their order. The first offer is returned if there is no Accept header,
+// and empty string is returned if none is acceptable
+func NegotiateContentType(h http.Header, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	accept := strings.Join(h.Values(Accept), ",")
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+	ranges := ParseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(ranges, strings.ToLower(offer)); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// acceptQuality returns q of the most specific range which matches typ
+func acceptQuality(ranges []MediaRange, typ string) float64 {
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		var s int
+		switch {
+		case r.Type == typ:
+			s = 2
+		case r.Type == "*/*":
+			s = 0
+		case strings.HasSuffix(r.Type, "/*") && strings.HasPrefix(typ, r.Type[:len(r.Type)-1]):
+			s = 1
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = r.Q, s
+		}
+	}
+	return q
+}
@@ -8,17 +8,30 @@ import (
 
 const (
 	Authorization       = "Authorization"
+	Accept              = "Accept"
 	AcceptEncoding      = "Accept-Encoding"
 	ACLAllowCredentials = "Access-Control-Allow-Credentials"
 	ACLAllowHeaders     = "Access-Control-Allow-Headers"
 	ACLAllowMethods     = "Access-Control-Allow-Methods"
 	ACLAllowOrigin      = "Access-Control-Allow-Origin"
 	ACLExposeHeaders    = "Access-Control-Expose-Headers"
+	ACLMaxAge           = "Access-Control-Max-Age"
+	ACLRequestMethod    = "Access-Control-Request-Method"
+	ACLRequestHeaders   = "Access-Control-Request-Headers"
 	ContentType         = "Content-Type"
 	ContentDisposition  = "Content-Disposition"
 	ContentEncoding     = "Content-Encoding"
 	Location            = "Location"
 	Cookies             = "Cookies"
+	Origin              = "Origin"
+	Vary                = "Vary"
+	RetryAfter          = "Retry-After"
+	XForwardedFor       = "X-Forwarded-For"
+	XRealIP             = "X-Real-Ip"
+
+	RateLimitLimit     = "RateLimit-Limit"
+	RateLimitRemaining = "RateLimit-Remaining"
+	RateLimitReset     = "RateLimit-Reset"
 
 	RequestID = "X-Request-Id"
 
@@ -14,6 +14,14 @@ const (
 	XML      = "application/xml"
 	XHTML    = "application/xhtml+xml"
 	Protobuf = "application/x-protobuf"
+	CSV      = "text/csv"
+	MsgPack  = "application/msgpack"
+	// XMsgPack is an alias of MsgPack used by many clients
+	XMsgPack = "application/x-msgpack"
+	CBOR     = "application/cbor"
+	YAML     = "application/yaml"
+	// XYAML is an alias of YAML used by many clients
+	XYAML = "application/x-yaml"
 
 	FormData = "multipart/form-data"
 	GIF      = "image/gif"
@@ -10,6 +10,7 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/gopub/conv"
 	"github.com/gopub/errors"
+	"github.com/gopub/wine/codec"
 	"github.com/gopub/wine/httpvalue"
 )
 
@@ -41,6 +42,9 @@ func DecodeResponse(resp *http.Response, result interface{}) error {
 		}
 		return conv.SetBytes(result, body)
 	default:
+		if c := codec.Get(ct); c != nil {
+			return c.Unmarshal(body, result)
+		}
 		return errors.New("invalid result")
 	}
 }
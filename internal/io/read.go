@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/gopub/types"
+	"github.com/gopub/wine/codec"
 	"github.com/gopub/wine/httpvalue"
 )
 
@@ -32,11 +33,7 @@ func (p *RequestParams) Combine() types.M {
 }
 
 func ReadRequest(req *http.Request, maxMemory types.ByteUnit) (*RequestParams, []byte, error) {
-	params := &RequestParams{
-		CookieParams: ReadCookies(req.Cookies()),
-		HeaderParams: ReadHeader(req.Header),
-		QueryParams:  ReadValues(req.URL.Query()),
-	}
+	params := ReadParams(req)
 	bp, body, err := ReadBody(req, maxMemory)
 	if err != nil {
 		return params, body, fmt.Errorf("read request body: %w", err)
@@ -45,6 +42,16 @@ func ReadRequest(req *http.Request, maxMemory types.ByteUnit) (*RequestParams, [
 	return params, body, nil
 }
 
+// ReadParams reads params from cookies, header and query, leaving body unread
+func ReadParams(req *http.Request) *RequestParams {
+	return &RequestParams{
+		CookieParams: ReadCookies(req.Cookies()),
+		HeaderParams: ReadHeader(req.Header),
+		QueryParams:  ReadValues(req.URL.Query()),
+		BodyParams:   types.M{},
+	}
+}
+
 func ReadCookies(cookies []*http.Cookie) types.M {
 	params := types.M{}
 	for _, c := range cookies {
@@ -164,7 +171,14 @@ func ReadBody(req *http.Request, maxMemory types.ByteUnit) (types.M, []byte, err
 		body, err := ioutil.ReadAll(req.Body)
 		req.Body.Close()
 		if err != nil {
-			return params, nil, fmt.Errorf("read json body: %w", err)
+			return params, nil, fmt.Errorf("read body: %w", err)
+		}
+		// Body of other codecs is decoded into params if it's a map, e.g. MessagePack, CBOR and YAML.
+		// Otherwise it's decoded into model by Request
+		if c := codec.Get(typ); c != nil && len(body) > 0 {
+			if err = c.Unmarshal(body, &params); err != nil {
+				params = types.M{}
+			}
 		}
 		return params, body, nil
 	}
@@ -29,6 +29,7 @@ type ResponseWriter struct {
 	http.ResponseWriter
 	status int
 	body   []byte
+	size   int64
 }
 
 func NewResponseWriter(rw http.ResponseWriter) *ResponseWriter {
@@ -55,7 +56,9 @@ func (w *ResponseWriter) Write(data []byte) (int, error) {
 		w.status = http.StatusOK
 	}
 	w.body = data
-	return w.ResponseWriter.Write(data)
+	n, err := w.ResponseWriter.Write(data)
+	w.size += int64(n)
+	return n, err
 }
 
 func (w *ResponseWriter) Status() int {
@@ -78,3 +81,8 @@ func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 func (w *ResponseWriter) Body() []byte {
 	return w.body
 }
+
+// Size returns number of bytes written into body
+func (w *ResponseWriter) Size() int64 {
+	return w.size
+}
@@ -116,6 +116,16 @@ func (r *Response) SetValue(v interface{}) {
 	r.value = v
 }
 
+// Clone returns a copy of r, whose header can be modified without affecting r, e.g. shared responses
+func (r *Response) Clone() *Response {
+	return &Response{
+		status:         r.status,
+		header:         r.header.Clone(),
+		value:          r.value,
+		marshaledValue: r.marshaledValue,
+	}
+}
+
 type Func func(ctx context.Context, w http.ResponseWriter)
 
 func (f Func) Respond(ctx context.Context, w http.ResponseWriter) {
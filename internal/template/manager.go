@@ -1,13 +1,17 @@
 package template
 
 import (
+	"fmt"
 	"html/template"
 	"io"
+	"path/filepath"
 )
 
 type Manager struct {
 	templates []*template.Template
-	funcMap   template.FuncMap
+	// pristine templates are never executed, so that they can be cloned with funcs of each execution
+	pristine []*template.Template
+	funcMap  template.FuncMap
 }
 
 func NewManager() *Manager {
@@ -19,19 +23,29 @@ func NewManager() *Manager {
 
 // AddGlobTemplate adds a template by parsing template files with pattern
 func (m *Manager) AddGlobTemplate(pattern string) {
-	tmpl := template.Must(template.ParseGlob(pattern))
-	m.AddTemplate(tmpl)
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		panic(err)
+	}
+	if len(files) == 0 {
+		panic(fmt.Sprintf("html/template: pattern matches no files: %#q", pattern))
+	}
+	m.AddFilesTemplate(files...)
 }
 
 // AddFilesTemplate adds a template by parsing template files
 func (m *Manager) AddFilesTemplate(files ...string) {
-	tmpl := template.Must(template.ParseFiles(files...))
-	m.AddTemplate(tmpl)
+	if len(files) == 0 {
+		panic("html/template: no files named in call to ParseFiles")
+	}
+	// Named after the first file like template.ParseFiles, but parsed with funcs
+	tmpl := template.New(filepath.Base(files[0])).Funcs(m.funcMap)
+	m.AddTemplate(template.Must(tmpl.ParseFiles(files...)))
 }
 
 // AddTextTemplate adds a template by parsing texts
 func (m *Manager) AddTextTemplate(name string, texts ...string) {
-	tmpl := template.New(name)
+	tmpl := template.New(name).Funcs(m.funcMap)
 	for _, txt := range texts {
 		tmpl = template.Must(tmpl.Parse(txt))
 	}
@@ -44,6 +58,11 @@ func (m *Manager) AddTemplate(tmpl *template.Template) {
 		tmpl.Funcs(m.funcMap)
 	}
 	m.templates = append(m.templates, tmpl)
+	if c, err := tmpl.Clone(); err == nil {
+		m.pristine = append(m.pristine, c)
+	} else {
+		m.pristine = append(m.pristine, nil)
+	}
 }
 
 // AddTemplateFuncMap adds template functions
@@ -63,10 +82,28 @@ func (m *Manager) AddTemplateFuncMap(funcMap template.FuncMap) {
 	for _, tmpl := range m.templates {
 		tmpl.Funcs(funcMap)
 	}
+	for _, tmpl := range m.pristine {
+		if tmpl != nil {
+			tmpl.Funcs(funcMap)
+		}
+	}
 }
 
 func (m *Manager) Execute(w io.Writer, name string, params interface{}) {
-	for _, tmpl := range m.templates {
+	m.ExecuteWithFuncs(w, name, params, nil)
+}
+
+// ExecuteWithFuncs executes template with funcs of this execution, e.g. csrf token of the request.
+// Templates are cloned if funcs isn't empty, so funcs must be declared ahead in order to parse templates
+func (m *Manager) ExecuteWithFuncs(w io.Writer, name string, params interface{}, funcs template.FuncMap) {
+	for i, tmpl := range m.templates {
+		if len(funcs) > 0 && m.pristine[i] != nil {
+			c, err := m.pristine[i].Clone()
+			if err != nil {
+				continue
+			}
+			tmpl = c.Funcs(funcs)
+		}
 		var err error
 		if name == "" {
 			err = tmpl.Execute(w, params)
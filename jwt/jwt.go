@@ -0,0 +1,298 @@
+// Package jwt verifies and signs JSON Web Tokens with HS256, RS256 and ES256
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+var (
+	ErrMalformed        = errors.New("malformed token")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpired          = errors.New("token is expired")
+	ErrNotValidYet      = errors.New("token is not valid yet")
+)
+
+var encoding = base64.RawURLEncoding
+
+type Header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Claims are claims of token. Numeric values are decoded as json.Number
+type Claims map[string]interface{}
+
+func (c Claims) String(key string) string {
+	switch v := c[key].(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return ""
+	}
+}
+
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+func (c Claims) Issuer() string {
+	return c.String("iss")
+}
+
+// Audience returns aud, which can be a string or an array of strings
+func (c Claims) Audience() []string {
+	switch v := c["aud"].(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		l := make([]string, 0, len(v))
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				l = append(l, s)
+			}
+		}
+		return l
+	default:
+		return nil
+	}
+}
+
+// Time returns time of NumericDate claim, e.g. exp, nbf, iat
+func (c Claims) Time(key string) (time.Time, bool) {
+	var sec float64
+	switch v := c[key].(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		sec = f
+	case float64:
+		sec = v
+	case int64:
+		sec = float64(v)
+	case int:
+		sec = float64(v)
+	default:
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(sec*float64(time.Second))), true
+}
+
+// GetID returns user id parsed from sub, it's 0 if sub isn't an integer.
+// Claims can be used as ctxutil.User
+func (c Claims) GetID() int64 {
+	id, _ := strconv.ParseInt(c.Subject(), 10, 64)
+	return id
+}
+
+// Token is a verified token
+type Token struct {
+	Header Header
+	Claims Claims
+}
+
+// Verifier verifies signatures and registered claims of tokens
+type Verifier struct {
+	keys KeySet
+	// Issuer is checked if it's not empty
+	Issuer string
+	// Audience is checked if it's not empty
+	Audience string
+	// ClockSkew is tolerated when checking exp and nbf
+	ClockSkew time.Duration
+	// RequireExpiration rejects tokens without exp
+	RequireExpiration bool
+}
+
+func NewVerifier(keys KeySet) *Verifier {
+	return &Verifier{
+		keys:              keys,
+		ClockSkew:         time.Minute,
+		RequireExpiration: true,
+	}
+}
+
+// Verify verifies s and returns the token
+func (v *Verifier) Verify(ctx context.Context, s string) (*Token, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+	t := new(Token)
+	if err := decodeSegment(parts[0], &t.Header); err != nil {
+		return nil, fmt.Errorf("decode header: %w", err)
+	}
+	sig, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("decode signature: %w", ErrMalformed)
+	}
+	key, err := v.keys.Key(ctx, t.Header.Alg, t.Header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("get key: %w", err)
+	}
+	if err = verifySignature(t.Header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+	if err = decodeSegment(parts[1], &t.Claims); err != nil {
+		return nil, fmt.Errorf("decode claims: %w", err)
+	}
+	if err = v.verifyClaims(t.Claims); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (v *Verifier) verifyClaims(c Claims) error {
+	now := time.Now()
+	if exp, ok := c.Time("exp"); ok {
+		if now.After(exp.Add(v.ClockSkew)) {
+			return ErrExpired
+		}
+	} else if v.RequireExpiration {
+		return errors.New("missing exp")
+	}
+	if nbf, ok := c.Time("nbf"); ok && now.Add(v.ClockSkew).Before(nbf) {
+		return ErrNotValidYet
+	}
+	if v.Issuer != "" && c.Issuer() != v.Issuer {
+		return fmt.Errorf("invalid issuer %s", c.Issuer())
+	}
+	if v.Audience != "" {
+		found := false
+		for _, a := range c.Audience() {
+			if a == v.Audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("invalid audience %v", c.Audience())
+		}
+	}
+	return nil
+}
+
+func decodeSegment(s string, v interface{}) error {
+	b, err := encoding.DecodeString(s)
+	if err != nil {
+		return ErrMalformed
+	}
+	d := json.NewDecoder(strings.NewReader(string(b)))
+	d.UseNumber()
+	if err = d.Decode(v); err != nil {
+		return ErrMalformed
+	}
+	return nil
+}
+
+func verifySignature(alg string, key interface{}, signingInput string, sig []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+	switch alg {
+	case HS256:
+		k, ok := key.([]byte)
+		if !ok {
+			return fmt.Errorf("%s key is %T", alg, key)
+		}
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return ErrInvalidSignature
+		}
+	case RS256:
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s key is %T", alg, key)
+		}
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) != nil {
+			return ErrInvalidSignature
+		}
+	case ES256:
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s key is %T", alg, key)
+		}
+		if len(sig) != 64 {
+			return ErrInvalidSignature
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(k, digest[:], r, s) {
+			return ErrInvalidSignature
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	return nil
+}
+
+// Sign signs claims with key: []byte for HS256, *rsa.PrivateKey for RS256 and *ecdsa.PrivateKey for ES256
+func Sign(claims Claims, alg, kid string, key interface{}) (string, error) {
+	h, err := json.Marshal(Header{Alg: alg, Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", fmt.Errorf("marshal header: %w", err)
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("marshal claims: %w", err)
+	}
+	input := encoding.EncodeToString(h) + "." + encoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(input))
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		if alg != HS256 {
+			return "", fmt.Errorf("cannot sign %s with %T", alg, key)
+		}
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		if alg != RS256 {
+			return "", fmt.Errorf("cannot sign %s with %T", alg, key)
+		}
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			return "", fmt.Errorf("sign: %w", err)
+		}
+	case *ecdsa.PrivateKey:
+		if alg != ES256 {
+			return "", fmt.Errorf("cannot sign %s with %T", alg, key)
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			return "", fmt.Errorf("sign: %w", err)
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	default:
+		return "", fmt.Errorf("unsupported key %T", key)
+	}
+	return input + "." + encoding.EncodeToString(sig), nil
+}
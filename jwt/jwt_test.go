@@ -0,0 +1,159 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gopub/wine/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier(t *testing.T) {
+	ctx := context.Background()
+	secret := []byte("secret")
+	v := jwt.NewVerifier(jwt.HMACKey(secret))
+	v.Issuer = "wine"
+	v.Audience = "api"
+	v.ClockSkew = 10 * time.Second
+	sign := func(c jwt.Claims) string {
+		s, err := jwt.Sign(c, jwt.HS256, "", secret)
+		require.NoError(t, err)
+		return s
+	}
+	now := time.Now()
+
+	t.Run("Valid", func(t *testing.T) {
+		token, err := v.Verify(ctx, sign(jwt.Claims{
+			"sub": "12", "iss": "wine", "aud": []string{"web", "api"}, "exp": now.Add(time.Minute).Unix(),
+		}))
+		require.NoError(t, err)
+		require.Equal(t, int64(12), token.Claims.GetID())
+		require.Equal(t, jwt.HS256, token.Header.Alg)
+	})
+
+	t.Run("ClockSkew", func(t *testing.T) {
+		_, err := v.Verify(ctx, sign(jwt.Claims{"iss": "wine", "aud": "api", "exp": now.Add(-5 * time.Second).Unix()}))
+		require.NoError(t, err)
+		_, err = v.Verify(ctx, sign(jwt.Claims{"iss": "wine", "aud": "api", "exp": now.Add(-time.Minute).Unix()}))
+		require.True(t, errors.Is(err, jwt.ErrExpired), err)
+		_, err = v.Verify(ctx, sign(jwt.Claims{"iss": "wine", "aud": "api", "exp": now.Add(time.Hour).Unix(), "nbf": now.Add(time.Minute).Unix()}))
+		require.True(t, errors.Is(err, jwt.ErrNotValidYet), err)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		exp := now.Add(time.Minute).Unix()
+		for _, c := range []jwt.Claims{
+			{"iss": "other", "aud": "api", "exp": exp},
+			{"iss": "wine", "aud": "web", "exp": exp},
+			{"iss": "wine", "aud": "api"},
+		} {
+			_, err := v.Verify(ctx, sign(c))
+			require.Error(t, err, c)
+		}
+
+		s, err := jwt.Sign(jwt.Claims{"iss": "wine", "aud": "api", "exp": exp}, jwt.HS256, "", []byte("other"))
+		require.NoError(t, err)
+		_, err = v.Verify(ctx, s)
+		require.True(t, errors.Is(err, jwt.ErrInvalidSignature), err)
+
+		_, err = v.Verify(ctx, "a.b")
+		require.True(t, errors.Is(err, jwt.ErrMalformed), err)
+
+		// Public key must not be used as hmac secret
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		s, err = jwt.Sign(jwt.Claims{"exp": exp}, jwt.RS256, "", key)
+		require.NoError(t, err)
+		_, err = v.Verify(ctx, s)
+		require.Error(t, err)
+	})
+}
+
+func TestJWKS(t *testing.T) {
+	ctx := context.Background()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwks, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "r1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "e1", "crv": "P-256", "x": b64(ecKey.X.Bytes()), "y": b64(ecKey.Y.Bytes())},
+		},
+	})
+	require.NoError(t, err)
+	var fetches int32
+	var blocked chan struct{}
+	fetching := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fetches, 1) > 1 && blocked != nil {
+			fetching <- struct{}{}
+			<-blocked
+		}
+		w.Write(jwks)
+	}))
+	defer srv.Close()
+
+	keys := jwt.NewJWKSFromURL(srv.URL, nil, time.Hour)
+	v := jwt.NewVerifier(keys)
+	claims := jwt.Claims{"sub": "1", "exp": time.Now().Add(time.Minute).Unix()}
+	s, err := jwt.Sign(claims, jwt.RS256, "r1", rsaKey)
+	require.NoError(t, err)
+	_, err = v.Verify(ctx, s)
+	require.NoError(t, err)
+
+	s, err = jwt.Sign(claims, jwt.ES256, "e1", ecKey)
+	require.NoError(t, err)
+	_, err = v.Verify(ctx, s)
+	require.NoError(t, err)
+
+	// Algorithm must match key type
+	s, err = jwt.Sign(claims, jwt.ES256, "r1", ecKey)
+	require.NoError(t, err)
+	_, err = v.Verify(ctx, s)
+	require.Error(t, err)
+
+	// Unknown kid doesn't refresh keys within the minimum interval
+	s, err = jwt.Sign(claims, jwt.RS256, "r2", rsaKey)
+	require.NoError(t, err)
+	_, err = v.Verify(ctx, s)
+	require.Error(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	// Slow refresh doesn't block verification by cached keys
+	blocked = make(chan struct{})
+	refreshed := make(chan error, 1)
+	go func() {
+		refreshed <- keys.Refresh(ctx)
+	}()
+	<-fetching
+	s, err = jwt.Sign(claims, jwt.RS256, "r1", rsaKey)
+	require.NoError(t, err)
+	verified := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(ctx, s)
+		verified <- err
+	}()
+	select {
+	case err = <-verified:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("verification waits for refresh")
+	}
+	close(blocked)
+	require.NoError(t, <-refreshed)
+	require.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+}
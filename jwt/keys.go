@@ -0,0 +1,292 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gopub/log"
+)
+
+var logger = log.Default()
+
+const (
+	defaultJWKSTTL = time.Hour
+	// minJWKSRefreshInterval limits refreshing on unknown kid, which can be triggered by forged tokens
+	minJWKSRefreshInterval = time.Minute
+)
+
+// KeySet provides keys to verify signatures by alg and kid of token headers.
+// Keys are []byte for HS256, *rsa.PublicKey for RS256 and *ecdsa.PublicKey for ES256
+type KeySet interface {
+	Key(ctx context.Context, alg, kid string) (interface{}, error)
+}
+
+type KeySetFunc func(ctx context.Context, alg, kid string) (interface{}, error)
+
+func (f KeySetFunc) Key(ctx context.Context, alg, kid string) (interface{}, error) {
+	return f(ctx, alg, kid)
+}
+
+// HMACKey returns a key set which only accepts HS256 tokens signed by secret
+func HMACKey(secret []byte) KeySet {
+	return KeySetFunc(func(_ context.Context, alg, _ string) (interface{}, error) {
+		if alg != HS256 {
+			return nil, fmt.Errorf("unexpected algorithm %q", alg)
+		}
+		return secret, nil
+	})
+}
+
+// PublicKey returns a key set which only accepts tokens signed by private key of key, i.e. RS256 for *rsa.PublicKey
+// and ES256 for *ecdsa.PublicKey
+func PublicKey(key interface{}) KeySet {
+	return KeySetFunc(func(_ context.Context, alg, _ string) (interface{}, error) {
+		if !matchAlg(alg, key) {
+			return nil, fmt.Errorf("unexpected algorithm %q", alg)
+		}
+		return key, nil
+	})
+}
+
+func matchAlg(alg string, key interface{}) bool {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return alg == RS256
+	case *ecdsa.PublicKey:
+		return alg == ES256
+	default:
+		return false
+	}
+}
+
+// JWKS is a JSON Web Key Set loaded from a file or url, which is cached for a while
+type JWKS struct {
+	fetch func(ctx context.Context) ([]byte, error)
+	ttl   time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+	// refreshing is the fetch in flight, which concurrent refreshes wait for instead of fetching again
+	refreshing *jwksFetch
+}
+
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+var _ KeySet = (*JWKS)(nil)
+
+// NewJWKSFromFile loads key set from file, which is reloaded every ttl
+func NewJWKSFromFile(name string, ttl time.Duration) (*JWKS, error) {
+	s := newJWKS(func(_ context.Context) ([]byte, error) {
+		return ioutil.ReadFile(name)
+	}, ttl)
+	if err := s.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewJWKSFromURL returns key set fetched from url, e.g. https://example.com/.well-known/jwks.json.
+// Keys are fetched at the first verification and cached for ttl, or refreshed once a token has unknown kid
+func NewJWKSFromURL(url string, client *http.Client, ttl time.Duration) *JWKS {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return newJWKS(func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("get %s: %s", url, resp.Status)
+		}
+		return ioutil.ReadAll(resp.Body)
+	}, ttl)
+}
+
+func newJWKS(fetch func(ctx context.Context) ([]byte, error), ttl time.Duration) *JWKS {
+	if ttl <= 0 {
+		ttl = defaultJWKSTTL
+	}
+	return &JWKS{
+		fetch: fetch,
+		ttl:   ttl,
+	}
+}
+
+// Refresh fetches keys no matter whether cache expires
+func (s *JWKS) Refresh(ctx context.Context) error {
+	return s.refresh(ctx)
+}
+
+// refresh fetches keys without holding s.mu, so that keys in cache are still available during the fetch
+func (s *JWKS) refresh(ctx context.Context) error {
+	s.mu.Lock()
+	if f := s.refreshing; f != nil {
+		s.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	f := &jwksFetch{done: make(chan struct{})}
+	s.refreshing = f
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+
+	var keys map[string]interface{}
+	b, err := s.fetch(ctx)
+	if err != nil {
+		err = fmt.Errorf("fetch jwks: %w", err)
+	} else {
+		keys, err = parseJWKS(b)
+	}
+
+	s.mu.Lock()
+	if err == nil {
+		s.keys = keys
+	}
+	s.refreshing = nil
+	s.mu.Unlock()
+	f.err = err
+	close(f.done)
+	return err
+}
+
+func (s *JWKS) Key(ctx context.Context, alg, kid string) (interface{}, error) {
+	s.mu.Lock()
+	elapsed := time.Since(s.fetchedAt)
+	stale := s.keys == nil || elapsed > s.ttl || (s.keys[kid] == nil && elapsed > minJWKSRefreshInterval)
+	s.mu.Unlock()
+	if stale {
+		if err := s.refresh(ctx); err != nil {
+			s.mu.Lock()
+			cached := s.keys != nil
+			s.mu.Unlock()
+			if !cached {
+				return nil, err
+			}
+			// Keep using cached keys, e.g. jwks url is temporarily unavailable
+			logger.Errorf("Refresh jwks: %v", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.keys[kid]
+	if key == nil && kid == "" && len(s.keys) == 1 {
+		for _, k := range s.keys {
+			key = k
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if !matchAlg(alg, key) {
+		return nil, fmt.Errorf("unexpected algorithm %q", alg)
+	}
+	return key, nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func parseJWKS(b []byte) (map[string]interface{}, error) {
+	var set struct {
+		Keys []*jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("unmarshal jwks: %w", err)
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			// Ignore keys of unsupported types
+			logger.Warnf("Ignore json web key %s: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no valid keys in jwks")
+	}
+	return keys, nil
+}
+
+func (k *jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode n: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode e: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid e")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decode x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decode y: %w", err)
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := encoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,121 @@
+package wine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ShutdownHook is called after the server becomes unready and before it stops accepting new connections
+type ShutdownHook func(ctx context.Context) error
+
+// RegisterOnShutdown registers a hook which will be called during shutdown, e.g. websocket.Server.Shutdown
+func (s *Server) RegisterOnShutdown(h ShutdownHook) {
+	s.mu.Lock()
+	s.shutdownHooks = append(s.shutdownHooks, h)
+	s.mu.Unlock()
+}
+
+// Ready reports whether server is ready to accept requests. It becomes false once shutdown starts
+func (s *Server) Ready() bool {
+	return atomic.LoadInt32(&s.draining) == 0
+}
+
+// InFlight returns the number of requests being handled
+func (s *Server) InFlight() int64 {
+	return atomic.LoadInt64(&s.inFlight)
+}
+
+// Shutdown gracefully shuts down the server:
+// 1. Flip readiness to unhealthy and wait DrainDelay, so that load balancers stop sending new requests
+// 2. Call shutdown hooks, e.g. close websocket connections with close frame
+// 3. Stop listeners and wait for in-flight requests until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&s.draining, 0, 1) {
+		return errors.New("server is shutting down")
+	}
+	logger.Infof("Shutting down, in-flight requests: %d", s.InFlight())
+
+	s.mu.Lock()
+	hooks := s.shutdownHooks
+	srv := s.server
+	s.mu.Unlock()
+
+	if s.DrainDelay > 0 {
+		select {
+		case <-time.After(s.DrainDelay):
+			break
+		case <-ctx.Done():
+			// Stop anyway, otherwise the server could never be stopped as shutdown can't be retried
+			if srv != nil {
+				srv.Close()
+			}
+			return fmt.Errorf("drain: %w", ctx.Err())
+		}
+	}
+
+	var err error
+	for _, h := range hooks {
+		if er := h(ctx); er != nil {
+			logger.Errorf("Shutdown hook: %v", er)
+			if err == nil {
+				err = er
+			}
+		}
+	}
+
+	if srv != nil {
+		if er := srv.Shutdown(ctx); er != nil {
+			// Shutdown leaves active connections open once ctx is done
+			srv.Close()
+			return fmt.Errorf("shutdown http server: %w", er)
+		}
+	}
+
+	// Hijacked connections are not tracked by http.Server
+	t := time.NewTicker(10 * time.Millisecond)
+	defer t.Stop()
+	for s.InFlight() > 0 {
+		select {
+		case <-t.C:
+			break
+		case <-ctx.Done():
+			return fmt.Errorf("wait for %d in-flight requests: %w", s.InFlight(), ctx.Err())
+		}
+	}
+	logger.Infof("Shutdown completed")
+	return err
+}
+
+// ShutdownOnSignal shuts down server gracefully within timeout once receiving any of signals.
+// SIGTERM and SIGINT are used if no signal is specified
+func (s *Server) ShutdownOnSignal(timeout time.Duration, signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGTERM, syscall.SIGINT}
+	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, signals...)
+	go func() {
+		sig := <-c
+		signal.Stop(c)
+		logger.Infof("Received signal: %v", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			logger.Errorf("Shutdown: %v", err)
+		}
+	}()
+}
+
+func (s *Server) handleReady(_ context.Context, _ *Request) Responder {
+	if s.Ready() {
+		return Text(http.StatusOK, "ready")
+	}
+	return Text(http.StatusServiceUnavailable, "shutting down")
+}
@@ -0,0 +1,97 @@
+package wine_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gopub/wine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Shutdown(t *testing.T) {
+	newServer := func() (s *wine.Server, started, release chan struct{}) {
+		s = wine.NewServer(nil)
+		started = make(chan struct{})
+		release = make(chan struct{})
+		s.Get("slow", func(ctx context.Context, req *wine.Request) wine.Responder {
+			close(started)
+			<-release
+			return wine.OK
+		})
+		go s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+		<-started
+		return s, started, release
+	}
+
+	t.Run("Drain", func(t *testing.T) {
+		s, _, release := newServer()
+		var hooked int32
+		s.RegisterOnShutdown(func(ctx context.Context) error {
+			atomic.StoreInt32(&hooked, 1)
+			return nil
+		})
+		require.True(t, s.Ready())
+		require.EqualValues(t, 1, s.InFlight())
+
+		errC := make(chan error, 1)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			errC <- s.Shutdown(ctx)
+		}()
+		require.Eventually(t, func() bool {
+			return !s.Ready()
+		}, time.Second, 10*time.Millisecond)
+
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_wine/ready", nil))
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+		close(release)
+		require.NoError(t, <-errC)
+		require.EqualValues(t, 0, s.InFlight())
+		require.EqualValues(t, 1, atomic.LoadInt32(&hooked))
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		s, _, release := newServer()
+		defer close(release)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		require.Error(t, s.Shutdown(ctx))
+	})
+
+	t.Run("DrainTimeout", func(t *testing.T) {
+		s := wine.NewServer(nil)
+		s.DrainDelay = time.Minute
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		serveErrC := make(chan error, 1)
+		go func() {
+			serveErrC <- s.Serve(l)
+		}()
+		require.Eventually(t, func() bool {
+			resp, err := http.Get("http://" + l.Addr().String() + "/_wine/ready")
+			if err != nil {
+				return false
+			}
+			resp.Body.Close()
+			return true
+		}, time.Second, 10*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		require.Error(t, s.Shutdown(ctx))
+		select {
+		case err := <-serveErrC:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			require.Fail(t, "server is still running")
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package wine
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	unixAddrPrefix = "unix:"
+	fdAddrPrefix   = "fd:"
+
+	// File descriptors passed by systemd socket activation start from 3, following stdin, stdout and stderr
+	listenFDsStart = 3
+)
+
+var inherited struct {
+	once      sync.Once
+	listeners []net.Listener
+	err       error
+}
+
+// Listen announces on addr which can be:
+//   - host:port, e.g. :8000, 127.0.0.1:0 for TCP
+//   - unix:path, e.g. unix:/run/wine.sock for Unix domain socket
+//   - fd:N, e.g. fd:0 for the Nth listener inherited via LISTEN_FDS
+func Listen(addr string) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(addr, unixAddrPrefix):
+		return ListenUnix(strings.TrimPrefix(addr, unixAddrPrefix))
+	case strings.HasPrefix(addr, fdAddrPrefix):
+		i, err := strconv.Atoi(strings.TrimPrefix(addr, fdAddrPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid addr %s: %w", addr, err)
+		}
+		l, err := InheritedListeners()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= len(l) {
+			return nil, fmt.Errorf("no inherited listener %d, total %d", i, len(l))
+		}
+		return l[i], nil
+	default:
+		return net.Listen("tcp", addr)
+	}
+}
+
+// ListenUnix announces on Unix domain socket at path.
+// Stale socket file left by a crashed process is removed, while the one in use makes ListenUnix fail
+func ListenUnix(path string) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("empty unix socket path")
+	}
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		conn, err := net.DialTimeout("unix", path, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("unix socket %s is in use", path)
+		}
+		if err = os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale unix socket: %w", err)
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// InheritedListeners returns listeners passed by systemd socket activation, or by parent process during
+// zero-downtime restart, following the LISTEN_FDS protocol. LISTEN_PID is checked if it's set.
+// Inherited file descriptors are consumed at the first call, the same listeners are returned afterwards
+func InheritedListeners() ([]net.Listener, error) {
+	inherited.once.Do(func() {
+		inherited.listeners, inherited.err = inheritListeners()
+	})
+	return inherited.listeners, inherited.err
+}
+
+func inheritListeners() ([]net.Listener, error) {
+	if pid := os.Getenv("LISTEN_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	s := os.Getenv("LISTEN_FDS")
+	if s == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %s", s)
+	}
+	// Don't pass them to child processes
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	l := make([]net.Listener, 0, n)
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		ln, err := net.FileListener(f)
+		// FileListener dups fd, so the inherited one can be closed
+		f.Close()
+		if err != nil {
+			for _, ln := range l {
+				ln.Close()
+			}
+			return nil, fmt.Errorf("create listener from fd %d: %w", fd, err)
+		}
+		l = append(l, ln)
+	}
+	return l, nil
+}
+
+// ListenerFile returns a dup of l's file descriptor, which can be passed to a child process via exec.Cmd.ExtraFiles.
+// Child process can restore it by InheritedListeners if LISTEN_FDS is set accordingly
+func ListenerFile(l net.Listener) (*os.File, error) {
+	fl, ok := l.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, fmt.Errorf("cannot get file from %T", l)
+	}
+	return fl.File()
+}
@@ -0,0 +1,93 @@
+package wine_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gopub/wine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_RunUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wine.sock")
+	s := wine.NewServer(nil)
+	s.Get("hello", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.Text(http.StatusOK, "hello")
+	})
+	errC := make(chan error, 1)
+	go func() {
+		errC <- s.RunUnix(path)
+	}()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return new(net.Dialer).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+	require.Eventually(t, func() bool {
+		resp, err := client.Get("http://unix/hello")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "hello", string(b))
+		return true
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, "unix:"+path, s.Addr())
+
+	t.Run("InUse", func(t *testing.T) {
+		_, err := wine.ListenUnix(path)
+		require.Error(t, err)
+	})
+
+	client.CloseIdleConnections()
+	require.NoError(t, s.Shutdown(context.Background()))
+	require.NoError(t, <-errC)
+}
+
+func TestServer_Addr(t *testing.T) {
+	s := wine.NewServer(nil)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- s.Serve(l)
+	}()
+	// Addr and URL are read while Serve assigns them
+	require.Eventually(t, func() bool {
+		return s.Addr() == l.Addr().String()
+	}, time.Second, time.Millisecond)
+	require.Equal(t, "http://"+l.Addr().String(), s.URL())
+	require.NoError(t, s.Shutdown(context.Background()))
+	require.NoError(t, <-errC)
+	require.Empty(t, s.Addr())
+}
+
+func TestListen(t *testing.T) {
+	t.Run("StaleUnixSocket", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "wine.sock")
+		l, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		// Keep socket file as if the process crashed
+		l.(*net.UnixListener).SetUnlinkOnClose(false)
+		require.NoError(t, l.Close())
+
+		l, err = wine.Listen("unix:" + path)
+		require.NoError(t, err)
+		require.NoError(t, l.Close())
+	})
+
+	t.Run("NoInheritedListener", func(t *testing.T) {
+		_, err := wine.Listen("fd:0")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,64 @@
+package wine
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gopub/wine/httpvalue"
+	"github.com/gopub/wine/internal/respond"
+	"github.com/gopub/wine/metrics"
+)
+
+type serverMetrics struct {
+	registry *metrics.Registry
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+	size     *metrics.HistogramVec
+}
+
+func newServerMetrics(s *Server) *serverMetrics {
+	m := &serverMetrics{
+		registry: metrics.NewRegistry(),
+		requests: metrics.NewCounterVec("wine_http_requests_total", "Total number of http requests.",
+			"method", "route", "status"),
+		duration: metrics.NewHistogramVec("wine_http_request_duration_seconds", "Http request latencies in seconds.",
+			metrics.DefBuckets, "method", "route", "status"),
+		size: metrics.NewHistogramVec("wine_http_response_size_bytes", "Http response sizes in bytes.",
+			metrics.SizeBuckets, "method", "route", "status"),
+	}
+	inFlight := metrics.NewGaugeFunc("wine_http_requests_in_flight", "Number of http requests being handled.", func() float64 {
+		return float64(s.InFlight())
+	})
+	m.registry.MustRegister(m.requests, m.duration, m.size, inFlight)
+	return m
+}
+
+// Metrics returns the registry exported at _wine/metrics. Custom metrics can be registered into it
+func (s *Server) Metrics() *metrics.Registry {
+	return s.metrics.registry
+}
+
+func (s *Server) observe(req *Request, res *Result, cost time.Duration) {
+	// Route pattern is used instead of path to limit cardinality of labels
+	var route string
+	if req.endpoint != nil {
+		route = "/" + req.endpoint.Path()
+	}
+	method := req.request.Method
+	status := strconv.Itoa(res.Status)
+	s.metrics.requests.With(method, route, status).Inc()
+	s.metrics.duration.With(method, route, status).Observe(cost.Seconds())
+	s.metrics.size.With(method, route, status).Observe(float64(res.BytesOut))
+}
+
+func (s *Server) handleMetrics(_ context.Context, _ *Request) Responder {
+	return respond.Func(func(ctx context.Context, w http.ResponseWriter) {
+		w.Header().Set(httpvalue.ContentType, metrics.ContentType)
+		w.WriteHeader(http.StatusOK)
+		if _, err := s.metrics.registry.WriteTo(w); err != nil {
+			logger.Errorf("Write metrics: %v", err)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"io"
+)
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	v atomicFloat
+}
+
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+// Add adds v which must not be negative
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("counter cannot decrease")
+	}
+	c.v.Add(v)
+}
+
+func (c *Counter) Value() float64 {
+	return c.v.Load()
+}
+
+// CounterVec is a family of counters partitioned by labels
+type CounterVec struct {
+	*family
+}
+
+var _ Collector = (*CounterVec)(nil)
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{
+		family: newFamily(name, help, labels, func() interface{} {
+			return new(Counter)
+		}),
+	}
+}
+
+// With returns the counter of label values, which are in the same order as labels
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.get(values).(*Counter)
+}
+
+func (v *CounterVec) Write(w io.Writer) error {
+	if err := writeHeader(w, v.name, v.help, "counter"); err != nil {
+		return err
+	}
+	for _, c := range v.sortedChildren() {
+		if err := writeSample(w, v.name, v.labels, c.values, c.metric.(*Counter).Value()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"io"
+)
+
+// Gauge is a value which can go up and down
+type Gauge struct {
+	v atomicFloat
+}
+
+func (g *Gauge) Set(v float64) {
+	g.v.Set(v)
+}
+
+func (g *Gauge) Add(v float64) {
+	g.v.Add(v)
+}
+
+func (g *Gauge) Inc() {
+	g.v.Add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.v.Add(-1)
+}
+
+func (g *Gauge) Value() float64 {
+	return g.v.Load()
+}
+
+// GaugeVec is a family of gauges partitioned by labels
+type GaugeVec struct {
+	*family
+}
+
+var _ Collector = (*GaugeVec)(nil)
+
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{
+		family: newFamily(name, help, labels, func() interface{} {
+			return new(Gauge)
+		}),
+	}
+}
+
+// With returns the gauge of label values, which are in the same order as labels
+func (v *GaugeVec) With(values ...string) *Gauge {
+	return v.get(values).(*Gauge)
+}
+
+func (v *GaugeVec) Write(w io.Writer) error {
+	if err := writeHeader(w, v.name, v.help, "gauge"); err != nil {
+		return err
+	}
+	for _, c := range v.sortedChildren() {
+		if err := writeSample(w, v.name, v.labels, c.values, c.metric.(*Gauge).Value()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GaugeFunc is a gauge whose value is got by calling a function at collecting time, e.g. number of connections
+type GaugeFunc struct {
+	name string
+	help string
+	f    func() float64
+}
+
+var _ Collector = (*GaugeFunc)(nil)
+
+func NewGaugeFunc(name, help string, f func() float64) *GaugeFunc {
+	return &GaugeFunc{
+		name: name,
+		help: help,
+		f:    f,
+	}
+}
+
+func (g *GaugeFunc) Name() string {
+	return g.name
+}
+
+func (g *GaugeFunc) Write(w io.Writer) error {
+	if err := writeHeader(w, g.name, g.help, "gauge"); err != nil {
+		return err
+	}
+	return writeSample(w, g.name, nil, nil, g.f())
+}
@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"io"
+	"math"
+	"sort"
+	"sync/atomic"
+)
+
+var (
+	// DefBuckets are buckets for latencies in seconds, from 5ms to 10s
+	DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	// SizeBuckets are buckets for sizes in bytes, from 100B to 100MB
+	SizeBuckets = ExponentialBuckets(100, 10, 7)
+)
+
+// ExponentialBuckets returns count buckets, the first one is start, the others are multiplied by factor in turn
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	l := make([]float64, count)
+	for i := range l {
+		l[i] = start
+		start *= factor
+	}
+	return l
+}
+
+// Histogram counts observations into buckets
+type Histogram struct {
+	upperBounds []float64
+	counts      []uint64
+	count       uint64
+	sum         atomicFloat
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		upperBounds: buckets,
+		counts:      make([]uint64, len(buckets)),
+	}
+}
+
+func (h *Histogram) Observe(v float64) {
+	// Buckets are cumulative when written, only the first matched one is counted here
+	if i := sort.SearchFloat64s(h.upperBounds, v); i < len(h.upperBounds) {
+		atomic.AddUint64(&h.counts[i], 1)
+	}
+	atomic.AddUint64(&h.count, 1)
+	h.sum.Add(v)
+}
+
+// Count returns number of observations
+func (h *Histogram) Count() uint64 {
+	return atomic.LoadUint64(&h.count)
+}
+
+// Sum returns sum of observations
+func (h *Histogram) Sum() float64 {
+	return h.sum.Load()
+}
+
+// HistogramVec is a family of histograms partitioned by labels
+type HistogramVec struct {
+	*family
+	buckets []float64
+}
+
+var _ Collector = (*HistogramVec)(nil)
+
+// NewHistogramVec creates histograms with buckets, which are upper bounds in increasing order. DefBuckets is used if it's empty
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	if math.IsInf(buckets[len(buckets)-1], 1) {
+		buckets = buckets[:len(buckets)-1]
+	}
+	return &HistogramVec{
+		family: newFamily(name, help, labels, func() interface{} {
+			return newHistogram(buckets)
+		}),
+		buckets: buckets,
+	}
+}
+
+// With returns the histogram of label values, which are in the same order as labels
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.get(values).(*Histogram)
+}
+
+func (v *HistogramVec) Write(w io.Writer) error {
+	if err := writeHeader(w, v.name, v.help, "histogram"); err != nil {
+		return err
+	}
+	labels := append(append([]string(nil), v.labels...), "le")
+	for _, c := range v.sortedChildren() {
+		h := c.metric.(*Histogram)
+		values := append(append([]string(nil), c.values...), "")
+		var cumulative uint64
+		for i, ub := range h.upperBounds {
+			cumulative += atomic.LoadUint64(&h.counts[i])
+			values[len(values)-1] = formatFloat(ub)
+			if err := writeSample(w, v.name+"_bucket", labels, values, float64(cumulative)); err != nil {
+				return err
+			}
+		}
+		count := h.Count()
+		if count < cumulative {
+			// Observe is in progress
+			count = cumulative
+		}
+		values[len(values)-1] = "+Inf"
+		if err := writeSample(w, v.name+"_bucket", labels, values, float64(count)); err != nil {
+			return err
+		}
+		if err := writeSample(w, v.name+"_sum", v.labels, c.values, h.Sum()); err != nil {
+			return err
+		}
+		if err := writeSample(w, v.name+"_count", v.labels, c.values, float64(count)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+// Package metrics implements counters, gauges and histograms which are exported in Prometheus text format
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ContentType is the content type of Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Collector writes a metric family in Prometheus text format
+type Collector interface {
+	Name() string
+	Write(w io.Writer) error
+}
+
+// Registry is a set of collectors
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: make(map[string]Collector),
+	}
+}
+
+// Register registers c. It fails if another collector with the same name has been registered
+func (r *Registry) Register(c Collector) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.collectors[c.Name()]; ok {
+		return fmt.Errorf("duplicate metric %s", c.Name())
+	}
+	r.collectors[c.Name()] = c
+	return nil
+}
+
+// MustRegister registers collectors, panics if any fails
+func (r *Registry) MustRegister(cs ...Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Unregister removes collector with name
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.collectors, name)
+	r.mu.Unlock()
+}
+
+// WriteTo writes all metrics sorted by name in Prometheus text format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	l := make([]Collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		l = append(l, c)
+	}
+	r.mu.RUnlock()
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].Name() < l[j].Name()
+	})
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range l {
+		if err := c.Write(bw); err != nil {
+			return cw.n, fmt.Errorf("write %s: %w", c.Name(), err)
+		}
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+func writeHeader(w io.Writer, name, help, typ string) error {
+	if help != "" {
+		help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n", name, help); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+	return err
+}
+
+func writeSample(w io.Writer, name string, labels, values []string, v float64) error {
+	_, err := fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(labels, values), formatFloat(v))
+	return err
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(labels, values []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(l)
+		b.WriteString(`="`)
+		b.WriteString(labelValueReplacer.Replace(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// atomicFloat is a float64 which can be updated atomically
+type atomicFloat struct {
+	bits uint64
+}
+
+func (f *atomicFloat) Add(v float64) {
+	for {
+		old := atomic.LoadUint64(&f.bits)
+		n := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&f.bits, old, n) {
+			return
+		}
+	}
+}
+
+func (f *atomicFloat) Set(v float64) {
+	atomic.StoreUint64(&f.bits, math.Float64bits(v))
+}
+
+func (f *atomicFloat) Load() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&f.bits))
+}
+
+// family holds children of a metric vector, keyed by label values
+type family struct {
+	name     string
+	help     string
+	labels   []string
+	newChild func() interface{}
+
+	mu       sync.RWMutex
+	children map[string]*familyChild
+}
+
+type familyChild struct {
+	values []string
+	metric interface{}
+}
+
+func newFamily(name, help string, labels []string, newChild func() interface{}) *family {
+	return &family{
+		name:     name,
+		help:     help,
+		labels:   labels,
+		newChild: newChild,
+		children: make(map[string]*familyChild),
+	}
+}
+
+func (f *family) Name() string {
+	return f.name
+}
+
+func (f *family) get(values []string) interface{} {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	f.mu.RLock()
+	c := f.children[key]
+	f.mu.RUnlock()
+	if c != nil {
+		return c.metric
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c = f.children[key]; c == nil {
+		c = &familyChild{
+			values: append([]string(nil), values...),
+			metric: f.newChild(),
+		}
+		f.children[key] = c
+	}
+	return c.metric
+}
+
+// sortedChildren returns children sorted by label values, so that output is stable
+func (f *family) sortedChildren() []*familyChild {
+	f.mu.RLock()
+	l := make([]*familyChild, 0, len(f.children))
+	for _, c := range f.children {
+		l = append(l, c)
+	}
+	f.mu.RUnlock()
+	sort.Slice(l, func(i, j int) bool {
+		a, b := l[i].values, l[j].values
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+	return l
+}
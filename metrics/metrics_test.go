@@ -0,0 +1,56 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gopub/wine/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	r := metrics.NewRegistry()
+	requests := metrics.NewCounterVec("requests_total", "Total requests.", "method", "path")
+	duration := metrics.NewHistogramVec("duration_seconds", "", []float64{0.1, 1}, "method")
+	conns := metrics.NewGaugeFunc("connections", "Active\nconnections.", func() float64 {
+		return 3
+	})
+	r.MustRegister(requests, duration, conns)
+	require.Error(t, r.Register(metrics.NewGaugeVec("connections", "")))
+
+	requests.With("GET", `/a"b`).Inc()
+	requests.With("GET", `/a"b`).Add(2)
+	requests.With("DELETE", "/").Inc()
+	duration.With("GET").Observe(0.05)
+	duration.With("GET").Observe(0.5)
+	duration.With("GET").Observe(5)
+
+	var b strings.Builder
+	_, err := r.WriteTo(&b)
+	require.NoError(t, err)
+	expected := `# HELP connections Active\nconnections.
+# TYPE connections gauge
+connections 3
+# TYPE duration_seconds histogram
+duration_seconds_bucket{method="GET",le="0.1"} 1
+duration_seconds_bucket{method="GET",le="1"} 2
+duration_seconds_bucket{method="GET",le="+Inf"} 3
+duration_seconds_sum{method="GET"} 5.55
+duration_seconds_count{method="GET"} 3
+# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{method="DELETE",path="/"} 1
+requests_total{method="GET",path="/a\"b"} 3
+`
+	require.Equal(t, expected, b.String())
+}
+
+func TestCounter_Add(t *testing.T) {
+	c := metrics.NewCounterVec("c", "").With()
+	require.Panics(t, func() {
+		c.Add(-1)
+	})
+	require.Panics(t, func() {
+		metrics.NewCounterVec("c", "", "label").With()
+	})
+}
@@ -0,0 +1,76 @@
+package wine
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gopub/wine/codec"
+	"github.com/gopub/wine/ctxutil"
+	"github.com/gopub/wine/httpvalue"
+)
+
+// Negotiation is a responder which encodes value in the content type preferred most by the request's Accept header
+type Negotiation struct {
+	status   int
+	value    interface{}
+	template string
+	registry *codec.Registry
+}
+
+var _ Responder = (*Negotiation)(nil)
+
+// Negotiate creates a responder which selects content type by Accept header with q-values among codecs which can marshal value,
+// e.g. JSON, protobuf for proto.Message, XML, MessagePack and CSV. JSON is selected if Accept is missing or */*.
+// 406 is responded if none is acceptable
+func Negotiate(status int, value interface{}) *Negotiation {
+	return &Negotiation{
+		status:   status,
+		value:    value,
+		registry: codec.Default,
+	}
+}
+
+// Template offers text/html rendered by template name with value
+func (n *Negotiation) Template(name string) *Negotiation {
+	n.template = name
+	return n
+}
+
+// WithRegistry negotiates among codecs in r instead of codec.Default
+func (n *Negotiation) WithRegistry(r *codec.Registry) *Negotiation {
+	n.registry = r
+	return n
+}
+
+func (n *Negotiation) Respond(ctx context.Context, w http.ResponseWriter) {
+	offers := n.registry.Offers(n.value)
+	if n.template != "" {
+		offers = append(offers, httpvalue.HTML)
+	}
+	w.Header().Add(httpvalue.Vary, httpvalue.Accept)
+	ct := httpvalue.NegotiateContentType(ctxutil.GetRequestHeader(ctx), offers...)
+	switch {
+	case ct == "":
+		Text(http.StatusNotAcceptable, "Acceptable types: %s", strings.Join(offers, ", ")).Respond(ctx, w)
+	case ct == httpvalue.HTML && n.template != "":
+		w.Header().Set(httpvalue.ContentType, httpvalue.HtmlUTF8)
+		w.WriteHeader(n.status)
+		ctxutil.GetTemplateManager(ctx).ExecuteWithFuncs(w, n.template, n.value, ctxutil.GetTemplateFuncs(ctx))
+	default:
+		b, err := n.registry.Get(ct).Marshal(n.value)
+		if err != nil {
+			logger.Errorf("Cannot marshal %T into %s: %v", n.value, ct, err)
+			Error(err).Respond(ctx, w)
+			return
+		}
+		if httpvalue.IsMIMETextType(ct) || strings.HasPrefix(ct, "text/") {
+			ct += "; " + httpvalue.CharsetUTF8
+		}
+		w.Header().Set(httpvalue.ContentType, ct)
+		w.WriteHeader(n.status)
+		if _, err = w.Write(b); err != nil {
+			logger.Errorf("Cannot write: %v", err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package wine_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/httpvalue"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestNegotiate(t *testing.T) {
+	type item struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	s := wine.NewTestServer(t)
+	s.AddTextTemplate("item", `<p>{{.Name}}</p>`)
+	s.Get("item", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.Negotiate(http.StatusOK, &item{ID: 1, Name: "wine"}).Template("item")
+	})
+	s.Get("message", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.Negotiate(http.StatusCreated, wrapperspb.String("wine"))
+	})
+	u := s.Run()
+
+	get := func(path, accept string) (int, string, string) {
+		req, err := http.NewRequest(http.MethodGet, u+path, nil)
+		require.NoError(t, err)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, resp.Header.Get(httpvalue.ContentType), string(b)
+	}
+
+	status, ct, body := get("/item", "")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, httpvalue.JsonUTF8, ct)
+	require.JSONEq(t, `{"id":1,"name":"wine"}`, body)
+
+	_, ct, body = get("/item", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	require.Equal(t, httpvalue.HtmlUTF8, ct)
+	require.Equal(t, "<p>wine</p>", body)
+
+	_, ct, body = get("/item", "application/json;q=0.5, application/xml")
+	require.Equal(t, httpvalue.XmlUTF8, ct)
+	require.Equal(t, "<item><ID>1</ID><Name>wine</Name></item>", body)
+
+	_, ct, _ = get("/item", "application/*;q=0.5, application/msgpack;q=0.8")
+	require.Equal(t, httpvalue.MsgPack, ct)
+
+	status, _, _ = get("/item", "application/x-protobuf, image/*")
+	require.Equal(t, http.StatusNotAcceptable, status)
+
+	status, ct, _ = get("/message", "application/x-protobuf, */*;q=0.1")
+	require.Equal(t, http.StatusCreated, status)
+	require.Equal(t, httpvalue.Protobuf, ct)
+
+	_, ct, _ = get("/item", "application/json;q=0, */*")
+	require.NotEqual(t, httpvalue.JsonUTF8, ct)
+}
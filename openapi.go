@@ -0,0 +1,286 @@
+package wine
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gopub/wine/httpvalue"
+	"github.com/gopub/wine/openapi"
+	"github.com/gopub/wine/router"
+)
+
+// Methods which an endpoint bound with wildcard method is documented with
+var anyMethods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+var operationIDRegexp = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// OpenAPI generates OpenAPI 3 document from all registered endpoints except system endpoints
+func (s *Server) OpenAPI() *openapi.Document {
+	doc := openapi.NewDocument(s.APIInfo.Title, s.APIInfo.Version)
+	doc.Info.Description = s.APIInfo.Description
+	if u := s.URL(); u != "" {
+		doc.Servers = []*openapi.Server{{URL: u}}
+	}
+
+	// Endpoints bound with specific method take precedence over wildcard method
+	var anyEndpoints []*router.Endpoint
+	for _, e := range s.ListRoutes() {
+		if reservedPaths[e.Path()] {
+			continue
+		}
+		if e.Scope == "" {
+			anyEndpoints = append(anyEndpoints, e)
+			continue
+		}
+		addOperation(doc, e.Scope, e)
+	}
+	for _, e := range anyEndpoints {
+		item := doc.Paths[toOpenAPIPath(e.Path())]
+		for _, method := range anyMethods {
+			if item != nil && getOperation(item, method) != nil {
+				continue
+			}
+			addOperation(doc, method, e)
+		}
+	}
+	return doc
+}
+
+func (s *Server) handleOpenAPI(_ context.Context, req *Request) Responder {
+	doc := s.OpenAPI()
+	if len(doc.Servers) == 0 && req.request.Host != "" {
+		scheme := "http"
+		if req.request.TLS != nil {
+			scheme = "https"
+		}
+		doc.Servers = []*openapi.Server{{URL: scheme + "://" + req.request.Host}}
+	}
+	return JSON(http.StatusOK, doc)
+}
+
+func addOperation(doc *openapi.Document, method string, e *router.Endpoint) {
+	p := toOpenAPIPath(e.Path())
+	item := doc.Paths[p]
+	if item == nil {
+		item = new(openapi.PathItem)
+	}
+	if !item.SetOperation(method, newOperation(doc, method, e)) {
+		return
+	}
+	doc.Paths[p] = item
+}
+
+func getOperation(item *openapi.PathItem, method string) *openapi.Operation {
+	switch method {
+	case http.MethodGet:
+		return item.Get
+	case http.MethodPost:
+		return item.Post
+	case http.MethodPut:
+		return item.Put
+	case http.MethodPatch:
+		return item.Patch
+	case http.MethodDelete:
+		return item.Delete
+	default:
+		return nil
+	}
+}
+
+func newOperation(doc *openapi.Document, method string, e *router.Endpoint) *openapi.Operation {
+	op := &openapi.Operation{
+		OperationID: strings.ToLower(method) + "_" + strings.Trim(operationIDRegexp.ReplaceAllString(e.Path(), "_"), "_"),
+		Summary:     e.Description(),
+		Responses:   newResponses(doc, e),
+	}
+	segments := strings.Split(e.Path(), "/")
+	if len(segments) > 0 && router.IsStatic(segments[0]) && segments[0] != "" {
+		op.Tags = []string{segments[0]}
+	}
+
+	var pathParams []*openapi.Parameter
+	for _, s := range segments {
+		var p *openapi.Parameter
+		switch {
+		case router.IsParam(s):
+			p = &openapi.Parameter{
+				Name: s[1 : len(s)-1],
+			}
+		case strings.HasPrefix(s, "*"):
+			p = &openapi.Parameter{
+				Name:        wildcardParamName(s),
+				Description: "Matches the rest of the path",
+			}
+		default:
+			continue
+		}
+		p.In = openapi.InPath
+		p.Required = true
+		p.Schema = &openapi.Schema{Type: "string"}
+		pathParams = append(pathParams, p)
+	}
+	op.Parameters = pathParams
+
+	m := e.Model()
+	if m == nil {
+		return op
+	}
+
+	if _, ok := m.(proto.Message); ok {
+		op.RequestBody = &openapi.RequestBody{
+			Required: true,
+			Content: map[string]*openapi.MediaType{
+				httpvalue.Protobuf: {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+			},
+		}
+		return op
+	}
+
+	t := reflect.TypeOf(m)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	hasBody := method != http.MethodGet && method != http.MethodHead && method != http.MethodDelete
+	if t.Kind() != reflect.Struct {
+		switch {
+		case hasBody:
+			op.RequestBody = newRequestBody(doc.Schema(t))
+		case len(pathParams) == 1:
+			// Model is assigned with the single path param
+			pathParams[0].Schema = doc.Schema(t)
+		}
+		return op
+	}
+
+	fields := make(map[string]reflect.StructField)
+	collectFields(t, fields)
+	for _, p := range pathParams {
+		if f, ok := fields[p.Name]; ok {
+			p.Schema = doc.Schema(f.Type)
+			delete(fields, p.Name)
+		}
+	}
+
+	if hasBody {
+		op.RequestBody = newRequestBody(doc.Schema(t))
+		return op
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := fields[name]
+		op.Parameters = append(op.Parameters, &openapi.Parameter{
+			Name:        name,
+			In:          openapi.InQuery,
+			Description: f.Tag.Get("description"),
+			Schema:      doc.Schema(f.Type),
+		})
+	}
+	return op
+}
+
+func newResponses(doc *openapi.Document, e *router.Endpoint) map[string]*openapi.Response {
+	md, _ := e.Metadata().(*metadata)
+	if md == nil || len(md.Responses) == 0 {
+		return map[string]*openapi.Response{
+			"default": {Description: "Default response"},
+		}
+	}
+
+	m := make(map[string]*openapi.Response, len(md.Responses))
+	for _, spec := range md.Responses {
+		r := &openapi.Response{
+			Description: spec.Description,
+		}
+		if r.Description == "" {
+			r.Description = http.StatusText(spec.Status)
+		}
+		switch v := spec.Model.(type) {
+		case nil:
+			break
+		case proto.Message:
+			r.Content = map[string]*openapi.MediaType{
+				httpvalue.Protobuf: {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+			}
+		case string:
+			r.Content = map[string]*openapi.MediaType{
+				httpvalue.Plain: {Schema: &openapi.Schema{Type: "string"}},
+			}
+		default:
+			r.Content = map[string]*openapi.MediaType{
+				httpvalue.JSON: {Schema: doc.Schema(v)},
+			}
+		}
+		m[strconv.Itoa(spec.Status)] = r
+	}
+	return m
+}
+
+func newRequestBody(schema *openapi.Schema) *openapi.RequestBody {
+	return &openapi.RequestBody{
+		Required: true,
+		Content: map[string]*openapi.MediaType{
+			httpvalue.JSON:           {Schema: schema},
+			httpvalue.FormURLEncoded: {Schema: schema},
+		},
+	}
+}
+
+// collectFields collects json fields including ones promoted from embedded structs
+func collectFields(t reflect.Type, fields map[string]reflect.StructField) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := openapi.FieldName(f)
+		if !ok {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				collectFields(ft, fields)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f
+	}
+}
+
+func wildcardParamName(segment string) string {
+	if name := strings.TrimPrefix(segment, "*"); name != "" {
+		return name
+	}
+	return "wildcard"
+}
+
+// toOpenAPIPath converts router path into OpenAPI path template, e.g. items/{id} to /items/{id}, files/* to /files/{wildcard}
+func toOpenAPIPath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, "*") {
+			segments[i] = "{" + wildcardParamName(s) + "}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
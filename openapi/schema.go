@@ -0,0 +1,186 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+}
+
+const componentSchemaPrefix = "#/components/schemas/"
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	bytesType         = reflect.TypeOf([]byte(nil))
+	rawMessageType    = reflect.TypeOf(json.RawMessage(nil))
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+	invalidNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9._\-]`)
+)
+
+// Schema returns the schema of v's type.
+// Named struct types are registered in d.Components and referenced by $ref
+func (d *Document) Schema(v interface{}) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+	if t, ok := v.(reflect.Type); ok {
+		return d.schemaOf(t)
+	}
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+// Lookup returns the schema referenced by s, or s itself if it isn't a reference
+func (d *Document) Lookup(s *Schema) *Schema {
+	if s == nil || s.Ref == "" || d.Components == nil {
+		return s
+	}
+	return d.Components.Schemas[strings.TrimPrefix(s.Ref, componentSchemaPrefix)]
+}
+
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+	case bytesType:
+		return &Schema{Type: "string", Format: "byte"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+			// Unable to know the result of custom marshaling
+			return &Schema{}
+		}
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		return d.componentRef(t)
+	default:
+		// interface, func, chan etc.
+		return &Schema{}
+	}
+}
+
+func (d *Document) componentRef(t reflect.Type) *Schema {
+	if d.types == nil {
+		d.types = make(map[string]string)
+	}
+	if d.Components == nil {
+		d.Components = &Components{}
+	}
+	if d.Components.Schemas == nil {
+		d.Components.Schemas = make(map[string]*Schema)
+	}
+
+	key := t.PkgPath() + "." + t.Name()
+	if name, ok := d.types[key]; ok {
+		return &Schema{Ref: componentSchemaPrefix + name}
+	}
+
+	name := invalidNameRegexp.ReplaceAllString(t.Name(), "_")
+	if _, exists := d.Components.Schemas[name]; exists {
+		name = invalidNameRegexp.ReplaceAllString(path.Base(t.PkgPath()), "_") + "." + name
+	}
+	d.types[key] = name
+	// Register placeholder ahead in case of recursive types
+	d.Components.Schemas[name] = &Schema{}
+	*d.Components.Schemas[name] = *d.structSchema(t)
+	return &Schema{Ref: componentSchemaPrefix + name}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+	d.addFields(s, t)
+	return s
+}
+
+func (d *Document) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := FieldName(f)
+		if !ok {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				d.addFields(s, ft)
+				continue
+			}
+			name = ft.Name()
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		fs := d.schemaOf(f.Type)
+		if f.Type.Kind() == reflect.Ptr && fs.Ref == "" {
+			fs.Nullable = true
+		}
+		if desc := f.Tag.Get("description"); desc != "" && fs.Ref == "" {
+			// Siblings of $ref are ignored in OpenAPI 3.0
+			fs.Description = desc
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// FieldName returns json name of struct field f. ok is false if f is not exported or ignored by json
+func FieldName(f reflect.StructField) (name string, ok bool) {
+	if f.PkgPath != "" && !f.Anonymous {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	return strings.Split(tag, ",")[0], true
+}
@@ -0,0 +1,57 @@
+package openapi_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gopub/wine/openapi"
+	"github.com/stretchr/testify/require"
+)
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+type Item struct {
+	ID        int64             `json:"id"`
+	Title     string            `json:"title,omitempty" description:"item title"`
+	Price     *float64          `json:"price"`
+	Tags      []*Tag            `json:"tags"`
+	Attrs     map[string]string `json:"attrs"`
+	CreatedAt time.Time         `json:"created_at"`
+	Parent    *Item             `json:"parent"`
+	Ignored   string            `json:"-"`
+	internal  string
+}
+
+func TestDocument_Schema(t *testing.T) {
+	doc := openapi.NewDocument("test", "1.0")
+	s := doc.Schema(&Item{})
+	require.Equal(t, "#/components/schemas/Item", s.Ref)
+
+	item := doc.Lookup(s)
+	require.NotNil(t, item)
+	require.Equal(t, "object", item.Type)
+	require.Len(t, item.Properties, 7)
+	require.Equal(t, "integer", item.Properties["id"].Type)
+	require.Equal(t, "int64", item.Properties["id"].Format)
+	require.Equal(t, "item title", item.Properties["title"].Description)
+	require.True(t, item.Properties["price"].Nullable)
+	require.Equal(t, "array", item.Properties["tags"].Type)
+	require.Equal(t, "#/components/schemas/Tag", item.Properties["tags"].Items.Ref)
+	require.Equal(t, "string", item.Properties["attrs"].AdditionalProperties.Type)
+	require.Equal(t, "date-time", item.Properties["created_at"].Format)
+	require.Equal(t, s.Ref, item.Properties["parent"].Ref)
+	require.Contains(t, doc.Components.Schemas, "Tag")
+}
+
+func TestDocument_SchemaAnonymous(t *testing.T) {
+	doc := openapi.NewDocument("test", "1.0")
+	s := doc.Schema(struct {
+		Tag
+		Count int `json:"count"`
+	}{})
+	require.Empty(t, s.Ref)
+	require.Equal(t, "string", s.Properties["name"].Type)
+	require.Equal(t, "integer", s.Properties["count"].Type)
+}
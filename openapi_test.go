@@ -0,0 +1,109 @@
+package wine_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/openapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_OpenAPI(t *testing.T) {
+	type Item struct {
+		ID    int64   `json:"id"`
+		Title string  `json:"title"`
+		Price float32 `json:"price"`
+	}
+	handler := func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.OK
+	}
+	s := wine.NewServer(nil)
+	s.Get("items/{id}", handler).SetModel(int64(0)).SetDescription("Get item").
+		SetResponse(http.StatusOK, &Item{}).
+		SetResponse(http.StatusNotFound, nil)
+	s.Get("items", handler).SetModel(&Item{})
+	s.Post("items", handler).SetModel(&Item{})
+	s.Put("items/{id}", handler).SetModel(&Item{})
+	s.StaticDir("files", ".")
+	doc := s.OpenAPI()
+	require.Equal(t, openapi.Version, doc.OpenAPI)
+	require.NotContains(t, doc.Paths, "/_wine/openapi.json")
+
+	t.Run("PathParam", func(t *testing.T) {
+		op := doc.Paths["/items/{id}"].Get
+		require.NotNil(t, op)
+		require.Equal(t, "Get item", op.Summary)
+		require.Len(t, op.Parameters, 1)
+		require.Equal(t, openapi.InPath, op.Parameters[0].In)
+		require.Equal(t, "integer", op.Parameters[0].Schema.Type)
+	})
+
+	t.Run("QueryParams", func(t *testing.T) {
+		op := doc.Paths["/items"].Get
+		require.NotNil(t, op)
+		require.Nil(t, op.RequestBody)
+		require.Len(t, op.Parameters, 3)
+		for _, p := range op.Parameters {
+			require.Equal(t, openapi.InQuery, p.In)
+		}
+	})
+
+	t.Run("RequestBody", func(t *testing.T) {
+		op := doc.Paths["/items"].Post
+		require.NotNil(t, op)
+		require.NotNil(t, op.RequestBody)
+		require.Equal(t, "#/components/schemas/Item", op.RequestBody.Content["application/json"].Schema.Ref)
+
+		op = doc.Paths["/items/{id}"].Put
+		require.NotNil(t, op)
+		require.Len(t, op.Parameters, 1)
+		require.Equal(t, "integer", op.Parameters[0].Schema.Type)
+		require.NotNil(t, op.RequestBody)
+	})
+
+	t.Run("Wildcard", func(t *testing.T) {
+		item := doc.Paths["/files/{wildcard}"]
+		require.NotNil(t, item)
+		require.NotNil(t, item.Get)
+		require.Equal(t, "wildcard", item.Get.Parameters[0].Name)
+	})
+
+	t.Run("Responses", func(t *testing.T) {
+		op := doc.Paths["/items/{id}"].Get
+		require.Len(t, op.Responses, 2)
+		require.Equal(t, "#/components/schemas/Item", op.Responses["200"].Content["application/json"].Schema.Ref)
+		require.Equal(t, "Not Found", op.Responses["404"].Description)
+		require.Empty(t, op.Responses["404"].Content)
+
+		op = doc.Paths["/items"].Post
+		require.Contains(t, op.Responses, "default")
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		_, err := json.Marshal(doc)
+		require.NoError(t, err)
+	})
+}
+
+func TestEndpoint_SetResponse(t *testing.T) {
+	type Item struct {
+		ID int64 `json:"id"`
+	}
+	s := wine.NewServer(nil)
+	e := s.Get("items/{id}", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.JSON(http.StatusOK, &Item{})
+	}).SetResponse(http.StatusNotFound, nil).SetResponse(http.StatusOK, &Item{})
+	l := e.Responses()
+	require.Len(t, l, 2)
+	require.Equal(t, http.StatusOK, l[0].Status)
+	require.Equal(t, http.StatusNotFound, l[1].Status)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_wine/endpoints", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "=> 200:*wine_test.Item, 404")
+}
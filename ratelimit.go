@@ -0,0 +1,126 @@
+package wine
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopub/wine/ctxutil"
+	"github.com/gopub/wine/httpvalue"
+	"github.com/gopub/wine/internal/respond"
+	"github.com/gopub/wine/ratelimit"
+)
+
+// RateLimitKeyFunc returns key which requests are limited by. Requests with empty key aren't limited
+type RateLimitKeyFunc func(ctx context.Context, req *Request) string
+
+// RateLimitByIP limits requests by ip of remote address
+func RateLimitByIP(_ context.Context, req *Request) string {
+	return "ip:" + remoteIP(req.request.RemoteAddr)
+}
+
+// RateLimitByForwardedIP limits requests by client ip in X-Forwarded-For or X-Real-Ip.
+// Only use it behind a trusted proxy, as headers can be forged by clients
+func RateLimitByForwardedIP(_ context.Context, req *Request) string {
+	r := req.request
+	if s := r.Header.Get(httpvalue.XForwardedFor); s != "" {
+		return "ip:" + strings.TrimSpace(strings.Split(s, ",")[0])
+	}
+	if s := r.Header.Get(httpvalue.XRealIP); s != "" {
+		return "ip:" + s
+	}
+	return "ip:" + remoteIP(r.RemoteAddr)
+}
+
+// RateLimitByUserID limits requests by id of authenticated user
+func RateLimitByUserID(ctx context.Context, _ *Request) string {
+	if id := ctxutil.GetUserID(ctx); id > 0 {
+		return "user:" + strconv.FormatInt(id, 10)
+	}
+	return ""
+}
+
+// RateLimitByAppID limits requests by X-Wine-App-Id
+func RateLimitByAppID(_ context.Context, req *Request) string {
+	if id := req.Header(httpvalue.CustomAppID); id != "" {
+		return "app:" + id
+	}
+	return ""
+}
+
+// RateLimitByDeviceID limits requests by X-Wine-Device-Id
+func RateLimitByDeviceID(_ context.Context, req *Request) string {
+	if id := req.Header(httpvalue.CustomDeviceID); id != "" {
+		return "device:" + id
+	}
+	return ""
+}
+
+// NewRateLimitHandler returns an interceptor which rejects requests exceeding limits with 429.
+// Endpoints are limited separately if limiter has limits of their routes, e.g. "POST /login".
+// Requests are allowed if limiter fails, e.g. shared store is unavailable
+func NewRateLimitHandler(l *ratelimit.Limiter, key RateLimitKeyFunc) HandlerFunc {
+	if l == nil || key == nil {
+		logger.Panic("Limiter and key func cannot be nil")
+	}
+	return func(ctx context.Context, req *Request) Responder {
+		k := key(ctx, req)
+		if k == "" {
+			return Next(ctx, req)
+		}
+		var name string
+		if e := req.endpoint; e != nil {
+			name = req.request.Method + " /" + e.Path()
+		}
+		res, err := l.Allow(ctx, name, k)
+		if err != nil {
+			logger.Errorf("Rate limit %s: %v", k, err)
+			return Next(ctx, req)
+		}
+		header := make(http.Header)
+		header.Set(httpvalue.RateLimitLimit, strconv.Itoa(res.Limit))
+		header.Set(httpvalue.RateLimitRemaining, strconv.Itoa(res.Remaining))
+		header.Set(httpvalue.RateLimitReset, ceilSeconds(res.ResetAfter))
+		if !res.Allowed {
+			header.Set(httpvalue.RetryAfter, ceilSeconds(res.RetryAfter))
+			return withHeader(Text(http.StatusTooManyRequests, "Too many requests"), header)
+		}
+		return withHeader(Next(ctx, req), header)
+	}
+}
+
+// withHeader adds header to the response of r
+func withHeader(r Responder, header http.Header) Responder {
+	if r == nil {
+		return nil
+	}
+	if resp, ok := r.(*respond.Response); ok {
+		// r may be shared, e.g. OK
+		resp = resp.Clone()
+		for k, v := range header {
+			resp.Header()[k] = v
+		}
+		return resp
+	}
+	return respond.Func(func(ctx context.Context, w http.ResponseWriter) {
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		r.Respond(ctx, w)
+	})
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+func remoteIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const memorySweepInterval = time.Minute
+
+type memoryEntry struct {
+	// token bucket
+	tokens float64
+	// sliding window
+	windowStart time.Time
+	prev, curr  int
+
+	updatedAt time.Time
+	period    time.Duration
+}
+
+// MemoryStore keeps states in memory, which only works for a single instance
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	sweptAt time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]*memoryEntry),
+		sweptAt: time.Now(),
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (*Result, error) {
+	if err := limit.Validate(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.sweep(now)
+	e := s.entries[key]
+	if e == nil || e.period != limit.Period {
+		e = &memoryEntry{
+			tokens:      float64(limit.Capacity()),
+			windowStart: now,
+			updatedAt:   now,
+			period:      limit.Period,
+		}
+		s.entries[key] = e
+	}
+	var res *Result
+	if limit.Algorithm == SlidingWindow {
+		res = e.slide(limit, now)
+	} else {
+		res = e.refill(limit, now)
+	}
+	e.updatedAt = now
+	return res, nil
+}
+
+func (e *memoryEntry) refill(limit Limit, now time.Time) *Result {
+	capacity := float64(limit.Capacity())
+	perToken := limit.Period / time.Duration(limit.Rate)
+	e.tokens = math.Min(capacity, e.tokens+float64(now.Sub(e.updatedAt))/float64(perToken))
+	res := &Result{
+		Limit: limit.Capacity(),
+	}
+	if e.tokens >= 1 {
+		e.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - e.tokens) * float64(perToken))
+	}
+	res.Remaining = int(e.tokens)
+	res.ResetAfter = time.Duration((capacity - e.tokens) * float64(perToken))
+	return res
+}
+
+func (e *memoryEntry) slide(limit Limit, now time.Time) *Result {
+	elapsed := now.Sub(e.windowStart)
+	if elapsed >= limit.Period {
+		// Previous window is stale if more than one window passed
+		if elapsed < 2*limit.Period {
+			e.prev = e.curr
+		} else {
+			e.prev = 0
+		}
+		e.curr = 0
+		n := elapsed / limit.Period
+		e.windowStart = e.windowStart.Add(n * limit.Period)
+		elapsed -= n * limit.Period
+	}
+	weight := 1 - float64(elapsed)/float64(limit.Period)
+	estimated := float64(e.prev)*weight + float64(e.curr)
+	res := &Result{
+		Limit:      limit.Rate,
+		ResetAfter: limit.Period - elapsed,
+	}
+	if estimated+1 <= float64(limit.Rate) {
+		e.curr++
+		estimated++
+		res.Allowed = true
+	} else if e.curr+1 <= limit.Rate && e.prev > 0 {
+		// Wait until weighted count of previous window decreases enough
+		need := 1 - float64(limit.Rate-e.curr-1)/float64(e.prev)
+		res.RetryAfter = time.Duration(need*float64(limit.Period)) - elapsed
+	} else {
+		res.RetryAfter = limit.Period - elapsed
+	}
+	res.Remaining = limit.Rate - int(math.Ceil(estimated))
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	return res
+}
+
+// sweep removes idle entries, whose quotas are fully restored. s.mu must be held
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < memorySweepInterval {
+		return
+	}
+	s.sweptAt = now
+	for k, e := range s.entries {
+		if now.Sub(e.updatedAt) > 2*e.period {
+			delete(s.entries, k)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Package ratelimit implements token bucket and sliding window rate limiting with pluggable stores
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type Algorithm int
+
+const (
+	// TokenBucket allows bursts up to Limit.Burst, refilling Limit.Rate tokens every Limit.Period
+	TokenBucket Algorithm = iota
+	// SlidingWindow allows Limit.Rate requests in any Limit.Period, estimated by counts of the current and previous windows
+	SlidingWindow
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case TokenBucket:
+		return "token_bucket"
+	case SlidingWindow:
+		return "sliding_window"
+	default:
+		return "unknown"
+	}
+}
+
+// Limit allows Rate requests every Period
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	// Burst is the capacity of token bucket, Rate is used if it's not positive
+	Burst     int
+	Algorithm Algorithm
+}
+
+func PerSecond(rate int) Limit {
+	return Limit{Rate: rate, Period: time.Second}
+}
+
+func PerMinute(rate int) Limit {
+	return Limit{Rate: rate, Period: time.Minute}
+}
+
+func PerHour(rate int) Limit {
+	return Limit{Rate: rate, Period: time.Hour}
+}
+
+// Capacity returns the maximum number of requests allowed at once
+func (l Limit) Capacity() int {
+	if l.Algorithm == TokenBucket && l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Rate
+}
+
+// Validate reports whether l can be taken by stores
+func (l Limit) Validate() error {
+	if l.Rate <= 0 || l.Period <= 0 {
+		return fmt.Errorf("invalid limit %d per %v", l.Rate, l.Period)
+	}
+	return nil
+}
+
+// Result is the outcome of taking a request
+type Result struct {
+	Allowed bool
+	// Limit is the capacity of the limit
+	Limit     int
+	Remaining int
+	// ResetAfter is the duration until the quota is fully restored
+	ResetAfter time.Duration
+	// RetryAfter is the duration until the next request is allowed, it's zero if Allowed is true
+	RetryAfter time.Duration
+}
+
+// Store keeps states of limits. Take must be atomic for the same key,
+// so shared backends, e.g. Redis, usually implement it with scripts
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (*Result, error)
+}
+
+// Limiter limits requests by key with a default limit, or limits of names, e.g. routes, call names
+type Limiter struct {
+	store Store
+	limit Limit
+
+	mu     sync.RWMutex
+	limits map[string]Limit
+}
+
+// NewLimiter returns a limiter, whose states are kept in memory if store is nil
+func NewLimiter(limit Limit, store Store) *Limiter {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &Limiter{
+		store:  store,
+		limit:  limit,
+		limits: make(map[string]Limit),
+	}
+}
+
+// SetLimit sets limit of name. Requests of name are counted separately from the default limit
+func (l *Limiter) SetLimit(name string, limit Limit) {
+	l.mu.Lock()
+	l.limits[name] = limit
+	l.mu.Unlock()
+}
+
+// Allow takes a request of key under the limit of name, or the default limit if name has no limit
+func (l *Limiter) Allow(ctx context.Context, name, key string) (*Result, error) {
+	l.mu.RLock()
+	limit, ok := l.limits[name]
+	l.mu.RUnlock()
+	if ok {
+		key = name + "|" + key
+	} else {
+		limit = l.limit
+	}
+	return l.store.Take(ctx, key, limit)
+}
@@ -0,0 +1,82 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gopub/wine/ratelimit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	s := ratelimit.NewMemoryStore()
+	ctx := context.Background()
+	limit := ratelimit.Limit{Rate: 10, Period: 100 * time.Millisecond, Burst: 3}
+	for i := 2; i >= 0; i-- {
+		res, err := s.Take(ctx, "k", limit)
+		require.NoError(t, err)
+		require.True(t, res.Allowed)
+		require.Equal(t, 3, res.Limit)
+		require.Equal(t, i, res.Remaining)
+	}
+	res, err := s.Take(ctx, "k", limit)
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+	require.True(t, res.RetryAfter > 0 && res.RetryAfter <= 10*time.Millisecond, res.RetryAfter)
+
+	res, err = s.Take(ctx, "other", limit)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+
+	time.Sleep(res.RetryAfter + 10*time.Millisecond)
+	res, err = s.Take(ctx, "k", limit)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+
+	_, err = s.Take(ctx, "k", ratelimit.Limit{})
+	require.Error(t, err)
+}
+
+func TestMemoryStore_SlidingWindow(t *testing.T) {
+	s := ratelimit.NewMemoryStore()
+	ctx := context.Background()
+	limit := ratelimit.Limit{Rate: 2, Period: 200 * time.Millisecond, Algorithm: ratelimit.SlidingWindow}
+	for i := 0; i < 2; i++ {
+		res, err := s.Take(ctx, "k", limit)
+		require.NoError(t, err)
+		require.True(t, res.Allowed)
+	}
+	res, err := s.Take(ctx, "k", limit)
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+	require.Equal(t, 0, res.Remaining)
+	require.True(t, res.RetryAfter > 0 && res.RetryAfter <= limit.Period, res.RetryAfter)
+
+	// Requests of the previous window still count at the beginning of the next one
+	time.Sleep(limit.Period + 20*time.Millisecond)
+	res, err = s.Take(ctx, "k", limit)
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+
+	time.Sleep(limit.Period)
+	res, err = s.Take(ctx, "k", limit)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+}
+
+func TestLimiter(t *testing.T) {
+	l := ratelimit.NewLimiter(ratelimit.PerMinute(1), nil)
+	l.SetLimit("login", ratelimit.PerMinute(2))
+	ctx := context.Background()
+	allow := func(name string) bool {
+		res, err := l.Allow(ctx, name, "k")
+		require.NoError(t, err)
+		return res.Allowed
+	}
+	require.True(t, allow("a"))
+	require.False(t, allow("b"))
+	require.True(t, allow("login"))
+	require.True(t, allow("login"))
+	require.False(t, allow("login"))
+}
@@ -0,0 +1,58 @@
+package wine_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/httpvalue"
+	"github.com/gopub/wine/ratelimit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitHandler(t *testing.T) {
+	s := wine.NewTestServer(t)
+	l := ratelimit.NewLimiter(ratelimit.PerMinute(2), nil)
+	l.SetLimit("POST /login", ratelimit.PerMinute(1))
+	r := s.Use(wine.NewRateLimitHandler(l, wine.RateLimitByAppID))
+	ok := func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.OK
+	}
+	r.Get("items", ok)
+	r.Post("login", ok)
+	url := s.Run()
+
+	do := func(method, path, appID string) *http.Response {
+		req, err := http.NewRequest(method, url+path, nil)
+		require.NoError(t, err)
+		if appID != "" {
+			req.Header.Set(httpvalue.CustomAppID, appID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := do(http.MethodGet, "/items", "a")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "2", resp.Header.Get(httpvalue.RateLimitLimit))
+	require.Equal(t, "1", resp.Header.Get(httpvalue.RateLimitRemaining))
+	require.NotEmpty(t, resp.Header.Get(httpvalue.RateLimitReset))
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/items", "a").StatusCode)
+	resp = do(http.MethodGet, "/items", "a")
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.Equal(t, "0", resp.Header.Get(httpvalue.RateLimitRemaining))
+	require.NotEmpty(t, resp.Header.Get(httpvalue.RetryAfter))
+
+	// Other keys, endpoints with own limits and requests without key are counted separately
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/items", "b").StatusCode)
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/login", "a").StatusCode)
+	require.Equal(t, http.StatusTooManyRequests, do(http.MethodPost, "/login", "a").StatusCode)
+	for i := 0; i < 3; i++ {
+		resp = do(http.MethodGet, "/items", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Empty(t, resp.Header.Get(httpvalue.RateLimitLimit))
+	}
+}
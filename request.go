@@ -13,9 +13,11 @@ import (
 	"github.com/gopub/conv"
 	"github.com/gopub/errors"
 	"github.com/gopub/types"
+	"github.com/gopub/wine/codec"
 	"github.com/gopub/wine/httpvalue"
 	iopkg "github.com/gopub/wine/internal/io"
 	"github.com/gopub/wine/router"
+	"github.com/gopub/wine/trace"
 )
 
 type GroupedParams = iopkg.RequestParams
@@ -27,11 +29,13 @@ type Request struct {
 	params        types.M
 	body          []byte
 	contentType   string
+	streaming     bool
 	Model         interface{}
 
 	uid       int64
 	sensitive bool
 	endpoint  *Endpoint
+	span      *trace.Span
 }
 
 // Request returns original http request
@@ -56,7 +60,7 @@ func (r *Request) setPathParams(p map[string]string) {
 	}
 }
 
-// Body returns request body
+// Body returns request body, which is nil if endpoint's body is streaming
 func (r *Request) Body() []byte {
 	return r.body
 }
@@ -112,15 +116,32 @@ func (r *Request) NormalizedPath() string {
 // bind: m represents the prototype of request.Model
 func (r *Request) bind(m interface{}) error {
 	if _, ok := m.(proto.Message); ok {
+		// proto message can be encoded in protobuf, or other codecs e.g. json which is decoded by protojson
+		c := codec.Get(r.contentType)
+		if c == nil {
+			c = codec.Protobuf
+		}
 		pv := reflect.New(reflect.TypeOf(m).Elem())
-		if err := proto.Unmarshal(r.body, pv.Interface().(proto.Message)); err != nil {
-			return errors.BadRequest("cannot unmarshal protobuf message: %v", err)
+		if len(r.body) > 0 {
+			if err := c.Unmarshal(r.body, pv.Interface()); err != nil {
+				return errors.BadRequest("cannot unmarshal protobuf message: %v", err)
+			}
 		}
 		r.Model = pv.Interface()
 		return Validate(r.Model)
 	}
 
 	pv := reflect.New(reflect.TypeOf(m))
+	if c := r.bodyCodec(); c != nil {
+		// path and query params are assigned first, then overwritten by body
+		_ = conv.Assign(pv.Interface(), r.params)
+		if err := c.Unmarshal(r.body, pv.Interface()); err != nil {
+			return errors.BadRequest("cannot unmarshal %s body: %v", r.contentType, err)
+		}
+		r.Model = pv.Elem().Interface()
+		return Validate(r.Model)
+	}
+
 	err := conv.Assign(pv.Interface(), r.params)
 	if err == nil {
 		r.Model = pv.Elem().Interface()
@@ -140,6 +161,18 @@ func (r *Request) bind(m interface{}) error {
 	return errors.BadRequest("cannot assign: %v", err)
 }
 
+// bodyCodec returns codec to decode body into model if body isn't json or form, e.g. xml, msgpack, cbor and yaml
+func (r *Request) bodyCodec() codec.Codec {
+	if len(r.body) == 0 {
+		return nil
+	}
+	switch r.contentType {
+	case httpvalue.JSON, httpvalue.Protobuf:
+		return nil
+	}
+	return codec.Get(r.contentType)
+}
+
 // get one value from path or query params
 func getSingleParam(r *Request) interface{} {
 	var params = r.groupedParams.PathParams
@@ -191,16 +224,13 @@ func (r *Request) SetUserID(id int64) {
 	r.uid = id
 }
 
-func parseRequest(r *http.Request, maxMem types.ByteUnit) (*Request, error) {
-	params, body, err := iopkg.ReadRequest(r, maxMem)
-	if err != nil {
-		return nil, fmt.Errorf("read request: %w", err)
-	}
+func newRequest(r *http.Request, params *GroupedParams, body []byte, streaming bool) *Request {
 	return &Request{
 		request:       r,
 		groupedParams: params,
 		params:        params.Combine(),
 		body:          body,
 		contentType:   httpvalue.GetContentType(r.Header),
-	}, nil
+		streaming:     streaming,
+	}
 }
@@ -107,7 +107,7 @@ func HTML(status int, html string) Responder {
 
 func TemplateHTML(name string, params interface{}) Responder {
 	return respond.Func(func(ctx context.Context, w http.ResponseWriter) {
-		ctxutil.GetTemplateManager(ctx).Execute(w, name, params)
+		ctxutil.GetTemplateManager(ctx).ExecuteWithFuncs(w, name, params, ctxutil.GetTemplateFuncs(ctx))
 	})
 }
 
@@ -129,8 +129,9 @@ func Error(err error) Responder {
 }
 
 type Result struct {
-	Status int
-	Body   []byte
+	Status   int
+	Body     []byte
+	BytesOut int64
 }
 
 func CompressWriter(w http.ResponseWriter, encodings ...string) (http.ResponseWriter, error) {
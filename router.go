@@ -7,11 +7,18 @@ import (
 	"strings"
 
 	"github.com/gopub/conv"
+	"github.com/gopub/types"
 	"github.com/gopub/wine/router"
 )
 
 type metadata struct {
-	Header *Header
+	Header    *Header
+	Responses map[int]*ResponseSpec
+	Name      string
+	CORS      *CORSPolicy
+
+	MaxBodySize   types.ByteUnit
+	StreamingBody bool
 }
 
 func newMetadata() *metadata {
@@ -21,9 +28,18 @@ func newMetadata() *metadata {
 }
 
 func (m *metadata) clone() *metadata {
-	return &metadata{
-		Header: m.Header.Clone(),
+	c := &metadata{
+		Header:      m.Header.Clone(),
+		CORS:        m.CORS,
+		MaxBodySize: m.MaxBodySize,
+	}
+	if len(m.Responses) > 0 {
+		c.Responses = make(map[int]*ResponseSpec, len(m.Responses))
+		for k, v := range m.Responses {
+			c.Responses[k] = v
+		}
 	}
+	return c
 }
 
 type Endpoint struct {
@@ -31,7 +47,26 @@ type Endpoint struct {
 }
 
 func (e *Endpoint) Header() *Header {
-	return e.Metadata().(*metadata).Header
+	return e.metadata().Header
+}
+
+// SetName sets name which identifies e in access logs
+func (e *Endpoint) SetName(name string) *Endpoint {
+	e.metadata().Name = name
+	return e
+}
+
+// Name returns name set by SetName, or name of the last handler
+func (e *Endpoint) Name() string {
+	if name := e.metadata().Name; name != "" {
+		return name
+	}
+	l := strings.Split(e.HandlerPath(), ", ")
+	return l[len(l)-1]
+}
+
+func (e *Endpoint) metadata() *metadata {
+	return e.Metadata().(*metadata)
 }
 
 // Router implements routing function
@@ -238,6 +273,10 @@ func (r *Router) listEndpoints(ctx context.Context, req *Request) Responder {
 		format := fmt.Sprintf("%%3d. %%6s /%%-%ds %%s", maxLenOfPath)
 		line := fmt.Sprintf(format, i+1, n.Scope, n.Path(), n.HandlerPath())
 		b.WriteString(line)
+		if md, ok := n.Metadata().(*metadata); ok && len(md.Responses) > 0 {
+			b.WriteString(" => ")
+			b.WriteString(md.responsesString())
+		}
 		if n.Description() != "" {
 			b.WriteString(" #")
 			b.WriteString(n.Description())
@@ -257,10 +296,26 @@ func (r *Router) toEndpoint(e *router.Endpoint) *Endpoint {
 	}
 
 	new := r.md.clone()
-	if md, ok := e.Metadata().(*metadata); ok && md.Header != nil {
-		for k, v := range md.Header.Header {
-			new.Header.Header[k] = v
+	if md, ok := e.Metadata().(*metadata); ok {
+		if md.Header != nil {
+			for k, v := range md.Header.Header {
+				new.Header.Header[k] = v
+			}
+		}
+		if len(md.Responses) > 0 && new.Responses == nil {
+			new.Responses = make(map[int]*ResponseSpec, len(md.Responses))
+		}
+		for k, v := range md.Responses {
+			new.Responses[k] = v
+		}
+		new.Name = md.Name
+		if md.CORS != nil {
+			new.CORS = md.CORS
+		}
+		if md.MaxBodySize != 0 {
+			new.MaxBodySize = md.MaxBodySize
 		}
+		new.StreamingBody = md.StreamingBody
 	}
 	e.SetMetadata(new)
 	return &Endpoint{
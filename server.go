@@ -2,16 +2,17 @@ package wine
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"math/rand"
 	"mime"
 	"net"
 	"net/http"
 	"path"
 	"runtime/debug"
 	"strings"
-	"testing"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gopub/conv"
@@ -24,13 +25,17 @@ import (
 	"github.com/gopub/wine/internal/resource"
 	"github.com/gopub/wine/internal/respond"
 	"github.com/gopub/wine/internal/template"
+	"github.com/gopub/wine/openapi"
+	"github.com/gopub/wine/router"
+	"github.com/gopub/wine/trace"
 )
 
 const (
 	faviconPath = "favicon.ico"
 
-	defaultReqMaxMem = int(8 * types.MB)
-	defaultTimeout   = 10 * time.Second
+	defaultReqMaxMem   = int(8 * types.MB)
+	defaultMaxBodySize = int(32 * types.MB)
+	defaultTimeout     = 10 * time.Second
 
 	minAutoCompressionSize = 2048
 )
@@ -41,29 +46,52 @@ var reservedPaths = map[string]bool{
 	versionPath:  true,
 	endpointPath: true,
 	echoPath:     true,
+	openAPIPath:  true,
+	readyPath:    true,
+	metricsPath:  true,
 	faviconPath:  true,
 }
 
 type Options struct {
-	ReqFormMem      types.ByteUnit
+	ReqFormMem types.ByteUnit
+	// MaxBodySize limits size of request bodies, larger bodies are responded with 413. Zero or negative size means no limit
+	MaxBodySize     types.ByteUnit
 	Timeout         time.Duration
 	Recovery        bool
 	AutoCompression bool
 	LoggingReqModel bool
+	// Development enables checks which are expensive or noisy in production, e.g. validating responses against declared specs
+	Development bool
+	// DrainDelay is the duration between readiness turning unhealthy and server stopping accepting connections
+	DrainDelay time.Duration
 }
 
 // Server implements web server
 type Server struct {
+	inFlight int64 // accessed atomically, keep it 64-bit aligned
+
 	*Router
 	*template.Manager
 	server *http.Server
 
+	mu            sync.Mutex
+	draining      int32
+	shutdownHooks []ShutdownHook
+
+	metrics *serverMetrics
+
 	addr string
 	url  string
 
 	Options
-	ResultLogger    func(req *Request, result *Result, cost time.Duration)
+	ResultLogger func(req *Request, result *Result, cost time.Duration)
+	// AccessLogger writes structured access logs if it's not nil
+	AccessLogger *AccessLogger
+	// Tracer starts a server span for each request, which continues the trace in traceparent header
+	Tracer          *trace.Tracer
 	NotFoundHandler Handler
+	// APIInfo is used to generate OpenAPI document
+	APIInfo *openapi.Info
 }
 
 // NewServer returns a server
@@ -76,10 +104,13 @@ func NewServer(options *Options) *Server {
 	if options == nil {
 		options = &Options{
 			ReqFormMem:      types.ByteUnit(environ.SizeInBytes("wine.max_memory", defaultReqMaxMem)),
+			MaxBodySize:     types.ByteUnit(environ.SizeInBytes("wine.max_body_size", defaultMaxBodySize)),
 			Timeout:         environ.Duration("wine.timeout", defaultTimeout),
 			Recovery:        environ.Bool("wine.recovery", true),
 			AutoCompression: environ.Bool("wine.compression.auto", true),
 			LoggingReqModel: environ.Bool("wine.logging.request.model", true),
+			Development:     environ.Bool("wine.development", false),
+			DrainDelay:      environ.Duration("wine.shutdown.drain_delay", 0),
 		}
 	}
 
@@ -87,83 +118,158 @@ func NewServer(options *Options) *Server {
 		Router:       NewRouter(),
 		Manager:      template.NewManager(),
 		ResultLogger: logResult,
+		AccessLogger: newAccessLoggerFromEnv(),
+		Tracer:       trace.NewTracer(nil),
 		Options:      *options,
+		APIInfo: &openapi.Info{
+			Title:   environ.String("wine.openapi.title", "Wine"),
+			Version: environ.String("wine.openapi.version", "1.0.0"),
+		},
 	}
 
+	s.metrics = newServerMetrics(s)
 	s.AddTemplateFuncMap(template.FuncMap)
+	s.Get(openAPIPath, s.handleOpenAPI)
+	s.Get(readyPath, s.handleReady)
+	s.Get(metricsPath, s.handleMetrics)
 	return s
 }
 
 func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.addr
 }
 
 func (s *Server) URL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.url
 }
 
-func (s *Server) assignAddr(addr string, tls bool) {
-	s.addr = addr
-	if strings.HasPrefix(addr, ":") {
-		s.addr = "0.0.0.0" + addr
-	} else {
-		s.addr = addr
+// assignAddr must be called with s.mu held
+func (s *Server) assignAddr(l net.Listener, tls bool) {
+	if l == nil {
+		s.addr = ""
+		s.url = ""
+		return
 	}
 
-	if addr == "" {
+	if l.Addr().Network() == "unix" {
+		s.addr = unixAddrPrefix + l.Addr().String()
 		s.url = ""
-	} else {
-		if tls {
-			s.url = "https://" + s.addr
-		} else {
-			s.url = "http://" + s.addr
+		return
+	}
+
+	s.addr = l.Addr().String()
+	if host, port, err := net.SplitHostPort(s.addr); err == nil {
+		if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+			s.addr = net.JoinHostPort("0.0.0.0", port)
 		}
 	}
+	if tls {
+		s.url = "https://" + s.addr
+	} else {
+		s.url = "http://" + s.addr
+	}
 }
 
-// Run starts server
-func (s *Server) Run(addr string) {
-	if s.server != nil {
-		logger.Panicf("HTTP Server is running")
+// Run starts server on addr. Refer to Listen for supported addr formats
+func (s *Server) Run(addr string) error {
+	l, err := Listen(addr)
+	if err != nil {
+		logger.Errorf("Listen %s: %v", addr, err)
+		return err
 	}
+	return s.Serve(l)
+}
 
-	logger.Infof("HTTP server is running on %s", addr)
-	s.server = &http.Server{Addr: addr, Handler: s}
-	s.assignAddr(s.server.Addr, false)
-	err := s.server.ListenAndServe()
-	s.assignAddr("", false)
+// RunTLS starts server with tls
+func (s *Server) RunTLS(addr, certFile, keyFile string) error {
+	l, err := Listen(addr)
 	if err != nil {
-		if errors.Is(err, http.ErrServerClosed) {
-			logger.Infof("HTTP server was closed")
-		} else {
-			logger.Panicf("HTTP server was terminated: %v", err)
-		}
+		logger.Errorf("Listen %s: %v", addr, err)
+		return err
 	}
+	return s.ServeTLS(l, certFile, keyFile)
 }
 
-// RunTLS starts server with tls
-func (s *Server) RunTLS(addr, certFile, keyFile string) {
-	if s.server != nil {
-		logger.Panic("HTTPS Server is running")
+// RunUnix starts server on Unix domain socket at path
+func (s *Server) RunUnix(path string) error {
+	return s.Run(unixAddrPrefix + path)
+}
+
+// Serve accepts connections on l. l is closed when Serve returns
+func (s *Server) Serve(l net.Listener) error {
+	srv := s.newHTTPServer(l, false)
+	logger.Infof("HTTP server is running on %s", s.Addr())
+	err := srv.Serve(l)
+	s.mu.Lock()
+	s.assignAddr(nil, false)
+	s.mu.Unlock()
+	if errors.Is(err, http.ErrServerClosed) {
+		logger.Infof("HTTP server was closed")
+		return nil
 	}
+	logger.Errorf("HTTP server was terminated: %v", err)
+	return err
+}
+
+// ServeTLS accepts tls connections on l. l is closed when ServeTLS returns
+func (s *Server) ServeTLS(l net.Listener, certFile, keyFile string) error {
+	return s.serveTLS(l, nil, certFile, keyFile)
+}
 
-	logger.Infof("HTTPS server is running on %s", addr)
-	s.server = &http.Server{Addr: addr, Handler: s}
-	s.assignAddr(s.server.Addr, true)
-	err := s.server.ListenAndServeTLS(certFile, keyFile)
-	s.assignAddr("", false)
+// RunTLSConfig starts server with tls config, e.g. CertManager.TLSConfig()
+func (s *Server) RunTLSConfig(addr string, config *tls.Config) error {
+	l, err := Listen(addr)
 	if err != nil {
-		if errors.Is(err, http.ErrServerClosed) {
-			logger.Infof("HTTPS server was closed")
-		} else {
-			logger.Panicf("HTTPS server was terminated: %v", err)
-		}
+		logger.Errorf("Listen %s: %v", addr, err)
+		return err
+	}
+	return s.ServeTLSConfig(l, config)
+}
+
+// ServeTLSConfig accepts tls connections on l with config. l is closed when ServeTLSConfig returns
+func (s *Server) ServeTLSConfig(l net.Listener, config *tls.Config) error {
+	return s.serveTLS(l, config, "", "")
+}
+
+// RunAutoTLS starts server with certificates which are reloaded from files or obtained by ACME automatically
+func (s *Server) RunAutoTLS(addr string, options *TLSOptions) error {
+	m, err := NewCertManager(options)
+	if err != nil {
+		logger.Errorf("Create cert manager: %v", err)
+		return err
+	}
+	return s.RunTLSConfig(addr, m.TLSConfig())
+}
+
+func (s *Server) serveTLS(l net.Listener, config *tls.Config, certFile, keyFile string) error {
+	srv := s.newHTTPServer(l, true)
+	srv.TLSConfig = config
+	logger.Infof("HTTPS server is running on %s", s.Addr())
+	err := srv.ServeTLS(l, certFile, keyFile)
+	s.mu.Lock()
+	s.assignAddr(nil, false)
+	s.mu.Unlock()
+	if errors.Is(err, http.ErrServerClosed) {
+		logger.Infof("HTTPS server was closed")
+		return nil
 	}
+	logger.Errorf("HTTPS server was terminated: %v", err)
+	return err
 }
 
-// Shutdown shutdown server
-func (s *Server) Shutdown() error {
-	return s.server.Shutdown(context.Background())
+func (s *Server) newHTTPServer(l net.Listener, tls bool) *http.Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.server != nil {
+		logger.Panicf("Server is running")
+	}
+	s.server = &http.Server{Addr: l.Addr().String(), Handler: s}
+	s.assignAddr(l, tls)
+	return s.server
 }
 
 func (s *Server) Match(scope string, path string) (*Endpoint, map[string]string) {
@@ -174,6 +280,12 @@ func (s *Server) Match(scope string, path string) (*Endpoint, map[string]string)
 // ServeHTTP implements for http.Handler interface, which will handle each http request
 func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	startAt := time.Now()
+	atomic.AddInt64(&s.inFlight, 1)
+	defer atomic.AddInt64(&s.inFlight, -1)
+	if !s.Ready() {
+		// Ask client to reconnect, probably to another instance
+		rw.Header().Set("Connection", "close")
+	}
 	if s.Recovery {
 		defer func() {
 			if e := recover(); e != nil {
@@ -185,15 +297,27 @@ func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	rw = s.wrapResponseWriter(rw, req)
 	ctx, cancel := s.initContext(req)
 	defer cancel()
+	ctx, span := s.startSpan(ctx, req)
 
-	wReq, err := parseRequest(req, s.ReqFormMem)
+	// Match ahead of parsing as endpoint decides how to read body
+	endpoint, params := s.Match(req.Method, router.Normalize(req.URL.Path))
+	wReq, err := s.parseRequest(req, endpoint)
 	if err != nil {
 		defer s.closeWriter(rw)
-		resp := Text(http.StatusBadRequest, fmt.Sprintf("Parse request: %v", err))
+		var resp Responder
+		if errors.Is(err, ErrBodyTooLarge) {
+			rw.Header().Set("Connection", "close")
+			resp = Error(err)
+		} else {
+			resp = Text(http.StatusBadRequest, fmt.Sprintf("Parse request: %v", err))
+		}
 		resp.Respond(ctx, rw)
-		s.logResult(&Request{request: req}, rw, startAt)
+		s.logResult(&Request{request: req, span: span}, rw, startAt)
 		return
 	}
+	wReq.span = span
+	wReq.endpoint = endpoint
+	wReq.setPathParams(params)
 	s.serve(ctx, wReq, rw)
 	s.logResult(wReq, rw, startAt)
 }
@@ -201,14 +325,21 @@ func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 func (s *Server) serve(ctx context.Context, req *Request, rw http.ResponseWriter) {
 	np := req.NormalizedPath()
 	method := req.Request().Method
-	endpoint, params := s.Match(method, np)
-	req.setPathParams(params)
-	req.endpoint = endpoint
+	endpoint := req.endpoint
+	if endpoint != nil && req.span != nil {
+		req.span.SetName(method + " /" + endpoint.Path())
+		req.span.SetAttribute("http.route", "/"+endpoint.Path())
+	}
 	s.Header().WriteTo(rw)
 	var h Handler
 	switch {
+	case isPreflight(req.request) && (endpoint == nil || s.hasPreflightPolicy(req)):
+		h = HandlerFunc(s.handlePreflight)
 	case endpoint != nil:
 		endpoint.Header().WriteTo(rw)
+		if p := endpoint.CORS(); p != nil {
+			p.writeHeader(rw, req.Header(httpvalue.Origin))
+		}
 		req.sensitive = endpoint.Sensitive()
 		if m := endpoint.Model(); m != nil {
 			if err := req.bind(m); err != nil {
@@ -231,6 +362,10 @@ func (s *Server) serve(ctx context.Context, req *Request, rw http.ResponseWriter
 	case np == faviconPath:
 		h = HandleResponder(respond.Bytes(http.StatusOK, resource.Favicon))
 	default:
+		if p := s.md.CORS; p != nil {
+			// Let browsers read the error
+			p.writeHeader(rw, req.Header(httpvalue.Origin))
+		}
 		if s.NotFoundHandler != nil {
 			h = s.NotFoundHandler
 		} else {
@@ -242,6 +377,9 @@ func (s *Server) serve(ctx context.Context, req *Request, rw http.ResponseWriter
 	if resp == nil {
 		resp = Status(http.StatusNotImplemented)
 	}
+	if s.Development && endpoint != nil {
+		endpoint.validateResponse(ctx, resp)
+	}
 	rw = s.compressWriter(rw, req, resp)
 	defer s.closeWriter(rw)
 	resp.Respond(ctx, rw)
@@ -288,9 +426,38 @@ func (s *Server) initContext(req *http.Request) (context.Context, context.Cancel
 	ctx, cancel := context.WithTimeout(req.Context(), s.Timeout)
 	ctx = ctxutil.WithTemplateManager(ctx, s.Manager)
 	ctx = ctxutil.WithRequestHeader(ctx, req.Header)
+	if sc, ok := trace.Extract(req.Header); ok {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+	} else if legacyID := legacyTraceID(req.Header); legacyID != "" {
+		// Keep ids of legacy clients which only send X-Wine-Trace-Id, e.g. uuid, so that logs can be correlated.
+		// Continue the trace if it's a valid trace id
+		ctx = ctxutil.WithTraceID(ctx, legacyID)
+		if id, err := trace.ParseTraceID(legacyID); err == nil {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, trace.SpanContext{TraceID: id, Flags: trace.FlagsSampled})
+		}
+	}
 	return ctx, cancel
 }
 
+// legacyTraceID returns X-Wine-Trace-Id if traceparent is absent
+func legacyTraceID(h http.Header) string {
+	if h.Get(trace.Traceparent) != "" {
+		return ""
+	}
+	return h.Get(httpvalue.CustomTraceID)
+}
+
+// startSpan starts a server span named by method until route is matched
+func (s *Server) startSpan(ctx context.Context, req *http.Request) (context.Context, *trace.Span) {
+	if s.Tracer == nil {
+		return ctx, nil
+	}
+	ctx, span := s.Tracer.Start(ctx, req.Method, trace.SpanKindServer)
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.target", req.URL.Path)
+	return ctx, span
+}
+
 func (s *Server) closeWriter(w http.ResponseWriter) {
 	if cw, ok := w.(*io.CompressResponseWriter); ok {
 		err := cw.Close()
@@ -301,7 +468,6 @@ func (s *Server) closeWriter(w http.ResponseWriter) {
 }
 
 func (s *Server) handleOptions(_ context.Context, req *Request) Responder {
-	// TODO: how to handle preflight correctly?
 	methods := s.MatchScopes(req.NormalizedPath())
 	if len(methods) > 0 {
 		methods = append(methods, http.MethodOptions)
@@ -320,9 +486,22 @@ func (s *Server) logResult(req *Request, rw http.ResponseWriter, startAt time.Ti
 	if getBody, ok := rw.(interface{ Body() []byte }); ok {
 		res.Body = getBody.Body()
 	}
+	if getSize, ok := rw.(interface{ Size() int64 }); ok {
+		res.BytesOut = getSize.Size()
+	}
+	cost := time.Since(startAt)
+	if span := req.span; span != nil {
+		span.SetAttribute("http.status_code", res.Status)
+		if res.Status >= http.StatusInternalServerError {
+			span.SetError(errors.New(http.StatusText(res.Status)))
+		}
+		span.End()
+	}
+	s.observe(req, res, cost)
 	if s.ResultLogger != nil {
-		s.ResultLogger(req, res, time.Since(startAt))
+		s.ResultLogger(req, res, cost)
 	}
+	s.logAccess(req, res, startAt)
 }
 
 func logResult(req *Request, res *Result, cost time.Duration) {
@@ -372,38 +551,3 @@ func logResult(req *Request, res *Result, cost time.Duration) {
 		}
 	}
 }
-
-type TestServer struct {
-	*Server
-	URL string
-}
-
-func NewTestServer(t *testing.T) *TestServer {
-	s := NewServer(nil)
-	t.Cleanup(func() {
-		s.Shutdown()
-	})
-	return &TestServer{
-		Server: s,
-	}
-}
-
-func (s *TestServer) Run() string {
-	for n := 0; n < 10; n++ {
-		addr := fmt.Sprintf("localhost:%d", rand.Int()%1e4+1e4)
-		conn, err := net.Dial("TCP", addr)
-		if err != nil {
-			go s.Server.Run(addr)
-			s.URL = "http://" + addr
-			break
-		}
-		conn.Close()
-	}
-	return s.URL
-}
-
-func (s *TestServer) RunTLS() string {
-	// TODO:
-	logger.Panic("Not implemented")
-	return s.URL
-}
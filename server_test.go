@@ -2,6 +2,7 @@ package wine_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
@@ -9,11 +10,14 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gopub/errors"
 	"github.com/gopub/wine"
+	"github.com/gopub/wine/ctxutil"
 	"github.com/gopub/wine/httpvalue"
+	"github.com/gopub/wine/trace"
 	"github.com/stretchr/testify/require"
 )
 
@@ -236,3 +240,166 @@ func TestServer_Bind(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestServer_Metrics(t *testing.T) {
+	s := wine.NewTestServer(t)
+	s.Get("items/{id}", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.Text(http.StatusOK, "item")
+	})
+	url := s.Run()
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(fmt.Sprintf("%s/items/%d", url, i))
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	resp, err := http.Get(url + "/_wine/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `wine_http_requests_total{method="GET",route="/items/{id}",status="200"} 2`)
+	require.Contains(t, string(b), `wine_http_request_duration_seconds_count{method="GET",route="/items/{id}",status="200"} 2`)
+	require.Contains(t, string(b), "wine_http_requests_in_flight 1")
+}
+
+func TestServer_Trace(t *testing.T) {
+	exporter := trace.NewMemoryExporter()
+	backend := wine.NewTestServer(t)
+	backend.Tracer = trace.NewTracer(exporter)
+	backend.Get("users/{id}", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.JSON(http.StatusOK, ctxutil.GetTraceID(ctx))
+	})
+	backendURL := backend.Run()
+
+	frontend := wine.NewTestServer(t)
+	frontend.Tracer = trace.NewTracer(exporter)
+	frontend.Get("profile", func(ctx context.Context, req *wine.Request) wine.Responder {
+		var traceID string
+		if err := wine.DefaultClient.Get(ctx, backendURL+"/users/1", nil, &traceID); err != nil {
+			return wine.Error(err)
+		}
+		return wine.Text(http.StatusOK, traceID)
+	})
+	url := frontend.Run()
+
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req, err := http.NewRequest(http.MethodGet, url+"/profile", nil)
+	require.NoError(t, err)
+	req.Header.Set(trace.Traceparent, parent)
+	req.Header.Set(trace.Tracestate, "vendor=1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", string(b))
+
+	// Spans end after responses are written
+	require.Eventually(t, func() bool {
+		return len(exporter.Spans()) == 2
+	}, time.Second, 10*time.Millisecond)
+	spans := exporter.Spans()
+	byName := make(map[string]*trace.SpanData, len(spans))
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+	front, back := byName["GET /profile"], byName["GET /users/{id}"]
+	require.NotEmpty(t, front)
+	require.NotEmpty(t, back)
+	require.Equal(t, trace.SpanKindServer, front.Kind)
+	require.Equal(t, "00f067aa0ba902b7", front.ParentSpanID.String())
+	require.Equal(t, front.Context.TraceID, back.Context.TraceID)
+	require.Equal(t, front.Context.SpanID, back.ParentSpanID)
+	require.Equal(t, "vendor=1", back.Context.TraceState)
+	require.Equal(t, http.StatusOK, back.Attributes["http.status_code"])
+}
+
+func TestServer_LegacyTraceID(t *testing.T) {
+	s := wine.NewTestServer(t)
+	s.Get("trace", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.Text(http.StatusOK, ctxutil.GetTraceID(ctx))
+	})
+	url := s.Run() + "/trace"
+	get := func(header http.Header) string {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		req.Header = header
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	// Ids which aren't W3C trace ids are kept
+	legacyID := "5f1c2f1e-3b0a-4c1e-9a57-6b1f7e0a9d2c"
+	require.Equal(t, legacyID, get(http.Header{"X-Wine-Trace-Id": {legacyID}}))
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", get(http.Header{
+		"X-Wine-Trace-Id": {legacyID},
+		trace.Traceparent: {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+	}))
+	require.Len(t, get(http.Header{}), 32)
+}
+
+func TestServer_BindCodec(t *testing.T) {
+	server := wine.NewTestServer(t)
+	url := server.Run()
+	type Item struct {
+		Title  string  `json:"title" xml:"title"`
+		Price  float64 `json:"price" xml:"price"`
+		UserID int64   `json:"user_id" xml:"user_id"`
+	}
+	server.Post("items", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.JSON(http.StatusOK, req.Model)
+	}).SetModel(&Item{})
+	server.Post("params", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.JSON(http.StatusOK, req.Params())
+	})
+
+	in := &Item{Title: "wine", Price: 9.9, UserID: 7}
+	for _, ct := range []string{httpvalue.XML, httpvalue.MsgPack, httpvalue.CBOR, httpvalue.YAML} {
+		t.Run(ct, func(t *testing.T) {
+			e := wine.DefaultClient.MustEndpoint(http.MethodPost, url+"/items")
+			e.SetContentType(ct)
+			out := new(Item)
+			require.NoError(t, e.Call(context.Background(), in, out))
+			require.Equal(t, in, out)
+		})
+	}
+
+	for _, ct := range []string{httpvalue.MsgPack, httpvalue.CBOR, httpvalue.YAML} {
+		t.Run("Params/"+ct, func(t *testing.T) {
+			e := wine.DefaultClient.MustEndpoint(http.MethodPost, url+"/params")
+			e.SetContentType(ct)
+			var out map[string]interface{}
+			require.NoError(t, e.Call(context.Background(), map[string]interface{}{"title": "wine"}, &out))
+			require.Equal(t, "wine", out["title"])
+		})
+	}
+
+	t.Run("YAMLJSONTag", func(t *testing.T) {
+		resp, err := http.Post(url+"/items", httpvalue.YAML, strings.NewReader("title: wine\nuser_id: 7\n"))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		out := new(Item)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		require.Equal(t, &Item{Title: "wine", UserID: 7}, out)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		resp, err := http.Post(url+"/items", httpvalue.XML, strings.NewReader("<item"))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("NoCodec", func(t *testing.T) {
+		e := wine.DefaultClient.MustEndpoint(http.MethodPost, url+"/items")
+		e.SetContentType("application/unknown")
+		require.Error(t, e.Call(context.Background(), in, nil))
+	})
+}
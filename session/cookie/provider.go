@@ -0,0 +1,188 @@
+// Package cookie provides a stateless session provider which keeps sessions in signed and optionally encrypted cookies.
+package cookie
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/session"
+)
+
+var logger = wine.Logger()
+
+// MaxSize is the size limit of cookie value which browsers accept
+const MaxSize = 4096
+
+const macPrefix = "wine.session."
+
+var (
+	ErrTooLarge         = errors.New("session cookie too large")
+	ErrInvalidSignature = errors.New("invalid session cookie signature")
+	ErrExpired          = errors.New("session cookie expired")
+)
+
+// KeyPair is used to sign and encrypt cookies
+type KeyPair struct {
+	// HashKey signs cookies with HMAC-SHA256. It's required and should be 32 or 64 bytes
+	HashKey []byte
+	// BlockKey encrypts cookies with AES-GCM if it's not empty. It must be 16, 24 or 32 bytes
+	BlockKey []byte
+}
+
+type codec struct {
+	hashKey []byte
+	aead    cipher.AEAD
+}
+
+type Provider struct {
+	codecs []*codec
+}
+
+var _ session.Provider = (*Provider)(nil)
+var _ session.CookieEncoder = (*Provider)(nil)
+
+// NewProvider creates a provider with keys. Cookies are encoded by the first key pair and decoded by any of them,
+// so keys can be rotated by prepending new key pairs and removing old ones after sessions have expired
+func NewProvider(keys ...KeyPair) (*Provider, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no key pair")
+	}
+	p := new(Provider)
+	for i, k := range keys {
+		if len(k.HashKey) == 0 {
+			return nil, fmt.Errorf("key pair %d: missing hash key", i)
+		}
+		c := &codec{hashKey: k.HashKey}
+		if len(k.BlockKey) > 0 {
+			block, err := aes.NewCipher(k.BlockKey)
+			if err != nil {
+				return nil, fmt.Errorf("key pair %d: %w", i, err)
+			}
+			c.aead, err = cipher.NewGCM(block)
+			if err != nil {
+				return nil, fmt.Errorf("key pair %d: %w", i, err)
+			}
+		}
+		p.codecs = append(p.codecs, c)
+	}
+	return p, nil
+}
+
+// Get decodes session from cookie value. errors.NotExist is returned if the cookie is invalid or expired
+func (p *Provider) Get(ctx context.Context, id string) (session.Session, error) {
+	s, err := p.decode(id)
+	if err != nil {
+		logger.Debugf("Cannot decode session cookie: %v", err)
+		return nil, errors.NotExist
+	}
+	return s, nil
+}
+
+func (p *Provider) Create(ctx context.Context, id string, ttl time.Duration) (session.Session, error) {
+	s := &Session{
+		p: p,
+		data: data{
+			ID:        id,
+			ExpiresAt: time.Now().Add(ttl).Unix(),
+			Values:    map[string]json.RawMessage{},
+		},
+	}
+	return s, nil
+}
+
+// Delete does nothing, as sessions are kept by clients
+func (p *Provider) Delete(ctx context.Context, id string) error {
+	return nil
+}
+
+func (p *Provider) EncodeCookie(s session.Session) (string, error) {
+	cs, ok := s.(*Session)
+	if !ok {
+		return "", fmt.Errorf("cannot encode %T", s)
+	}
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return p.encode(&cs.data)
+}
+
+func (p *Provider) encode(d *data) (string, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return "", fmt.Errorf("marshal: %w", err)
+	}
+	c := p.codecs[0]
+	if c.aead != nil {
+		nonce := make([]byte, c.aead.NonceSize())
+		if _, err = rand.Read(nonce); err != nil {
+			return "", fmt.Errorf("generate nonce: %w", err)
+		}
+		b = c.aead.Seal(nonce, nonce, b, nil)
+	}
+	body := base64.RawURLEncoding.EncodeToString(b)
+	v := body + "." + base64.RawURLEncoding.EncodeToString(c.sign(body))
+	if len(v) > MaxSize {
+		return "", fmt.Errorf("%w: %d bytes exceeds the limit of %d bytes", ErrTooLarge, len(v), MaxSize)
+	}
+	return v, nil
+}
+
+func (p *Provider) decode(v string) (*Session, error) {
+	i := strings.LastIndexByte(v, '.')
+	if i < 0 {
+		return nil, ErrInvalidSignature
+	}
+	body := v[:i]
+	mac, err := base64.RawURLEncoding.DecodeString(v[i+1:])
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	for _, c := range p.codecs {
+		if !hmac.Equal(mac, c.sign(body)) {
+			continue
+		}
+		b, err := base64.RawURLEncoding.DecodeString(body)
+		if err != nil {
+			return nil, fmt.Errorf("decode base64: %w", err)
+		}
+		if c.aead != nil {
+			n := c.aead.NonceSize()
+			if len(b) < n {
+				return nil, errors.New("invalid ciphertext")
+			}
+			b, err = c.aead.Open(nil, b[:n], b[n:], nil)
+			if err != nil {
+				return nil, fmt.Errorf("decrypt: %w", err)
+			}
+		}
+		s := &Session{p: p}
+		if err = json.Unmarshal(b, &s.data); err != nil {
+			return nil, fmt.Errorf("unmarshal: %w", err)
+		}
+		if time.Now().Unix() >= s.data.ExpiresAt {
+			return nil, ErrExpired
+		}
+		if s.data.Values == nil {
+			s.data.Values = map[string]json.RawMessage{}
+		}
+		return s, nil
+	}
+	return nil, ErrInvalidSignature
+}
+
+func (c *codec) sign(body string) []byte {
+	h := hmac.New(sha256.New, c.hashKey)
+	h.Write([]byte(macPrefix))
+	h.Write([]byte(body))
+	return h.Sum(nil)
+}
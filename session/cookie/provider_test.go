@@ -0,0 +1,163 @@
+package cookie_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/session"
+	"github.com/gopub/wine/session/cookie"
+	"github.com/gopub/wine/session/sessiontest"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oldKeys = cookie.KeyPair{
+		HashKey:  []byte("01234567890123456789012345678901"),
+		BlockKey: []byte("0123456789012345"),
+	}
+	newKeys = cookie.KeyPair{
+		HashKey:  []byte("abcdefghijabcdefghijabcdefghijab"),
+		BlockKey: []byte("abcdefghijabcdef"),
+	}
+)
+
+func newServer(t *testing.T, keys ...cookie.KeyPair) string {
+	p, err := cookie.NewProvider(keys...)
+	require.NoError(t, err)
+	s := wine.NewTestServer(t)
+	r := s.Use(session.NewHandler(p, nil))
+	r.Post("login", func(ctx context.Context, req *wine.Request) wine.Responder {
+		if err := session.Get(ctx).Set(ctx, "user", req.Params().String("user")); err != nil {
+			return wine.Error(err)
+		}
+		return wine.OK
+	})
+	r.Get("me", func(ctx context.Context, req *wine.Request) wine.Responder {
+		var user string
+		if err := session.Get(ctx).Get(ctx, "user", &user); err != nil {
+			return wine.Error(err)
+		}
+		return wine.Text(http.StatusOK, user)
+	})
+	return s.Run()
+}
+
+func get(t *testing.T, client *http.Client, u string) (int, string) {
+	resp, err := client.Get(u)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(b)
+}
+
+func TestProvider(t *testing.T) {
+	u := newServer(t, oldKeys)
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := &http.Client{Jar: jar}
+	resp, err := client.PostForm(u+"/login", url.Values{"user": {"tom"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	status, body := get(t, client, u+"/me")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "tom", body)
+
+	pu, err := url.Parse(u)
+	require.NoError(t, err)
+	cookies := jar.Cookies(pu)
+	require.Len(t, cookies, 1)
+	require.NotContains(t, cookies[0].Value, "tom")
+
+	t.Run("Tampered", func(t *testing.T) {
+		jar, err := cookiejar.New(nil)
+		require.NoError(t, err)
+		v := []byte(cookies[0].Value)
+		if v[0] == 'A' {
+			v[0] = 'B'
+		} else {
+			v[0] = 'A'
+		}
+		jar.SetCookies(pu, []*http.Cookie{{Name: cookies[0].Name, Value: string(v)}})
+		status, _ := get(t, &http.Client{Jar: jar}, u+"/me")
+		require.NotEqual(t, http.StatusOK, status)
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		ru := newServer(t, newKeys, oldKeys)
+		jar, err := cookiejar.New(nil)
+		require.NoError(t, err)
+		rpu, err := url.Parse(ru)
+		require.NoError(t, err)
+		jar.SetCookies(rpu, cookies)
+		client := &http.Client{Jar: jar}
+		status, body := get(t, client, ru+"/me")
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "tom", body)
+
+		// Re-encoded by new keys
+		nu := newServer(t, newKeys)
+		nuu, err := url.Parse(nu)
+		require.NoError(t, err)
+		jar.SetCookies(nuu, jar.Cookies(rpu))
+		status, body = get(t, client, nu+"/me")
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "tom", body)
+	})
+}
+
+func TestSession(t *testing.T) {
+	ctx := context.Background()
+	p, err := cookie.NewProvider(cookie.KeyPair{HashKey: oldKeys.HashKey})
+	require.NoError(t, err)
+	s, err := p.Create(ctx, "id", time.Minute)
+	require.NoError(t, err)
+
+	t.Run("TooLarge", func(t *testing.T) {
+		err := s.Set(ctx, "big", strings.Repeat("a", cookie.MaxSize))
+		require.True(t, errors.Is(err, cookie.ErrTooLarge))
+		require.True(t, errors.IsNotExist(s.Get(ctx, "big", new(string))))
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		require.NoError(t, s.Set(ctx, "k", 1))
+		require.NoError(t, s.SetTTL(-time.Second))
+		v, err := p.EncodeCookie(s)
+		require.NoError(t, err)
+		_, err = p.Get(ctx, v)
+		require.True(t, errors.IsNotExist(err))
+
+		require.NoError(t, s.SetTTL(time.Minute))
+		v, err = p.EncodeCookie(s)
+		require.NoError(t, err)
+		decoded, err := p.Get(ctx, v)
+		require.NoError(t, err)
+		require.Equal(t, "id", decoded.ID())
+		var k int
+		require.NoError(t, decoded.Get(ctx, "k", &k))
+		require.Equal(t, 1, k)
+	})
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		_, err := cookie.NewProvider(cookie.KeyPair{HashKey: oldKeys.HashKey, BlockKey: []byte("short")})
+		require.Error(t, err)
+		_, err = cookie.NewProvider()
+		require.Error(t, err)
+	})
+}
+
+func TestConformance(t *testing.T) {
+	p, err := cookie.NewProvider(oldKeys)
+	require.NoError(t, err)
+	sessiontest.TestProvider(t, p)
+}
@@ -0,0 +1,100 @@
+package cookie
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine/session"
+)
+
+type data struct {
+	ID        string                     `json:"id"`
+	ExpiresAt int64                      `json:"exp"`
+	Values    map[string]json.RawMessage `json:"v,omitempty"`
+}
+
+// Session keeps values in JSON. They are written to cookie by session handler after the request is handled
+type Session struct {
+	p    *Provider
+	mu   sync.RWMutex
+	data data
+}
+
+var _ session.Session = (*Session)(nil)
+
+func (s *Session) ID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data.ID
+}
+
+// Set returns ErrTooLarge if the encoded session exceeds MaxSize, and the value is not kept
+func (s *Session) Set(ctx context.Context, name string, value interface{}) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, ok := s.data.Values[name]
+	s.data.Values[name] = b
+	if _, err = s.p.encode(&s.data); err != nil {
+		if ok {
+			s.data.Values[name] = prev
+		} else {
+			delete(s.data.Values, name)
+		}
+		return fmt.Errorf("set %s: %w", name, err)
+	}
+	return nil
+}
+
+func (s *Session) Get(ctx context.Context, name string, ptrValue interface{}) error {
+	s.mu.RLock()
+	b, ok := s.data.Values[name]
+	s.mu.RUnlock()
+	if !ok {
+		return errors.NotExist
+	}
+	if err := json.Unmarshal(b, ptrValue); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+	return nil
+}
+
+func (s *Session) Delete(ctx context.Context, name string) error {
+	s.mu.Lock()
+	delete(s.data.Values, name)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Session) Clear() error {
+	s.mu.Lock()
+	s.data.Values = map[string]json.RawMessage{}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Session) Regenerate(ctx context.Context, id string) error {
+	s.mu.Lock()
+	s.data.ID = id
+	s.mu.Unlock()
+	return nil
+}
+
+// Destroy clears values. Session handler will expire the cookie
+func (s *Session) Destroy(ctx context.Context) error {
+	return s.Clear()
+}
+
+func (s *Session) SetTTL(ttl time.Duration) error {
+	s.mu.Lock()
+	s.data.ExpiresAt = time.Now().Add(ttl).Unix()
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,159 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/ctxutil"
+)
+
+const csrfTokenLen = 32
+
+type CSRFMode int
+
+const (
+	// CSRFSynchronizer keeps token in session, which requires the session handler ahead
+	CSRFSynchronizer CSRFMode = iota
+	// CSRFDoubleSubmit keeps token in cookie, which must be submitted in header or form as well
+	CSRFDoubleSubmit
+)
+
+type CSRFOptions struct {
+	Mode CSRFMode
+	// FieldName is the name of form field carrying token
+	FieldName string
+	// HeaderName is the name of header carrying token, e.g. set by javascript
+	HeaderName string
+	// CookieName is the name of cookie keeping token in double submit mode
+	CookieName   string
+	CookiePath   string
+	CookieSecure bool
+}
+
+func DefaultCSRFOptions() *CSRFOptions {
+	return &CSRFOptions{
+		Mode:       CSRFSynchronizer,
+		FieldName:  "csrf_token",
+		HeaderName: "X-CSRF-Token",
+		CookieName: "csrf_token",
+		CookiePath: "/",
+	}
+}
+
+type csrfContextKey struct{}
+
+// CSRFToken returns csrf token of the request, which is set by handler of NewCSRFHandler
+func CSRFToken(ctx context.Context) string {
+	t, _ := ctx.Value(csrfContextKey{}).(string)
+	return t
+}
+
+// NewCSRFHandler returns an interceptor which rejects state-changing requests without valid csrf tokens.
+// Token is available by CSRFToken, or csrfToken and csrfField in templates responded by wine.TemplateHTML.
+// Requests authenticated by bearer tokens are exempted, as browsers never attach them automatically,
+// unless they carry the session cookie as well. Session handler must be ahead to detect it
+func NewCSRFHandler(options *CSRFOptions) wine.HandlerFunc {
+	if options == nil {
+		options = DefaultCSRFOptions()
+	}
+	return func(ctx context.Context, req *wine.Request) wine.Responder {
+		if req.Bearer() != "" {
+			if st := getState(ctx); st == nil || !st.fromCookie {
+				return wine.Next(ctx, req)
+			}
+		}
+
+		var ses Session
+		var expected string
+		switch options.Mode {
+		case CSRFSynchronizer:
+			ses = Get(ctx)
+			if ses == nil {
+				logger.Errorf("No session for csrf token, session handler is required ahead")
+				return wine.Status(http.StatusInternalServerError)
+			}
+			if err := ses.Get(ctx, options.FieldName, &expected); err != nil && !errors.IsNotExist(err) {
+				return wine.Error(err)
+			}
+		case CSRFDoubleSubmit:
+			if c, err := req.Request().Cookie(options.CookieName); err == nil {
+				expected = c.Value
+			}
+		}
+
+		if !isSafeMethod(req.Request().Method) {
+			actual := req.Header(options.HeaderName)
+			if actual == "" {
+				// Cookies are excluded, otherwise the token cookie would submit itself
+				actual = req.GroupedParams().BodyParams.String(options.FieldName)
+			}
+			if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) != 1 {
+				return wine.Text(http.StatusForbidden, "Invalid csrf token")
+			}
+		}
+
+		token := expected
+		if token == "" {
+			var err error
+			token, err = newCSRFToken()
+			if err != nil {
+				return wine.Error(err)
+			}
+			if ses != nil {
+				if err = ses.Set(ctx, options.FieldName, token); err != nil {
+					return wine.Error(err)
+				}
+			}
+		}
+		ctx = context.WithValue(ctx, csrfContextKey{}, token)
+		ctx = ctxutil.WithTemplateFuncs(ctx, template.FuncMap{
+			"csrfToken": func() string {
+				return token
+			},
+			"csrfField": func() template.HTML {
+				return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+					template.HTMLEscapeString(options.FieldName), template.HTMLEscapeString(token)))
+			},
+		})
+		resp := wine.Next(ctx, req)
+		if options.Mode != CSRFDoubleSubmit || token == expected {
+			return resp
+		}
+
+		cookie := &http.Cookie{
+			Name:     options.CookieName,
+			Value:    token,
+			Path:     options.CookiePath,
+			Secure:   options.CookieSecure,
+			SameSite: http.SameSiteLaxMode,
+		}
+		return wine.Handle(req.Request(), http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			http.SetCookie(writer, cookie)
+			resp.Respond(ctx, writer)
+		}))
+	}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, csrfTokenLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate csrf token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
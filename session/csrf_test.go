@@ -0,0 +1,105 @@
+package session_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/session"
+	"github.com/gopub/wine/session/cookie"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRFHandler(t *testing.T) {
+	s := wine.NewTestServer(t)
+	s.AddTextTemplate("form", `<form>{{csrfField}}</form>`)
+	options := session.DefaultCSRFOptions()
+	options.Mode = session.CSRFDoubleSubmit
+	r := s.Use(session.NewCSRFHandler(options))
+	r.Get("form", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.TemplateHTML("form", nil)
+	})
+	r.Post("items", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.OK
+	})
+	u := s.Run()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := &http.Client{Jar: jar}
+	resp, err := client.Get(u + "/form")
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	m := regexp.MustCompile(`<input type="hidden" name="csrf_token" value="([^"]+)">`).FindStringSubmatch(string(b))
+	require.Len(t, m, 2, string(b))
+	token := m[1]
+
+	post := func(form url.Values, header map[string]string) int {
+		req, err := http.NewRequest(http.MethodPost, u+"/items", strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	require.Equal(t, http.StatusOK, post(url.Values{"csrf_token": {token}}, nil))
+	require.Equal(t, http.StatusOK, post(nil, map[string]string{"X-CSRF-Token": token}))
+	require.Equal(t, http.StatusForbidden, post(nil, nil))
+	require.Equal(t, http.StatusForbidden, post(url.Values{"csrf_token": {"forged"}}, nil))
+	require.Equal(t, http.StatusOK, post(nil, map[string]string{"Authorization": "Bearer token"}))
+}
+
+func TestCSRFHandler_Synchronizer(t *testing.T) {
+	p, err := cookie.NewProvider(cookie.KeyPair{HashKey: []byte("01234567890123456789012345678901")})
+	require.NoError(t, err)
+	s := wine.NewTestServer(t)
+	r := s.Use(session.NewHandler(p, nil)).Use(session.NewCSRFHandler(nil))
+	r.Get("token", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.Text(http.StatusOK, session.CSRFToken(ctx))
+	})
+	r.Post("items", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.OK
+	})
+	u := s.Run()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := &http.Client{Jar: jar}
+	resp, err := client.Get(u + "/token")
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	token := string(b)
+	require.NotEmpty(t, token)
+
+	post := func(client *http.Client, header map[string]string) int {
+		req, err := http.NewRequest(http.MethodPost, u+"/items", nil)
+		require.NoError(t, err)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	require.Equal(t, http.StatusOK, post(client, map[string]string{"X-CSRF-Token": token}))
+	require.Equal(t, http.StatusForbidden, post(client, nil))
+	require.Equal(t, http.StatusForbidden, post(client, map[string]string{"X-CSRF-Token": "forged"}))
+	// Bearer token doesn't exempt requests carrying session cookie
+	require.Equal(t, http.StatusForbidden, post(client, map[string]string{"Authorization": "Bearer token"}))
+	require.Equal(t, http.StatusOK, post(http.DefaultClient, map[string]string{"Authorization": "Bearer token"}))
+}
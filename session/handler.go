@@ -19,6 +19,8 @@ func NewHandler(provider Provider, options *Options) wine.HandlerFunc {
 	cookieIDKey := options.Name + "id"
 	headerIDKey := "X-" + strings.ToUpper(cookieIDKey[0:1]) + cookieIDKey[1:]
 
+	encoder, stateless := provider.(CookieEncoder)
+
 	return func(ctx context.Context, req *wine.Request) wine.Responder {
 		sid := req.Params().String(cookieIDKey)
 		var ses Session
@@ -28,13 +30,16 @@ func NewHandler(provider Provider, options *Options) wine.HandlerFunc {
 			if err != nil && !errors.IsNotExist(err) {
 				return wine.Error(err)
 			}
-		} else {
-			sid = uuid.NewString()
 		}
 
+		var fromCookie bool
 		if ses == nil {
-			ses, err = provider.Create(ctx, sid, options.TTL)
+			// Never accept unknown session id from clients, which leads to session fixation.
+			// For stateless providers, sid is the encoded session which cannot be decoded
+			ses, err = provider.Create(ctx, uuid.NewString(), options.TTL)
 		} else {
+			c, cerr := req.Request().Cookie(cookieIDKey)
+			fromCookie = cerr == nil && c.Value == sid
 			err = ses.SetTTL(options.TTL)
 		}
 
@@ -42,22 +47,39 @@ func NewHandler(provider Provider, options *Options) wine.HandlerFunc {
 			return wine.Error(err)
 		}
 
-		ctx = withSession(ctx, ses)
+		st := &state{
+			provider:   provider,
+			session:    ses,
+			fromCookie: fromCookie,
+		}
+		ctx = withState(ctx, st)
+		resp := wine.Next(ctx, req)
 
 		cookie := &http.Cookie{
 			Name:     cookieIDKey,
-			Value:    sid,
+			Value:    st.session.ID(),
 			Expires:  time.Now().Add(options.TTL),
 			Path:     options.CookiePath,
+			Domain:   options.CookieDomain,
+			Secure:   options.CookieSecure,
 			HttpOnly: options.CookieHttpOnly,
+			SameSite: options.CookieSameSite,
+		}
+		if st.destroyed {
+			cookie.Value = ""
+			cookie.Expires = time.Unix(0, 0)
+			cookie.MaxAge = -1
+		} else if stateless {
+			if cookie.Value, err = encoder.EncodeCookie(st.session); err != nil {
+				logger.Errorf("Cannot encode session %s: %v", st.session.ID(), err)
+				return wine.Error(err)
+			}
 		}
-
-		resp := wine.Next(ctx, req)
 
 		return wine.Handle(req.Request(), http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 			http.SetCookie(writer, cookie)
 			// Write to Header in case cookie is disabled by some browsers
-			writer.Header().Set(headerIDKey, sid)
+			writer.Header().Set(headerIDKey, cookie.Value)
 			resp.Respond(ctx, writer)
 		}))
 	}
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/gopub/errors"
+)
+
+// ErrNoSession is returned if session handler isn't ahead
+const ErrNoSession errors.String = "no session"
+
+// UserIndex is implemented by providers which index sessions by user ids, e.g. in order to log out all devices of a user
+type UserIndex interface {
+	// BindUser adds session into index of the user
+	BindUser(ctx context.Context, sid string, userID int64) error
+	// ListUserSessions returns ids of alive sessions of the user
+	ListUserSessions(ctx context.Context, userID int64) ([]string, error)
+}
+
+type contextKey int
+
+const (
+	keyState contextKey = iota + 1
+)
+
+// state is shared by handler and the request's handlers, so that handler can set cookie after session is changed
+type state struct {
+	provider  Provider
+	session   Session
+	destroyed bool
+	// fromCookie is true if session was resumed by cookie, which browsers attach automatically
+	fromCookie bool
+}
+
+func getState(ctx context.Context) *state {
+	v, _ := ctx.Value(keyState).(*state)
+	return v
+}
+
+func withState(ctx context.Context, s *state) context.Context {
+	return context.WithValue(ctx, keyState, s)
+}
+
+func Get(ctx context.Context) Session {
+	if st := getState(ctx); st != nil {
+		return st.session
+	}
+	return nil
+}
+
+// Regenerate changes id of the current session and keeps its values.
+// It should be called after login or privilege change to prevent session fixation
+func Regenerate(ctx context.Context) error {
+	st := getState(ctx)
+	if st == nil || st.destroyed {
+		return ErrNoSession
+	}
+	if err := st.session.Regenerate(ctx, uuid.NewString()); err != nil {
+		return fmt.Errorf("regenerate: %w", err)
+	}
+	return nil
+}
+
+// Destroy removes the current session and expires its cookie, e.g. on logout
+func Destroy(ctx context.Context) error {
+	st := getState(ctx)
+	if st == nil || st.destroyed {
+		return ErrNoSession
+	}
+	if err := st.session.Destroy(ctx); err != nil {
+		return fmt.Errorf("destroy: %w", err)
+	}
+	st.destroyed = true
+	return nil
+}
+
+// BindUser binds the current session to user. Provider must implement UserIndex
+func BindUser(ctx context.Context, userID int64) error {
+	st := getState(ctx)
+	if st == nil || st.destroyed {
+		return ErrNoSession
+	}
+	index, ok := st.provider.(UserIndex)
+	if !ok {
+		return fmt.Errorf("%T doesn't implement UserIndex", st.provider)
+	}
+	return index.BindUser(ctx, st.session.ID(), userID)
+}
+
+// DeleteUserSessions deletes all sessions of the user, i.e. logs out all devices
+func DeleteUserSessions(ctx context.Context, provider Provider, userID int64) error {
+	index, ok := provider.(UserIndex)
+	if !ok {
+		return fmt.Errorf("%T doesn't implement UserIndex", provider)
+	}
+	ids, err := index.ListUserSessions(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("list sessions: %w", err)
+	}
+	for _, id := range ids {
+		if err = provider.Delete(ctx, id); err != nil {
+			return fmt.Errorf("delete %s: %w", id, err)
+		}
+	}
+	if st := getState(ctx); st != nil && !st.destroyed {
+		for _, id := range ids {
+			if id == st.session.ID() {
+				st.destroyed = true
+				break
+			}
+		}
+	}
+	return nil
+}
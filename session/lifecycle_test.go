@@ -0,0 +1,80 @@
+package session_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/session"
+	"github.com/gopub/wine/session/cookie"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycle(t *testing.T) {
+	p, err := cookie.NewProvider(cookie.KeyPair{HashKey: []byte("01234567890123456789012345678901")})
+	require.NoError(t, err)
+	s := wine.NewTestServer(t)
+	r := s.Use(session.NewHandler(p, nil))
+	var ids []string
+	r.Post("login", func(ctx context.Context, req *wine.Request) wine.Responder {
+		ses := session.Get(ctx)
+		if err := ses.Set(ctx, "user", "tom"); err != nil {
+			return wine.Error(err)
+		}
+		ids = append(ids, ses.ID())
+		if err := session.Regenerate(ctx); err != nil {
+			return wine.Error(err)
+		}
+		ids = append(ids, ses.ID())
+		return wine.OK
+	})
+	r.Get("me", func(ctx context.Context, req *wine.Request) wine.Responder {
+		var user string
+		if err := session.Get(ctx).Get(ctx, "user", &user); err != nil {
+			return wine.Error(err)
+		}
+		ids = append(ids, session.Get(ctx).ID())
+		return wine.Text(http.StatusOK, user)
+	})
+	r.Post("logout", func(ctx context.Context, req *wine.Request) wine.Responder {
+		if err := session.Destroy(ctx); err != nil {
+			return wine.Error(err)
+		}
+		return wine.OK
+	})
+	u := s.Run()
+
+	var cookies []*http.Cookie
+	do := func(method, path string) *http.Response {
+		req, err := http.NewRequest(method, u+path, nil)
+		require.NoError(t, err)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		cookies = resp.Cookies()
+		return resp
+	}
+
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/login").StatusCode)
+	require.Len(t, ids, 2)
+	require.NotEqual(t, ids[0], ids[1])
+	require.Len(t, cookies, 1)
+	c := cookies[0]
+	require.True(t, c.HttpOnly)
+	require.Equal(t, http.SameSiteLaxMode, c.SameSite)
+
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/me").StatusCode)
+	require.Equal(t, ids[1], ids[2])
+
+	resp := do(http.MethodPost, "/logout")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, cookies, 1)
+	require.Empty(t, cookies[0].Value)
+	require.Less(t, cookies[0].MaxAge, 0)
+
+	require.Equal(t, session.ErrNoSession, session.Regenerate(context.Background()))
+}
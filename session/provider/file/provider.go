@@ -0,0 +1,249 @@
+// Package file provides a session provider which keeps one file per session in a local directory.
+// Sessions survive restarts, but the directory must not be shared by multiple processes
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/session"
+)
+
+var logger = wine.Logger()
+
+// DefaultCleanupInterval is the interval of sweeping expired sessions
+const DefaultCleanupInterval = time.Minute
+
+const fileExt = ".json"
+
+type record struct {
+	ID     string `json:"id"`
+	UserID int64  `json:"uid,omitempty"`
+	// ExpiresAt is in unix nanoseconds
+	ExpiresAt int64                      `json:"exp"`
+	Values    map[string]json.RawMessage `json:"values,omitempty"`
+}
+
+func (r *record) expired() bool {
+	return time.Now().UnixNano() >= r.ExpiresAt
+}
+
+type Provider struct {
+	dir string
+	// mu guards all session files
+	mu        sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ session.Provider = (*Provider)(nil)
+var _ session.UserIndex = (*Provider)(nil)
+
+func NewProvider(dir string) (*Provider, error) {
+	return NewProviderWithCleanupInterval(dir, DefaultCleanupInterval)
+}
+
+// NewProviderWithCleanupInterval creates a provider whose janitor sweeps expired sessions every interval until it's closed
+func NewProviderWithCleanupInterval(dir string, interval time.Duration) (*Provider, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("make dir: %w", err)
+	}
+	p := &Provider{
+		dir:  dir,
+		done: make(chan struct{}),
+	}
+	go p.sweep(interval)
+	return p, nil
+}
+
+// Close stops the janitor
+func (p *Provider) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+	return nil
+}
+
+func (p *Provider) Get(ctx context.Context, id string) (session.Session, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.load(id); err != nil {
+		return nil, err
+	}
+	return &Session{id: id, p: p}, nil
+}
+
+func (p *Provider) Create(ctx context.Context, id string, ttl time.Duration) (session.Session, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := &record{
+		ID:        id,
+		ExpiresAt: time.Now().Add(ttl).UnixNano(),
+	}
+	if err := p.save(r); err != nil {
+		return nil, err
+	}
+	return &Session{id: id, p: p}, nil
+}
+
+func (p *Provider) Delete(ctx context.Context, id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.remove(id)
+}
+
+func (p *Provider) BindUser(ctx context.Context, sid string, userID int64) error {
+	return p.update(sid, func(r *record) error {
+		r.UserID = userID
+		return nil
+	})
+}
+
+// ListUserSessions scans all session files
+func (p *Provider) ListUserSessions(ctx context.Context, userID int64) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var ids []string
+	err := p.walk(func(r *record) {
+		if r.UserID == userID && !r.expired() {
+			ids = append(ids, r.ID)
+		}
+	})
+	return ids, err
+}
+
+// Cleanup removes expired sessions
+func (p *Provider) Cleanup() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.walk(func(r *record) {
+		if r.expired() {
+			if err := p.remove(r.ID); err != nil {
+				logger.Errorf("Cannot remove session %s: %v", r.ID, err)
+			}
+		}
+	})
+}
+
+func (p *Provider) sweep(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := p.Cleanup(); err != nil {
+				logger.Errorf("Cannot clean up sessions: %v", err)
+			}
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *Provider) update(id string, f func(r *record) error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r, err := p.load(id)
+	if err != nil {
+		return err
+	}
+	if err = f(r); err != nil {
+		return err
+	}
+	return p.save(r)
+}
+
+// Following methods must be called with p.mu held
+
+// filename is hash of id, as id may be sent by clients
+func (p *Provider) filename(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(p.dir, hex.EncodeToString(sum[:])+fileExt)
+}
+
+func (p *Provider) load(id string) (*record, error) {
+	b, err := ioutil.ReadFile(p.filename(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NotExist
+		}
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	r := new(record)
+	if err = json.Unmarshal(b, r); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+	if r.expired() {
+		return nil, errors.NotExist
+	}
+	return r, nil
+}
+
+// save writes a temporary file and renames it, so that session files are never partially written
+func (p *Provider) save(r *record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	f, err := ioutil.TempFile(p.dir, ".tmp-")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("write: %w", err)
+	}
+	if err = os.Rename(f.Name(), p.filename(r.ID)); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("rename: %w", err)
+	}
+	return nil
+}
+
+func (p *Provider) remove(id string) error {
+	if err := os.Remove(p.filename(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (p *Provider) walk(f func(r *record)) error {
+	files, err := ioutil.ReadDir(p.dir)
+	if err != nil {
+		return fmt.Errorf("read dir: %w", err)
+	}
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), fileExt) {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(p.dir, fi.Name()))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("read file: %w", err)
+		}
+		r := new(record)
+		if err = json.Unmarshal(b, r); err != nil {
+			logger.Errorf("Cannot unmarshal session file %s: %v", fi.Name(), err)
+			continue
+		}
+		f(r)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package file_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine/session/provider/file"
+	"github.com/gopub/wine/session/sessiontest"
+	"github.com/stretchr/testify/require"
+)
+
+func newProvider(t *testing.T, interval time.Duration) (*file.Provider, string) {
+	dir, err := ioutil.TempDir("", "wine-session")
+	require.NoError(t, err)
+	p, err := file.NewProviderWithCleanupInterval(dir, interval)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		p.Close()
+		os.RemoveAll(dir)
+	})
+	return p, dir
+}
+
+func TestProvider(t *testing.T) {
+	p, _ := newProvider(t, file.DefaultCleanupInterval)
+	sessiontest.TestProvider(t, p)
+}
+
+func TestCleanup(t *testing.T) {
+	ctx := context.Background()
+	p, dir := newProvider(t, 50*time.Millisecond)
+	_, err := p.Create(ctx, "expired", 10*time.Millisecond)
+	require.NoError(t, err)
+	s, err := p.Create(ctx, "alive", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, s.Set(ctx, "k", "v"))
+	time.Sleep(200 * time.Millisecond)
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	// Survive restart
+	p2, err := file.NewProvider(dir)
+	require.NoError(t, err)
+	defer p2.Close()
+	s, err = p2.Get(ctx, "alive")
+	require.NoError(t, err)
+	var v string
+	require.NoError(t, s.Get(ctx, "k", &v))
+	require.Equal(t, "v", v)
+	_, err = p2.Get(ctx, "../alive")
+	require.True(t, errors.IsNotExist(err))
+}
@@ -0,0 +1,105 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine/session"
+)
+
+// Session reads and writes its file in every operation, so that it's shared by concurrent requests.
+// Values are kept in JSON
+type Session struct {
+	p *Provider
+
+	mu sync.RWMutex // guard id
+	id string
+}
+
+var _ session.Session = (*Session)(nil)
+
+func (s *Session) ID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.id
+}
+
+func (s *Session) Set(ctx context.Context, name string, value interface{}) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	return s.p.update(s.ID(), func(r *record) error {
+		if r.Values == nil {
+			r.Values = map[string]json.RawMessage{}
+		}
+		r.Values[name] = b
+		return nil
+	})
+}
+
+func (s *Session) Get(ctx context.Context, name string, ptrValue interface{}) error {
+	s.p.mu.Lock()
+	r, err := s.p.load(s.ID())
+	s.p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	b, ok := r.Values[name]
+	if !ok {
+		return errors.NotExist
+	}
+	if err = json.Unmarshal(b, ptrValue); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+	return nil
+}
+
+func (s *Session) Delete(ctx context.Context, name string) error {
+	return s.p.update(s.ID(), func(r *record) error {
+		delete(r.Values, name)
+		return nil
+	})
+}
+
+func (s *Session) Clear() error {
+	return s.p.update(s.ID(), func(r *record) error {
+		r.Values = nil
+		return nil
+	})
+}
+
+func (s *Session) SetTTL(ttl time.Duration) error {
+	return s.p.update(s.ID(), func(r *record) error {
+		r.ExpiresAt = time.Now().Add(ttl).UnixNano()
+		return nil
+	})
+}
+
+func (s *Session) Regenerate(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+	r, err := s.p.load(s.id)
+	if err != nil {
+		return err
+	}
+	r.ID = id
+	if err = s.p.save(r); err != nil {
+		return err
+	}
+	if err = s.p.remove(s.id); err != nil {
+		return err
+	}
+	s.id = id
+	return nil
+}
+
+func (s *Session) Destroy(ctx context.Context) error {
+	return s.p.Delete(ctx, s.ID())
+}
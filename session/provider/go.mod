@@ -6,7 +6,16 @@ require (
 	github.com/go-redis/redis v6.15.9+incompatible
 	github.com/gopub/errors v0.1.7
 	github.com/gopub/wine v1.40.3
+	github.com/mattn/go-sqlite3 v1.14.6
 	github.com/onsi/ginkgo v1.15.0 // indirect
 	github.com/onsi/gomega v1.10.5 // indirect
 	github.com/patrickmn/go-cache v2.1.0+incompatible
+	github.com/stretchr/testify v1.6.1
+)
+
+replace (
+	github.com/gopub/wine => ../../
+	github.com/gopub/wine/httpvalue => ../../httpvalue
+	github.com/gopub/wine/router => ../../router
+	github.com/gopub/wine/urlutil => ../../urlutil
 )
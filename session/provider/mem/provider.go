@@ -2,6 +2,7 @@ package mem
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/gopub/errors"
@@ -9,18 +10,41 @@ import (
 	"github.com/patrickmn/go-cache"
 )
 
+// DefaultCleanupInterval is the interval of sweeping expired sessions
+const DefaultCleanupInterval = time.Minute
+
 type Provider struct {
 	cache *cache.Cache
+
+	mu        sync.RWMutex // guard users, onExpired
+	users     map[int64]map[string]struct{}
+	onExpired func(s *Session)
 }
 
 var _ session.Provider = (*Provider)(nil)
+var _ session.UserIndex = (*Provider)(nil)
 
 func NewProvider() *Provider {
+	return NewProviderWithCleanupInterval(DefaultCleanupInterval)
+}
+
+// NewProviderWithCleanupInterval creates a provider whose janitor sweeps expired sessions every interval
+func NewProviderWithCleanupInterval(interval time.Duration) *Provider {
 	p := new(Provider)
-	p.cache = cache.New(session.DefaultOptions().TTL, session.DefaultOptions().TTL*50)
+	p.cache = cache.New(session.DefaultOptions().TTL, interval)
+	p.cache.OnEvicted(p.evicted)
+	p.users = make(map[int64]map[string]struct{})
 	return p
 }
 
+// OnExpired sets callback which is called after an expired session is swept.
+// It isn't called for sessions which are deleted, destroyed or regenerated
+func (p *Provider) OnExpired(f func(s *Session)) {
+	p.mu.Lock()
+	p.onExpired = f
+	p.mu.Unlock()
+}
+
 func (p *Provider) Get(ctx context.Context, id string) (session.Session, error) {
 	v, ok := p.cache.Get(id)
 	if !ok {
@@ -31,14 +55,88 @@ func (p *Provider) Get(ctx context.Context, id string) (session.Session, error)
 
 func (p *Provider) Create(ctx context.Context, id string, ttl time.Duration) (session.Session, error) {
 	s := &Session{
-		id:          id,
-		sharedCache: p.cache,
+		id: id,
+		p:  p,
 	}
 	p.cache.Set(id, s, ttl)
 	return s, nil
 }
 
 func (p *Provider) Delete(ctx context.Context, id string) error {
+	if v, ok := p.cache.Get(id); ok {
+		v.(*Session).markDeleted()
+	}
 	p.cache.Delete(id)
 	return nil
 }
+
+func (p *Provider) BindUser(ctx context.Context, sid string, userID int64) error {
+	v, ok := p.cache.Get(sid)
+	if !ok {
+		return errors.NotExist
+	}
+	s := v.(*Session)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.uid != 0 {
+		p.unbind(s.uid, s.id)
+	}
+	s.uid = userID
+	p.bind(userID, s.id)
+	return nil
+}
+
+func (p *Provider) ListUserSessions(ctx context.Context, userID int64) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var ids []string
+	for id := range p.users[userID] {
+		if _, ok := p.cache.Get(id); ok {
+			ids = append(ids, id)
+		} else {
+			p.unbind(userID, id)
+		}
+	}
+	return ids, nil
+}
+
+func (p *Provider) evicted(id string, v interface{}) {
+	s := v.(*Session)
+	s.mu.RLock()
+	sid, uid, deleted := s.id, s.uid, s.deleted
+	s.mu.RUnlock()
+	if sid != id {
+		// regenerated
+		return
+	}
+
+	p.mu.Lock()
+	if uid != 0 {
+		p.unbind(uid, sid)
+	}
+	f := p.onExpired
+	p.mu.Unlock()
+	if f != nil && !deleted {
+		f(s)
+	}
+}
+
+// bind and unbind must be called with p.mu held
+func (p *Provider) bind(userID int64, sid string) {
+	m, ok := p.users[userID]
+	if !ok {
+		m = make(map[string]struct{})
+		p.users[userID] = m
+	}
+	m[sid] = struct{}{}
+}
+
+func (p *Provider) unbind(userID int64, sid string) {
+	m := p.users[userID]
+	delete(m, sid)
+	if len(m) == 0 {
+		delete(p.users, userID)
+	}
+}
@@ -0,0 +1,90 @@
+package mem_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine/session"
+	"github.com/gopub/wine/session/provider/mem"
+	"github.com/gopub/wine/session/sessiontest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Expire", func(t *testing.T) {
+		p := mem.NewProviderWithCleanupInterval(10 * time.Millisecond)
+		expired := make(chan string, 2)
+		p.OnExpired(func(s *mem.Session) {
+			expired <- s.ID()
+		})
+		_, err := p.Create(ctx, "a", 20*time.Millisecond)
+		require.NoError(t, err)
+		_, err = p.Create(ctx, "b", 20*time.Millisecond)
+		require.NoError(t, err)
+		require.NoError(t, p.Delete(ctx, "b"))
+		select {
+		case id := <-expired:
+			require.Equal(t, "a", id)
+		case <-time.After(time.Second):
+			t.Fatal("session isn't swept")
+		}
+		_, err = p.Get(ctx, "a")
+		require.True(t, errors.IsNotExist(err))
+		select {
+		case id := <-expired:
+			t.Fatalf("deleted session %s is reported as expired", id)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("Regenerate", func(t *testing.T) {
+		p := mem.NewProvider()
+		s, err := p.Create(ctx, "a", time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, s.Set(ctx, "k", "v"))
+		require.NoError(t, s.Regenerate(ctx, "b"))
+		require.Equal(t, "b", s.ID())
+		_, err = p.Get(ctx, "a")
+		require.True(t, errors.IsNotExist(err))
+		s, err = p.Get(ctx, "b")
+		require.NoError(t, err)
+		var v string
+		require.NoError(t, s.Get(ctx, "k", &v))
+		require.Equal(t, "v", v)
+		require.NoError(t, s.Destroy(ctx))
+		_, err = p.Get(ctx, "b")
+		require.True(t, errors.IsNotExist(err))
+	})
+
+	t.Run("UserIndex", func(t *testing.T) {
+		p := mem.NewProvider()
+		for _, id := range []string{"a", "b", "c"} {
+			_, err := p.Create(ctx, id, time.Minute)
+			require.NoError(t, err)
+		}
+		require.NoError(t, p.BindUser(ctx, "a", 1))
+		require.NoError(t, p.BindUser(ctx, "b", 1))
+		require.NoError(t, p.BindUser(ctx, "c", 2))
+		s, err := p.Get(ctx, "b")
+		require.NoError(t, err)
+		require.NoError(t, s.Regenerate(ctx, "d"))
+		ids, err := p.ListUserSessions(ctx, 1)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a", "d"}, ids)
+
+		require.NoError(t, session.DeleteUserSessions(ctx, p, 1))
+		ids, err = p.ListUserSessions(ctx, 1)
+		require.NoError(t, err)
+		require.Empty(t, ids)
+		_, err = p.Get(ctx, "c")
+		require.NoError(t, err)
+	})
+}
+
+func TestConformance(t *testing.T) {
+	sessiontest.TestProvider(t, mem.NewProvider())
+}
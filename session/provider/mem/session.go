@@ -13,21 +13,35 @@ import (
 )
 
 type Session struct {
-	id   string
 	data sync.Map
+	p    *Provider
 
-	sharedCache *cache.Cache
+	mu      sync.RWMutex // guard id, uid, deleted
+	id      string
+	uid     int64
+	deleted bool
 }
 
 func (m *Session) SetTTL(ttl time.Duration) error {
-	m.sharedCache.Set(m.id, m, ttl)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.p.cache.Set(m.id, m, ttl)
 	return nil
 }
 
 func (m *Session) ID() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return m.id
 }
 
+// UserID returns id of the user bound by session.BindUser
+func (m *Session) UserID() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.uid
+}
+
 func (m *Session) Set(ctx context.Context, name string, value interface{}) error {
 	m.data.Store(name, value)
 	return nil
@@ -56,7 +70,7 @@ func (m *Session) Get(ctx context.Context, name string, ptrValue interface{}) er
 		return fmt.Errorf("cannot assign %T to %T", v, ptrValue)
 	}
 
-	pv.Set(reflect.ValueOf(v))
+	pv.Elem().Set(reflect.ValueOf(v))
 	return nil
 }
 
@@ -70,4 +84,44 @@ func (m *Session) Clear() error {
 	return nil
 }
 
+func (m *Session) Regenerate(ctx context.Context, id string) error {
+	m.mu.RLock()
+	old := m.id
+	m.mu.RUnlock()
+	_, expiresAt, ok := m.p.cache.GetWithExpiration(old)
+	if !ok {
+		return errors.NotExist
+	}
+	ttl := cache.NoExpiration
+	if !expiresAt.IsZero() {
+		ttl = time.Until(expiresAt)
+	}
+
+	m.p.mu.Lock()
+	m.mu.Lock()
+	m.id = id
+	if m.uid != 0 {
+		m.p.unbind(m.uid, old)
+		m.p.bind(m.uid, id)
+	}
+	m.mu.Unlock()
+	m.p.mu.Unlock()
+
+	m.p.cache.Set(id, m, ttl)
+	m.p.cache.Delete(old)
+	return nil
+}
+
+func (m *Session) Destroy(ctx context.Context) error {
+	m.markDeleted()
+	m.p.cache.Delete(m.ID())
+	return nil
+}
+
+func (m *Session) markDeleted() {
+	m.mu.Lock()
+	m.deleted = true
+	m.mu.Unlock()
+}
+
 var _ session.Session = (*Session)(nil)
@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/go-redis/redis"
@@ -13,7 +14,15 @@ type Provider struct {
 	c *redis.Client
 }
 
+const (
+	// placeholderField makes hash of a new session exist before any value is set
+	placeholderField = "@"
+	// userIDField keeps id of the user bound to session
+	userIDField = "@uid"
+)
+
 var _ session.Provider = (*Provider)(nil)
+var _ session.UserIndex = (*Provider)(nil)
 
 func NewProvider(c *redis.Client) *Provider {
 	p := new(Provider)
@@ -38,7 +47,7 @@ func (p *Provider) Get(ctx context.Context, id string) (session.Session, error)
 }
 
 func (p *Provider) Create(ctx context.Context, id string, ttl time.Duration) (session.Session, error) {
-	err := p.c.WithContext(ctx).HSet(id, "@", 0).Err()
+	err := p.c.WithContext(ctx).HSet(id, placeholderField, 0).Err()
 	if err != nil {
 		return nil, err
 	}
@@ -55,3 +64,38 @@ func (p *Provider) Create(ctx context.Context, id string, ttl time.Duration) (se
 func (p *Provider) Delete(ctx context.Context, id string) error {
 	return p.c.WithContext(ctx).Del(id).Err()
 }
+
+func (p *Provider) BindUser(ctx context.Context, sid string, userID int64) error {
+	c := p.c.WithContext(ctx)
+	if err := c.HSet(sid, userIDField, userID).Err(); err != nil {
+		return err
+	}
+	return c.SAdd(userKey(userID), sid).Err()
+}
+
+// ListUserSessions returns alive sessions of the user and removes expired ones from the index
+func (p *Provider) ListUserSessions(ctx context.Context, userID int64) ([]string, error) {
+	c := p.c.WithContext(ctx)
+	key := userKey(userID)
+	members, err := c.SMembers(key).Result()
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, id := range members {
+		n, err := c.Exists(id).Result()
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			ids = append(ids, id)
+		} else if err = c.SRem(key, id).Err(); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+func userKey(userID int64) string {
+	return fmt.Sprintf("wine.session.user.%d", userID)
+}
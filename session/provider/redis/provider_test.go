@@ -0,0 +1,20 @@
+package redis_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/go-redis/redis"
+	redisprovider "github.com/gopub/wine/session/provider/redis"
+	"github.com/gopub/wine/session/sessiontest"
+)
+
+func TestProvider(t *testing.T) {
+	addr := os.Getenv("WINE_TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("WINE_TEST_REDIS_ADDR is not set")
+	}
+	c := redis.NewClient(&redis.Options{Addr: addr})
+	defer c.Close()
+	sessiontest.TestProvider(t, redisprovider.NewProvider(c))
+}
@@ -5,9 +5,24 @@ import (
 	"time"
 
 	"github.com/go-redis/redis"
+	"github.com/gopub/errors"
 	"github.com/gopub/wine/session"
 )
 
+// clearScript deletes fields of hash KEYS[1] except ARGV, so that the key and its ttl are kept
+var clearScript = redis.NewScript(`
+local kept = {}
+for _, f in ipairs(ARGV) do
+	kept[f] = true
+end
+for _, f in ipairs(redis.call('HKEYS', KEYS[1])) do
+	if not kept[f] then
+		redis.call('HDEL', KEYS[1], f)
+	end
+end
+return 0
+`)
+
 type Session struct {
 	id string
 	c  *redis.Client
@@ -22,19 +37,44 @@ func (s *Session) Set(ctx context.Context, name string, value interface{}) error
 }
 
 func (s *Session) Get(ctx context.Context, name string, ptrValue interface{}) error {
-	return s.c.WithContext(ctx).HGet(s.id, name).Scan(ptrValue)
+	err := s.c.WithContext(ctx).HGet(s.id, name).Scan(ptrValue)
+	if err == redis.Nil {
+		return errors.NotExist
+	}
+	return err
 }
 
 func (s *Session) Delete(ctx context.Context, name string) error {
 	return s.c.WithContext(ctx).HDel(s.id, name).Err()
 }
 
+// Clear deletes values but keeps the session alive and bound to its user
 func (s *Session) Clear() error {
-	return s.c.Del(s.id).Err()
+	return clearScript.Run(s.c, []string{s.id}, placeholderField, userIDField).Err()
 }
 
 func (s *Session) SetTTL(ttl time.Duration) error {
 	return s.c.Expire(s.id, ttl).Err()
 }
 
+func (s *Session) Regenerate(ctx context.Context, id string) error {
+	c := s.c.WithContext(ctx)
+	if err := c.Rename(s.id, id).Err(); err != nil {
+		return err
+	}
+	s.id = id
+	uid, err := c.HGet(id, userIDField).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return nil
+		}
+		return err
+	}
+	return c.SAdd(userKey(uid), id).Err()
+}
+
+func (s *Session) Destroy(ctx context.Context) error {
+	return s.c.WithContext(ctx).Del(s.id).Err()
+}
+
 var _ session.Session = (*Session)(nil)
@@ -0,0 +1,197 @@
+// Package sql provides a session provider which keeps sessions in a table of database/sql.
+// Queries use ? as placeholder, e.g. SQLite and MySQL
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine"
+	"github.com/gopub/wine/session"
+)
+
+var logger = wine.Logger()
+
+const (
+	// DefaultTable is the name of session table
+	DefaultTable = "wine_session"
+	// DefaultCleanupInterval is the interval of deleting expired sessions
+	DefaultCleanupInterval = time.Minute
+)
+
+// Table has columns: id, user_id, data in JSON, and expires_at in unix nanoseconds.
+// Indexes on user_id and expires_at are recommended
+const createTableSQL = `CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(64) PRIMARY KEY,
+	user_id BIGINT NOT NULL DEFAULT 0,
+	data TEXT NOT NULL,
+	expires_at BIGINT NOT NULL
+)`
+
+type Provider struct {
+	db        *sql.DB
+	table     string
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ session.Provider = (*Provider)(nil)
+var _ session.UserIndex = (*Provider)(nil)
+
+// NewProvider creates a provider with table which is created if not exist. DefaultTable is used if table is empty
+func NewProvider(db *sql.DB, table string) (*Provider, error) {
+	return NewProviderWithCleanupInterval(db, table, DefaultCleanupInterval)
+}
+
+// NewProviderWithCleanupInterval creates a provider whose janitor deletes expired sessions every interval until it's closed
+func NewProviderWithCleanupInterval(db *sql.DB, table string, interval time.Duration) (*Provider, error) {
+	if table == "" {
+		table = DefaultTable
+	}
+	if _, err := db.Exec(fmt.Sprintf(createTableSQL, table)); err != nil {
+		return nil, fmt.Errorf("create table: %w", err)
+	}
+	p := &Provider{
+		db:    db,
+		table: table,
+		done:  make(chan struct{}),
+	}
+	go p.sweep(interval)
+	return p, nil
+}
+
+// Close stops the janitor. db isn't closed
+func (p *Provider) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+	return nil
+}
+
+func (p *Provider) Get(ctx context.Context, id string) (session.Session, error) {
+	var n int
+	err := p.db.QueryRowContext(ctx, p.query("SELECT COUNT(*) FROM %s WHERE id=? AND expires_at>?"),
+		id, now()).Scan(&n)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, errors.NotExist
+	}
+	return &Session{id: id, p: p}, nil
+}
+
+func (p *Provider) Create(ctx context.Context, id string, ttl time.Duration) (session.Session, error) {
+	_, err := p.db.ExecContext(ctx, p.query("INSERT INTO %s(id, data, expires_at) VALUES(?,?,?)"),
+		id, "{}", time.Now().Add(ttl).UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	return &Session{id: id, p: p}, nil
+}
+
+func (p *Provider) Delete(ctx context.Context, id string) error {
+	_, err := p.db.ExecContext(ctx, p.query("DELETE FROM %s WHERE id=?"), id)
+	return err
+}
+
+func (p *Provider) BindUser(ctx context.Context, sid string, userID int64) error {
+	if _, err := p.Get(ctx, sid); err != nil {
+		return err
+	}
+	_, err := p.db.ExecContext(ctx, p.query("UPDATE %s SET user_id=? WHERE id=?"), userID, sid)
+	return err
+}
+
+func (p *Provider) ListUserSessions(ctx context.Context, userID int64) ([]string, error) {
+	rows, err := p.db.QueryContext(ctx, p.query("SELECT id FROM %s WHERE user_id=? AND expires_at>?"), userID, now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Cleanup deletes expired sessions
+func (p *Provider) Cleanup(ctx context.Context) error {
+	_, err := p.db.ExecContext(ctx, p.query("DELETE FROM %s WHERE expires_at<=?"), now())
+	return err
+}
+
+func (p *Provider) sweep(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := p.Cleanup(context.Background()); err != nil {
+				logger.Errorf("Cannot clean up sessions: %v", err)
+			}
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *Provider) query(format string) string {
+	return fmt.Sprintf(format, p.table)
+}
+
+// update reads values, changes them by f and writes them back in a transaction
+func (p *Provider) update(ctx context.Context, id string, f func(values map[string]json.RawMessage)) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	values, err := p.load(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	f(values)
+	b, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, p.query("UPDATE %s SET data=? WHERE id=?"), string(b), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (p *Provider) load(ctx context.Context, q queryer, id string) (map[string]json.RawMessage, error) {
+	var data string
+	err := q.QueryRowContext(ctx, p.query("SELECT data FROM %s WHERE id=? AND expires_at>?"), id, now()).Scan(&data)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NotExist
+		}
+		return nil, err
+	}
+	values := map[string]json.RawMessage{}
+	if err = json.Unmarshal([]byte(data), &values); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+	return values, nil
+}
+
+func now() int64 {
+	return time.Now().UnixNano()
+}
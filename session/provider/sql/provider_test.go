@@ -0,0 +1,61 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gopub/errors"
+	sqlprovider "github.com/gopub/wine/session/provider/sql"
+	"github.com/gopub/wine/session/sessiontest"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func newProvider(t *testing.T, interval time.Duration) (*sqlprovider.Provider, *sql.DB) {
+	dir, err := ioutil.TempDir("", "wine-session")
+	require.NoError(t, err)
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "session.db"))
+	require.NoError(t, err)
+	// Avoid database is locked
+	db.SetMaxOpenConns(1)
+	p, err := sqlprovider.NewProviderWithCleanupInterval(db, "", interval)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		p.Close()
+		db.Close()
+		os.RemoveAll(dir)
+	})
+	return p, db
+}
+
+func TestProvider(t *testing.T) {
+	p, _ := newProvider(t, sqlprovider.DefaultCleanupInterval)
+	sessiontest.TestProvider(t, p)
+}
+
+func TestCleanup(t *testing.T) {
+	ctx := context.Background()
+	p, db := newProvider(t, 50*time.Millisecond)
+	_, err := p.Create(ctx, "expired", 10*time.Millisecond)
+	require.NoError(t, err)
+	_, err = p.Create(ctx, "alive", time.Minute)
+	require.NoError(t, err)
+	time.Sleep(200 * time.Millisecond)
+	_, err = p.Get(ctx, "expired")
+	require.True(t, errors.IsNotExist(err))
+	var ids []string
+	rows, err := db.Query("SELECT id FROM " + sqlprovider.DefaultTable)
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.Equal(t, []string{"alive"}, ids)
+}
@@ -0,0 +1,87 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine/session"
+)
+
+// Session reads and writes its row in every operation. Values are kept in JSON
+type Session struct {
+	p *Provider
+
+	mu sync.RWMutex // guard id
+	id string
+}
+
+var _ session.Session = (*Session)(nil)
+
+func (s *Session) ID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.id
+}
+
+func (s *Session) Set(ctx context.Context, name string, value interface{}) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	return s.p.update(ctx, s.ID(), func(values map[string]json.RawMessage) {
+		values[name] = b
+	})
+}
+
+func (s *Session) Get(ctx context.Context, name string, ptrValue interface{}) error {
+	values, err := s.p.load(ctx, s.p.db, s.ID())
+	if err != nil {
+		return err
+	}
+	b, ok := values[name]
+	if !ok {
+		return errors.NotExist
+	}
+	if err = json.Unmarshal(b, ptrValue); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+	return nil
+}
+
+func (s *Session) Delete(ctx context.Context, name string) error {
+	return s.p.update(ctx, s.ID(), func(values map[string]json.RawMessage) {
+		delete(values, name)
+	})
+}
+
+func (s *Session) Clear() error {
+	_, err := s.p.db.Exec(s.p.query("UPDATE %s SET data=? WHERE id=?"), "{}", s.ID())
+	return err
+}
+
+func (s *Session) SetTTL(ttl time.Duration) error {
+	_, err := s.p.db.Exec(s.p.query("UPDATE %s SET expires_at=? WHERE id=?"), time.Now().Add(ttl).UnixNano(), s.ID())
+	return err
+}
+
+func (s *Session) Regenerate(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res, err := s.p.db.ExecContext(ctx, s.p.query("UPDATE %s SET id=? WHERE id=? AND expires_at>?"), id, s.id, now())
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.NotExist
+	}
+	s.id = id
+	return nil
+}
+
+func (s *Session) Destroy(ctx context.Context) error {
+	return s.p.Delete(ctx, s.ID())
+}
@@ -2,17 +2,25 @@ package session
 
 import (
 	"context"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gopub/environ"
+	"github.com/gopub/wine"
 )
 
+var logger = wine.Logger()
+
 type Options struct {
 	Name           string        `json:"name,omitempty"`
 	TTL            time.Duration `json:"ttl,omitempty"`
 	CookiePath     string        `json:"cookie_path,omitempty"`
+	CookieDomain   string        `json:"cookie_domain,omitempty"`
 	CookieHttpOnly bool          `json:"cookie_http_only,omitempty"`
+	CookieSecure   bool          `json:"cookie_secure,omitempty"`
+	// CookieSameSite is http.SameSiteLaxMode by default
+	CookieSameSite http.SameSite `json:"cookie_same_site,omitempty"`
 }
 
 var defaultOptions *Options
@@ -26,6 +34,9 @@ func DefaultOptions() *Options {
 		TTL:            environ.Duration("wine.session.ttl", 30*time.Minute),
 		CookieHttpOnly: true,
 		CookiePath:     "/",
+		CookieDomain:   environ.String("wine.session.cookie_domain", ""),
+		CookieSecure:   environ.Bool("wine.session.cookie_secure", false),
+		CookieSameSite: http.SameSiteLaxMode,
 	}
 	o.Name = strings.ToLower(strings.TrimSpace(o.Name))
 	if o.Name == "" {
@@ -46,21 +57,10 @@ type Session interface {
 	Delete(ctx context.Context, name string) error
 	Clear() error
 	SetTTL(ttl time.Duration) error
-}
-
-type contextKey int
-
-const (
-	keySession contextKey = iota + 1
-)
-
-func Get(ctx context.Context) Session {
-	v, _ := ctx.Value(keySession).(Session)
-	return v
-}
-
-func withSession(ctx context.Context, s Session) context.Context {
-	return context.WithValue(ctx, keySession, s)
+	// Regenerate changes session id and keeps values
+	Regenerate(ctx context.Context, id string) error
+	// Destroy removes session from provider
+	Destroy(ctx context.Context) error
 }
 
 type Provider interface {
@@ -68,3 +68,9 @@ type Provider interface {
 	Create(ctx context.Context, id string, ttl time.Duration) (Session, error)
 	Delete(ctx context.Context, id string) error
 }
+
+// CookieEncoder is implemented by stateless providers which keep whole sessions in cookies.
+// Handler sets the encoded session as cookie value instead of session id, which is passed to Provider.Get in next requests
+type CookieEncoder interface {
+	EncodeCookie(s Session) (string, error)
+}
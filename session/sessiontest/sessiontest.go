@@ -0,0 +1,159 @@
+// Package sessiontest provides the conformance test suite of session providers.
+package sessiontest
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gopub/errors"
+	"github.com/gopub/wine/session"
+	"github.com/stretchr/testify/require"
+)
+
+// MinTTL is the least ttl used by the suite, as some providers expire sessions in seconds
+const MinTTL = time.Second
+
+// TestProvider runs the conformance test suite which every provider must pass.
+// For stateless providers, sessions are looked up by encoded cookies instead of ids
+func TestProvider(t *testing.T, p session.Provider) {
+	ctx := context.Background()
+	encoder, stateless := p.(session.CookieEncoder)
+
+	create := func(t *testing.T, ttl time.Duration) session.Session {
+		s, err := p.Create(ctx, uuid.NewString(), ttl)
+		require.NoError(t, err)
+		require.NotEmpty(t, s.ID())
+		return s
+	}
+
+	get := func(t *testing.T, s session.Session) (session.Session, error) {
+		id := s.ID()
+		if stateless {
+			var err error
+			id, err = encoder.EncodeCookie(s)
+			require.NoError(t, err)
+		}
+		return p.Get(ctx, id)
+	}
+
+	mustGet := func(t *testing.T, s session.Session) session.Session {
+		found, err := get(t, s)
+		require.NoError(t, err)
+		require.Equal(t, s.ID(), found.ID())
+		return found
+	}
+
+	notExist := func(t *testing.T, s session.Session) {
+		_, err := get(t, s)
+		require.Error(t, err)
+		require.True(t, errors.IsNotExist(err), err.Error())
+	}
+
+	t.Run("Values", func(t *testing.T) {
+		s := create(t, time.Minute)
+		require.NoError(t, s.Set(ctx, "name", "tom"))
+		require.NoError(t, s.Set(ctx, "age", 20))
+
+		found := mustGet(t, s)
+		var name string
+		require.NoError(t, found.Get(ctx, "name", &name))
+		require.Equal(t, "tom", name)
+		var age int
+		require.NoError(t, found.Get(ctx, "age", &age))
+		require.Equal(t, 20, age)
+		require.True(t, errors.IsNotExist(found.Get(ctx, "missing", &name)))
+
+		require.NoError(t, found.Set(ctx, "name", "jim"))
+		require.NoError(t, found.Delete(ctx, "age"))
+		found = mustGet(t, found)
+		require.NoError(t, found.Get(ctx, "name", &name))
+		require.Equal(t, "jim", name)
+		require.True(t, errors.IsNotExist(found.Get(ctx, "age", &age)))
+
+		require.NoError(t, found.Clear())
+		found = mustGet(t, found)
+		require.True(t, errors.IsNotExist(found.Get(ctx, "name", &name)))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if stateless {
+			t.Skip("stateless provider cannot delete sessions")
+		}
+		s := create(t, time.Minute)
+		require.NoError(t, p.Delete(ctx, s.ID()))
+		notExist(t, s)
+		require.NoError(t, p.Delete(ctx, s.ID()))
+	})
+
+	t.Run("Destroy", func(t *testing.T) {
+		s := create(t, time.Minute)
+		require.NoError(t, s.Set(ctx, "name", "tom"))
+		require.NoError(t, s.Destroy(ctx))
+		if stateless {
+			var name string
+			require.True(t, errors.IsNotExist(s.Get(ctx, "name", &name)))
+		} else {
+			notExist(t, s)
+		}
+	})
+
+	t.Run("Regenerate", func(t *testing.T) {
+		s := create(t, time.Minute)
+		require.NoError(t, s.Set(ctx, "name", "tom"))
+		old := s.ID()
+		id := uuid.NewString()
+		require.NoError(t, s.Regenerate(ctx, id))
+		require.Equal(t, id, s.ID())
+		if !stateless {
+			_, err := p.Get(ctx, old)
+			require.True(t, errors.IsNotExist(err))
+		}
+		var name string
+		require.NoError(t, mustGet(t, s).Get(ctx, "name", &name))
+		require.Equal(t, "tom", name)
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		expired := create(t, MinTTL)
+		extended := create(t, MinTTL)
+		require.NoError(t, extended.SetTTL(time.Minute))
+		time.Sleep(MinTTL + 200*time.Millisecond)
+		notExist(t, expired)
+		mustGet(t, extended)
+	})
+
+	t.Run("UserIndex", func(t *testing.T) {
+		index, ok := p.(session.UserIndex)
+		if !ok {
+			t.Skip("provider doesn't implement UserIndex")
+		}
+		uid := time.Now().UnixNano()
+		s1 := create(t, time.Minute)
+		s2 := create(t, time.Minute)
+		other := create(t, time.Minute)
+		require.NoError(t, index.BindUser(ctx, s1.ID(), uid))
+		require.NoError(t, index.BindUser(ctx, s2.ID(), uid))
+		require.NoError(t, index.BindUser(ctx, other.ID(), uid+1))
+
+		old := s2.ID()
+		require.NoError(t, s2.Regenerate(ctx, uuid.NewString()))
+		ids, err := index.ListUserSessions(ctx, uid)
+		require.NoError(t, err)
+		expected := []string{s1.ID(), s2.ID()}
+		sort.Strings(expected)
+		sort.Strings(ids)
+		require.Equal(t, expected, ids)
+		require.NotContains(t, ids, old)
+
+		require.NoError(t, session.DeleteUserSessions(ctx, p, uid))
+		notExist(t, s1)
+		notExist(t, s2)
+		mustGet(t, other)
+		ids, err = index.ListUserSessions(ctx, uid)
+		require.NoError(t, err)
+		require.Empty(t, ids)
+	})
+}
@@ -0,0 +1,131 @@
+package wine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/log"
+	"github.com/gopub/wine/internal/respond"
+)
+
+// ResponseSpec declares a response which an endpoint may produce
+type ResponseSpec struct {
+	Status      int
+	Model       interface{}
+	Description string
+}
+
+func (r *ResponseSpec) String() string {
+	if r.Model == nil {
+		return fmt.Sprint(r.Status)
+	}
+	return fmt.Sprintf("%d:%T", r.Status, r.Model)
+}
+
+// SetResponse declares a response with status and model. Model can be nil if response has no body, e.g. error responses
+func (e *Endpoint) SetResponse(status int, model interface{}) *Endpoint {
+	return e.SetResponseSpec(&ResponseSpec{
+		Status: status,
+		Model:  model,
+	})
+}
+
+func (e *Endpoint) SetResponseSpec(spec *ResponseSpec) *Endpoint {
+	if spec == nil || http.StatusText(spec.Status) == "" {
+		logger.Panicf("Invalid response spec: %v", spec)
+	}
+	md := e.metadata()
+	if md.Responses == nil {
+		md.Responses = make(map[int]*ResponseSpec)
+	}
+	md.Responses[spec.Status] = spec
+	return e
+}
+
+// Responses returns declared responses sorted by status
+func (e *Endpoint) Responses() []*ResponseSpec {
+	return e.metadata().sortedResponses()
+}
+
+// SetModel is similar with router.Endpoint.SetModel, returns e for chaining
+func (e *Endpoint) SetModel(m interface{}) *Endpoint {
+	e.Endpoint.SetModel(m)
+	return e
+}
+
+// SetDescription is similar with router.Endpoint.SetDescription, returns e for chaining
+func (e *Endpoint) SetDescription(s string) *Endpoint {
+	e.Endpoint.SetDescription(s)
+	return e
+}
+
+func (m *metadata) sortedResponses() []*ResponseSpec {
+	l := make([]*ResponseSpec, 0, len(m.Responses))
+	for _, r := range m.Responses {
+		l = append(l, r)
+	}
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].Status < l[j].Status
+	})
+	return l
+}
+
+func (m *metadata) responsesString() string {
+	l := m.sortedResponses()
+	a := make([]string, len(l))
+	for i, r := range l {
+		a[i] = r.String()
+	}
+	return strings.Join(a, ", ")
+}
+
+// validateResponse logs violations if resp doesn't match declared responses
+func (e *Endpoint) validateResponse(ctx context.Context, resp Responder) {
+	md := e.metadata()
+	if len(md.Responses) == 0 {
+		return
+	}
+
+	var status int
+	var value interface{}
+	switch v := resp.(type) {
+	case *respond.Response:
+		status, value = v.Status(), v.Value()
+	case *errors.Error:
+		status = errors.GetCode(v)
+	default:
+		// Unable to inspect other responders, e.g. files, raw handlers
+		return
+	}
+
+	logger := log.FromContext(ctx)
+	spec := md.Responses[status]
+	if spec == nil {
+		logger.Errorf("Undeclared response status %d of %s /%s, declared: %s", status, e.Scope, e.Path(), md.responsesString())
+		return
+	}
+	if spec.Model == nil || value == nil || status >= http.StatusBadRequest {
+		return
+	}
+	if !isCompatibleType(reflect.TypeOf(value), reflect.TypeOf(spec.Model)) {
+		logger.Errorf("Response of %s /%s is %T instead of declared %T", e.Scope, e.Path(), value, spec.Model)
+	}
+}
+
+func isCompatibleType(t, expected reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for expected.Kind() == reflect.Ptr {
+		expected = expected.Elem()
+	}
+	if expected.Kind() == reflect.Interface {
+		return t.Implements(expected)
+	}
+	return t == expected
+}
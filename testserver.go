@@ -0,0 +1,152 @@
+package wine
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+	"time"
+)
+
+// TestServer runs on an ephemeral port of loopback interface for testing
+type TestServer struct {
+	*Server
+	URL string
+
+	certPool *x509.CertPool
+}
+
+func NewTestServer(t *testing.T) *TestServer {
+	s := NewServer(nil)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	})
+	return &TestServer{
+		Server: s,
+	}
+}
+
+// Run starts server on an ephemeral port of loopback interface and returns its url.
+// The listener is bound before Run returns, so requests can be sent right away
+func (s *TestServer) Run() string {
+	l := s.listen()
+	// Create http server synchronously in case of shutting down before serving
+	srv := s.newHTTPServer(l, false)
+	go srv.Serve(l)
+	s.URL = "http://" + l.Addr().String()
+	return s.URL
+}
+
+// RunTLS is similar with Run, but serves https with a certificate issued by an in-memory CA.
+// Use Client or CertPool to trust the CA
+func (s *TestServer) RunTLS() string {
+	ca, cert, err := newTestCertificate()
+	if err != nil {
+		logger.Panicf("Create test certificate: %v", err)
+	}
+	s.certPool = x509.NewCertPool()
+	s.certPool.AddCert(ca)
+
+	l := s.listen()
+	srv := s.newHTTPServer(l, true)
+	srv.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+	go srv.ServeTLS(l, "", "")
+	s.URL = "https://" + l.Addr().String()
+	return s.URL
+}
+
+// CertPool returns the pool containing CA of RunTLS, or nil if server isn't running with tls
+func (s *TestServer) CertPool() *x509.CertPool {
+	return s.certPool
+}
+
+// Client returns a client with cookie jar, which trusts CA of RunTLS
+func (s *TestServer) Client() *Client {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		logger.Panicf("Create cookie jar: %v", err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs: s.certPool,
+	}
+	return NewClient(&http.Client{
+		Transport: transport,
+		Jar:       jar,
+	})
+}
+
+func (s *TestServer) listen() net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		logger.Panicf("Listen: %v", err)
+	}
+	return l
+}
+
+// newTestCertificate creates a CA and a certificate for localhost issued by the CA
+func newTestCertificate() (*x509.Certificate, tls.Certificate, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, tls.Certificate{}, fmt.Errorf("generate ca key: %w", err)
+	}
+	now := time.Now()
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(now.UnixNano()),
+		Subject:               pkix.Name{Organization: []string{"Wine Test CA"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, tls.Certificate{}, fmt.Errorf("create ca: %w", err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, tls.Certificate{}, fmt.Errorf("parse ca: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, tls.Certificate{}, fmt.Errorf("generate key: %w", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(now.UnixNano() + 1),
+		Subject:      pkix.Name{Organization: []string{"Wine Test"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, tls.Certificate{}, fmt.Errorf("create certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, tls.Certificate{}, fmt.Errorf("parse certificate: %w", err)
+	}
+	return ca, tls.Certificate{
+		Certificate: [][]byte{der, caDER},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
@@ -0,0 +1,55 @@
+package wine_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gopub/wine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestServer_RunTLS(t *testing.T) {
+	s := wine.NewTestServer(t)
+	s.Get("login", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.Handle(req.Request(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Strict-Transport-Security", "max-age=31536000")
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "123", Path: "/", Secure: true, HttpOnly: true})
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	})
+	s.Get("home", func(ctx context.Context, req *wine.Request) wine.Responder {
+		return wine.Redirect("/profile", false)
+	})
+	s.Get("profile", func(ctx context.Context, req *wine.Request) wine.Responder {
+		c, err := req.Request().Cookie("sid")
+		if err != nil {
+			return wine.Status(http.StatusUnauthorized)
+		}
+		return wine.Text(http.StatusOK, c.Value)
+	})
+	url := s.RunTLS()
+	require.Contains(t, url, "https://127.0.0.1:")
+
+	t.Run("UntrustedClient", func(t *testing.T) {
+		_, err := http.DefaultClient.Get(url + "/login")
+		require.Error(t, err)
+	})
+
+	client := s.Client().HTTPClient()
+	resp, err := client.Get(url + "/login")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.NotNil(t, resp.TLS)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, "max-age=31536000", resp.Header.Get("Strict-Transport-Security"))
+
+	resp, err = client.Get(url + "/home")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "123", string(b))
+}
@@ -0,0 +1,205 @@
+// Package trace implements W3C Trace Context propagation and spans
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Propagated fields defined by W3C Trace Context
+const (
+	Traceparent = "traceparent"
+	Tracestate  = "tracestate"
+)
+
+const (
+	traceparentVersion = "00"
+	traceparentLen     = 55
+)
+
+type TraceID [16]byte
+
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// ParseTraceID parses 32 lowercase hex digits
+func ParseTraceID(s string) (TraceID, error) {
+	var t TraceID
+	if err := decodeHex(s, t[:]); err != nil {
+		return t, fmt.Errorf("parse trace id: %w", err)
+	}
+	if !t.IsValid() {
+		return t, errors.New("all zero trace id")
+	}
+	return t, nil
+}
+
+type SpanID [8]byte
+
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+type Flags byte
+
+const FlagsSampled Flags = 0x01
+
+// SpanContext is the part of span which is propagated across processes
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      Flags
+	TraceState string
+}
+
+func (c SpanContext) IsValid() bool {
+	return c.TraceID.IsValid() && c.SpanID.IsValid()
+}
+
+func (c SpanContext) IsSampled() bool {
+	return c.Flags&FlagsSampled != 0
+}
+
+// Traceparent returns value of traceparent header, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func (c SpanContext) Traceparent() string {
+	return fmt.Sprintf("%s-%s-%s-%02x", traceparentVersion, c.TraceID, c.SpanID, byte(c.Flags))
+}
+
+// ParseTraceparent parses value of traceparent header.
+// Fields of future versions are ignored as required by the specification
+func ParseTraceparent(s string) (SpanContext, error) {
+	var c SpanContext
+	if len(s) < traceparentLen {
+		return c, errors.New("traceparent is too short")
+	}
+	version := s[:2]
+	if version == "ff" {
+		return c, errors.New("invalid traceparent version")
+	}
+	if version == traceparentVersion && len(s) != traceparentLen {
+		return c, errors.New("invalid traceparent length")
+	}
+	if len(s) > traceparentLen && s[traceparentLen] != '-' {
+		return c, errors.New("invalid traceparent format")
+	}
+	if s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return c, errors.New("invalid traceparent format")
+	}
+	var v [1]byte
+	if err := decodeHex(version, v[:]); err != nil {
+		return c, fmt.Errorf("parse version: %w", err)
+	}
+	if err := decodeHex(s[3:35], c.TraceID[:]); err != nil {
+		return c, fmt.Errorf("parse trace id: %w", err)
+	}
+	if err := decodeHex(s[36:52], c.SpanID[:]); err != nil {
+		return c, fmt.Errorf("parse parent id: %w", err)
+	}
+	var flags [1]byte
+	if err := decodeHex(s[53:55], flags[:]); err != nil {
+		return c, fmt.Errorf("parse flags: %w", err)
+	}
+	c.Flags = Flags(flags[0])
+	if !c.IsValid() {
+		return c, errors.New("all zero trace id or parent id")
+	}
+	return c, nil
+}
+
+func decodeHex(s string, b []byte) error {
+	if len(s) != hex.EncodedLen(len(b)) {
+		return fmt.Errorf("expect %d hex digits", hex.EncodedLen(len(b)))
+	}
+	if strings.ToLower(s) != s {
+		return errors.New("uppercase hex digits")
+	}
+	_, err := hex.Decode(b, []byte(s))
+	return err
+}
+
+// Carrier carries propagated fields, e.g. http.Header, websocket call metadata
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+// MapCarrier adapts map to Carrier
+type MapCarrier map[string]string
+
+func (c MapCarrier) Get(key string) string {
+	return c[key]
+}
+
+func (c MapCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+// Extract extracts span context from carrier, ok is false if it's absent or invalid
+func Extract(carrier Carrier) (c SpanContext, ok bool) {
+	s := strings.TrimSpace(carrier.Get(Traceparent))
+	if s == "" {
+		return c, false
+	}
+	c, err := ParseTraceparent(s)
+	if err != nil {
+		return c, false
+	}
+	c.TraceState = carrier.Get(Tracestate)
+	return c, true
+}
+
+// Inject injects span context of ctx into carrier
+func Inject(ctx context.Context, carrier Carrier) {
+	c := SpanContextFromContext(ctx)
+	if !c.IsValid() {
+		return
+	}
+	carrier.Set(Traceparent, c.Traceparent())
+	if c.TraceState != "" {
+		carrier.Set(Tracestate, c.TraceState)
+	}
+}
+
+type contextKey int
+
+const (
+	keySpan contextKey = iota
+	keyRemoteSpanContext
+)
+
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, keySpan, s)
+}
+
+// SpanFromContext returns span in ctx, or nil if there is none
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(keySpan).(*Span)
+	return s
+}
+
+// ContextWithRemoteSpanContext returns a context with span context from remote, e.g. extracted from http header.
+// Spans started with the returned context are children of the remote span
+func ContextWithRemoteSpanContext(ctx context.Context, c SpanContext) context.Context {
+	return context.WithValue(ctx, keyRemoteSpanContext, c)
+}
+
+// SpanContextFromContext returns context of the current span, or remote span context if there is no span in ctx
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.Context()
+	}
+	c, _ := ctx.Value(keyRemoteSpanContext).(SpanContext)
+	return c
+}
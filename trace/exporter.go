@@ -0,0 +1,47 @@
+package trace
+
+import (
+	"sync"
+)
+
+// Exporter exports ended spans, e.g. to a tracing backend
+type Exporter interface {
+	ExportSpan(s *SpanData)
+}
+
+type ExporterFunc func(s *SpanData)
+
+func (f ExporterFunc) ExportSpan(s *SpanData) {
+	f(s)
+}
+
+// MemoryExporter keeps spans in memory, it's useful in tests
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []*SpanData
+}
+
+var _ Exporter = (*MemoryExporter)(nil)
+
+func NewMemoryExporter() *MemoryExporter {
+	return &MemoryExporter{}
+}
+
+func (e *MemoryExporter) ExportSpan(s *SpanData) {
+	e.mu.Lock()
+	e.spans = append(e.spans, s)
+	e.mu.Unlock()
+}
+
+// Spans returns exported spans in order of ending
+func (e *MemoryExporter) Spans() []*SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*SpanData(nil), e.spans...)
+}
+
+func (e *MemoryExporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
@@ -0,0 +1,138 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = iota
+	SpanKindServer
+	SpanKindClient
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKindServer:
+		return "server"
+	case SpanKindClient:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+// SpanData is the snapshot of an ended span, which is passed to exporters
+type SpanData struct {
+	Name         string
+	Kind         SpanKind
+	Context      SpanContext
+	ParentSpanID SpanID
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   map[string]interface{}
+	Error        string
+}
+
+// Span represents an operation within a trace
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+func (s *Span) Context() SpanContext {
+	return s.data.Context
+}
+
+// SetName updates name, e.g. after route is matched
+func (s *Span) SetName(name string) {
+	s.mu.Lock()
+	s.data.Name = name
+	s.mu.Unlock()
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]interface{})
+	}
+	s.data.Attributes[key] = value
+	s.mu.Unlock()
+}
+
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Error = err.Error()
+	s.mu.Unlock()
+}
+
+// End ends s and exports it if it's sampled. Only the first call takes effect
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	d := s.data
+	if len(s.data.Attributes) > 0 {
+		d.Attributes = make(map[string]interface{}, len(s.data.Attributes))
+		for k, v := range s.data.Attributes {
+			d.Attributes[k] = v
+		}
+	}
+	s.mu.Unlock()
+
+	if e := s.tracer.exporter; e != nil && d.Context.IsSampled() {
+		e.ExportSpan(&d)
+	}
+}
+
+// Tracer starts spans and exports them once they end
+type Tracer struct {
+	exporter Exporter
+}
+
+// NewTracer returns a tracer which exports spans by exporter.
+// Spans are still created and propagated if exporter is nil
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{
+		exporter: exporter,
+	}
+}
+
+// Start starts a span which is child of span or remote span context in ctx, or root span of a new trace
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	s := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:      name,
+			Kind:      kind,
+			StartTime: time.Now(),
+		},
+	}
+	c := &s.data.Context
+	if parent.TraceID.IsValid() {
+		c.TraceID = parent.TraceID
+		c.Flags = parent.Flags
+		c.TraceState = parent.TraceState
+		s.data.ParentSpanID = parent.SpanID
+	} else {
+		rand.Read(c.TraceID[:])
+		c.Flags = FlagsSampled
+	}
+	rand.Read(c.SpanID[:])
+	return ContextWithSpan(ctx, s), s
+}
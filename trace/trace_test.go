@@ -0,0 +1,91 @@
+package trace_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gopub/wine/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		s := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		c, err := trace.ParseTraceparent(s)
+		require.NoError(t, err)
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", c.TraceID.String())
+		require.Equal(t, "00f067aa0ba902b7", c.SpanID.String())
+		require.True(t, c.IsSampled())
+		require.Equal(t, s, c.Traceparent())
+	})
+
+	t.Run("FutureVersion", func(t *testing.T) {
+		c, err := trace.ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+		require.NoError(t, err)
+		require.False(t, c.IsSampled())
+	})
+
+	for _, s := range []string{
+		"",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01",
+	} {
+		_, err := trace.ParseTraceparent(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	exporter := trace.NewMemoryExporter()
+	tracer := trace.NewTracer(exporter)
+
+	t.Run("Propagation", func(t *testing.T) {
+		exporter.Reset()
+		ctx, root := tracer.Start(context.Background(), "root", trace.SpanKindServer)
+		require.True(t, root.Context().IsValid())
+		require.True(t, root.Context().IsSampled())
+
+		h := make(http.Header)
+		trace.Inject(ctx, h)
+		remote, ok := trace.Extract(h)
+		require.True(t, ok)
+		require.Equal(t, root.Context(), remote)
+
+		md := make(trace.MapCarrier)
+		trace.Inject(ctx, md)
+		require.Equal(t, h.Get(trace.Traceparent), md[trace.Traceparent])
+
+		ctx = trace.ContextWithRemoteSpanContext(context.Background(), remote)
+		_, child := tracer.Start(ctx, "child", trace.SpanKindServer)
+		child.SetError(errors.New("failed"))
+		child.End()
+		child.End()
+		root.End()
+
+		spans := exporter.Spans()
+		require.Len(t, spans, 2)
+		require.Equal(t, "child", spans[0].Name)
+		require.Equal(t, "failed", spans[0].Error)
+		require.Equal(t, remote.TraceID, spans[0].Context.TraceID)
+		require.Equal(t, remote.SpanID, spans[0].ParentSpanID)
+		require.NotEqual(t, remote.SpanID, spans[0].Context.SpanID)
+		require.False(t, spans[1].ParentSpanID.IsValid())
+	})
+
+	t.Run("NotSampled", func(t *testing.T) {
+		exporter.Reset()
+		remote, err := trace.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+		require.NoError(t, err)
+		ctx := trace.ContextWithRemoteSpanContext(context.Background(), remote)
+		ctx, span := tracer.Start(ctx, "span", trace.SpanKindInternal)
+		require.Equal(t, remote.TraceID, trace.SpanContextFromContext(ctx).TraceID)
+		span.End()
+		require.Empty(t, exporter.Spans())
+	})
+}
@@ -0,0 +1,38 @@
+package websocket
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine/ctxutil"
+	"github.com/gopub/wine/httpvalue"
+	"github.com/gopub/wine/jwt"
+)
+
+// NewJWTAuthHandler returns an interceptor which authenticates bearer token in Authorization of metadata or
+// handshake header by v. It's usually used as Server.PreHandler.
+// Calls without token are passed on, so that endpoints can require auth by Router.RequireAuth
+func NewJWTAuthHandler(v *jwt.Verifier) HandlerFunc {
+	if v == nil {
+		logger.Panic("Verifier cannot be nil")
+	}
+	return func(ctx context.Context, params interface{}) (interface{}, error) {
+		c := GetServerConn(ctx)
+		if c == nil {
+			return Next(ctx, params)
+		}
+		a := c.GetValue(httpvalue.Authorization)
+		if !strings.HasPrefix(a, "Bearer ") {
+			return Next(ctx, params)
+		}
+		token := strings.TrimPrefix(a, "Bearer ")
+		t, err := v.Verify(ctx, token)
+		if err != nil {
+			return nil, errors.Unauthorized("invalid token: %v", err)
+		}
+		ctx = ctxutil.WithUser(ctx, t.Claims)
+		ctx = ctxutil.WithUserID(ctx, t.Claims.GetID())
+		return Next(ctx, params)
+	}
+}
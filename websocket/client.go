@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/gopub/errors"
+	"github.com/gopub/wine/trace"
 	"github.com/gorilla/websocket"
 )
 
@@ -216,7 +217,7 @@ func (c *Client) write(done <-chan struct{}) {
 				next := it.Next()
 				c.calls.Remove(it)
 				it = next
-				if err := c.conn.Call(ca.Id, ca.Name, ca.Data); err != nil {
+				if err := c.conn.WriteCall(ca); err != nil {
 					if c.state == Connected {
 						logger.Errorf("Cannot call %s: %v", ca.Name, err)
 					}
@@ -256,6 +257,11 @@ func (c *Client) Call(ctx context.Context, name string, params interface{}, resu
 	if err != nil {
 		return fmt.Errorf("cannot create call object: %w", err)
 	}
+	md := make(trace.MapCarrier)
+	trace.Inject(ctx, md)
+	if len(md) > 0 {
+		ca.Metadata = md
+	}
 	replyC := make(chan *Reply, 1)
 	c.mu.Lock()
 	c.calls.PushBack(ca)
@@ -63,11 +63,28 @@ func (c *Conn) Write(p *Packet) error {
 	return errors.Wrapf(err, "write binary message")
 }
 
+// WriteClose sends close frame with code and text. The peer is expected to reply close frame and close the connection
+func (c *Conn) WriteClose(code int, text string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return errors.New("cannot write to a closed conn")
+	}
+	msg := websocket.FormatCloseMessage(code, text)
+	err := c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(c.writeTimeout))
+	return errors.Wrapf(err, "write close message")
+}
+
 func (c *Conn) Call(id int32, name string, params interface{}) error {
 	ca, err := NewCall(id, name, params)
 	if err != nil {
 		return err
 	}
+	return c.WriteCall(ca)
+}
+
+// WriteCall writes ca as it is, including its metadata
+func (c *Conn) WriteCall(ca *Call) error {
 	return c.Write(&Packet{V: &Packet_Call{ca}})
 }
 
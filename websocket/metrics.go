@@ -0,0 +1,55 @@
+package websocket
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine/metrics"
+)
+
+type serverMetrics struct {
+	conns    *metrics.GaugeFunc
+	calls    *metrics.CounterVec
+	duration *metrics.HistogramVec
+}
+
+func newServerMetrics(s *Server) *serverMetrics {
+	return &serverMetrics{
+		conns: metrics.NewGaugeFunc("wine_websocket_connections", "Number of active websocket connections.", func() float64 {
+			return float64(s.NumConns())
+		}),
+		calls: metrics.NewCounterVec("wine_websocket_calls_total", "Total number of websocket calls.",
+			"route", "status"),
+		duration: metrics.NewHistogramVec("wine_websocket_call_duration_seconds", "Websocket call latencies in seconds.",
+			metrics.DefBuckets, "route", "status"),
+	}
+}
+
+// RegisterMetrics registers connection and call metrics into r, e.g. wine.Server.Metrics()
+func (s *Server) RegisterMetrics(r *metrics.Registry) error {
+	for _, c := range []metrics.Collector{s.metrics.conns, s.metrics.calls, s.metrics.duration} {
+		if err := r.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) observeCall(req *Request, err error, startAt time.Time) {
+	status := http.StatusOK
+	if err != nil {
+		status = errors.GetCode(err)
+		if status <= 0 {
+			status = http.StatusInternalServerError
+		}
+	}
+	route := req.route
+	if route != "" {
+		route = "/" + route
+	}
+	code := strconv.Itoa(status)
+	s.metrics.calls.With(route, code).Inc()
+	s.metrics.duration.With(route, code).Observe(time.Since(startAt).Seconds())
+}
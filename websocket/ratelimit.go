@@ -0,0 +1,87 @@
+package websocket
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gopub/errors"
+	"github.com/gopub/wine/ctxutil"
+	"github.com/gopub/wine/httpvalue"
+	"github.com/gopub/wine/ratelimit"
+)
+
+// RateLimitKeyFunc returns key which calls are limited by. Calls with empty key aren't limited
+type RateLimitKeyFunc func(ctx context.Context, req *Request) string
+
+// RateLimitByIP limits calls by ip of remote address
+func RateLimitByIP(_ context.Context, req *Request) string {
+	if req.remoteAddr == nil {
+		return ""
+	}
+	addr := req.remoteAddr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return "ip:" + addr
+}
+
+// RateLimitByUserID limits calls by id of authenticated user
+func RateLimitByUserID(ctx context.Context, _ *Request) string {
+	if id := ctxutil.GetUserID(ctx); id > 0 {
+		return "user:" + strconv.FormatInt(id, 10)
+	}
+	return ""
+}
+
+// RateLimitByAppID limits calls by X-Wine-App-Id in metadata or handshake header
+func RateLimitByAppID(ctx context.Context, _ *Request) string {
+	if c := GetServerConn(ctx); c != nil {
+		if id := c.GetValue(httpvalue.CustomAppID); id != "" {
+			return "app:" + id
+		}
+	}
+	return ""
+}
+
+// RateLimitByDeviceID limits calls by X-Wine-Device-Id in metadata or handshake header
+func RateLimitByDeviceID(ctx context.Context, _ *Request) string {
+	if c := GetServerConn(ctx); c != nil {
+		if id := c.GetValue(httpvalue.CustomDeviceID); id != "" {
+			return "device:" + id
+		}
+	}
+	return ""
+}
+
+// NewRateLimitHandler returns an interceptor which rejects calls exceeding limits with 429 errors.
+// Calls are limited separately if limiter has limits of their names, e.g. "auth.login".
+// Calls are allowed if limiter fails, e.g. shared store is unavailable
+func NewRateLimitHandler(l *ratelimit.Limiter, key RateLimitKeyFunc) HandlerFunc {
+	if l == nil || key == nil {
+		logger.Panic("Limiter and key func cannot be nil")
+	}
+	return func(ctx context.Context, params interface{}) (interface{}, error) {
+		req := GetRequest(ctx)
+		if req == nil {
+			return Next(ctx, params)
+		}
+		k := key(ctx, req)
+		if k == "" {
+			return Next(ctx, params)
+		}
+		res, err := l.Allow(ctx, req.route, k)
+		if err != nil {
+			logger.Errorf("Rate limit %s: %v", k, err)
+			return Next(ctx, params)
+		}
+		if !res.Allowed {
+			retry := time.Duration(math.Ceil(res.RetryAfter.Seconds())) * time.Second
+			return nil, errors.Format(http.StatusTooManyRequests, "too many requests, retry after %v", retry)
+		}
+		return Next(ctx, params)
+	}
+}
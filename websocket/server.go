@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gopub/environ"
@@ -16,6 +17,7 @@ import (
 	"github.com/gopub/wine"
 	"github.com/gopub/wine/ctxutil"
 	"github.com/gopub/wine/router"
+	"github.com/gopub/wine/trace"
 	"github.com/gorilla/websocket"
 )
 
@@ -23,9 +25,12 @@ type Request struct {
 	ID   int32
 	Name string
 	Data *Data
+	// Metadata of the call, e.g. traceparent
+	Metadata map[string]string
 
 	// server side
 	remoteAddr net.Addr
+	route      string
 	Model      interface{}
 }
 
@@ -84,15 +89,22 @@ func (c *serverConn) GetValue(key string) string {
 
 // Server implements websocket server
 type Server struct {
+	numConns int64 // accessed atomically, keep it 64-bit aligned
+	closing  int32
+
 	websocket.Upgrader
 	*Router
 	readTimeout time.Duration
 	timeout     time.Duration
 	PreHandler  Handler
 	conns       sync.Map // id:map[conn]bool
+	activeConns sync.Map // conn:bool, including connections without id
 	Handshake   func(rw PacketReadWriter) error
 	CallLogger  func(req *Request, resultOrErr interface{}, cost time.Duration)
 	Recovery    bool
+	// Tracer starts a server span for each call, which continues the trace in call metadata
+	Tracer  *trace.Tracer
+	metrics *serverMetrics
 }
 
 // Server implements http.Handler in order to take over http conn and upgrade to websocket conn
@@ -105,7 +117,9 @@ func NewServer() *Server {
 		timeout:     environ.Duration("wine.timeout", 10*time.Second),
 		CallLogger:  logCall,
 		Recovery:    environ.Bool("wine.recovery", true),
+		Tracer:      trace.NewTracer(nil),
 	}
+	s.metrics = newServerMetrics(s)
 	return s
 }
 
@@ -119,6 +133,11 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}()
 	}
 
+	if atomic.LoadInt32(&s.closing) != 0 {
+		wine.Status(http.StatusServiceUnavailable).Respond(r.Context(), w)
+		return
+	}
+
 	wconn, err := s.Upgrade(w, r, nil)
 	if err != nil {
 		wine.Error(err).Respond(r.Context(), w)
@@ -129,6 +148,12 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		header:   r.Header,
 		metadata: map[string]string{},
 	}
+	atomic.AddInt64(&s.numConns, 1)
+	s.activeConns.Store(conn, true)
+	defer func() {
+		s.activeConns.Delete(conn)
+		atomic.AddInt64(&s.numConns, -1)
+	}()
 	conn.readTimeout = s.readTimeout
 	logger.Debugf("New conn %s", wconn.RemoteAddr())
 	if s.Handshake != nil {
@@ -152,6 +177,7 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			req.ID = v.Call.Id
 			req.Name = v.Call.Name
 			req.Data = v.Call.Data
+			req.Metadata = v.Call.Metadata
 			req.remoteAddr = wconn.RemoteAddr()
 			go s.HandleRequest(conn, req)
 		case *Packet_Metadata:
@@ -174,6 +200,40 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// NumConns returns the number of active connections
+func (s *Server) NumConns() int64 {
+	return atomic.LoadInt64(&s.numConns)
+}
+
+// Shutdown rejects new connections, sends close frame to all connections and waits for them to be closed by clients.
+// Connections which are still open when ctx is done will be closed forcibly
+func (s *Server) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&s.closing, 1)
+	s.activeConns.Range(func(key, value interface{}) bool {
+		conn := key.(*serverConn)
+		if err := conn.WriteClose(websocket.CloseGoingAway, "server is shutting down"); err != nil {
+			logger.Errorf("Cannot write close frame: %v", err)
+		}
+		return true
+	})
+
+	t := time.NewTicker(10 * time.Millisecond)
+	defer t.Stop()
+	for s.NumConns() > 0 {
+		select {
+		case <-t.C:
+			break
+		case <-ctx.Done():
+			s.activeConns.Range(func(key, value interface{}) bool {
+				key.(*serverConn).Close()
+				return true
+			})
+			return fmt.Errorf("wait for %d connections: %w", s.NumConns(), ctx.Err())
+		}
+	}
+	return nil
+}
+
 func (s *Server) deleteConn(conn *serverConn) {
 	conns, ok := s.conns.Load(conn.id)
 	if !ok {
@@ -206,6 +266,7 @@ func (s *Server) HandleRequest(conn *serverConn, req *Request) {
 	if s.Recovery {
 		defer func() {
 			if e := recover(); e != nil {
+				s.observeCall(req, fmt.Errorf("panic: %v", e), startAt)
 				s.logCall(req, e, startAt)
 				logger.Errorf("\n%s\n", string(debug.Stack()))
 			}
@@ -214,8 +275,22 @@ func (s *Server) HandleRequest(conn *serverConn, req *Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
 	defer cancel()
 	ctx = conn.buildContext(ctx)
+	var span *trace.Span
+	if s.Tracer != nil {
+		if sc, ok := trace.Extract(trace.MapCarrier(req.Metadata)); ok {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+		}
+		ctx, span = s.Tracer.Start(ctx, req.Name, trace.SpanKindServer)
+		defer span.End()
+	}
 	var resultOrErr interface{}
 	result, err := s.Handle(ctx, req)
+	if span != nil {
+		if req.route != "" {
+			span.SetName("/" + req.route)
+		}
+		span.SetError(err)
+	}
 	if err != nil {
 		resultOrErr = err
 	} else {
@@ -234,6 +309,7 @@ func (s *Server) HandleRequest(conn *serverConn, req *Request) {
 		}
 	}
 
+	s.observeCall(req, err, startAt)
 	if err = conn.Reply(req.ID, resultOrErr); err != nil {
 		logger.Errorf("Cannot write reply: %v", err)
 		conn.Close()
@@ -246,6 +322,8 @@ func (s *Server) Handle(ctx context.Context, req *Request) (interface{}, error)
 	if r == nil {
 		return nil, errors.NotFound("")
 	}
+	req.route = r.Path()
+	ctx = withRequest(ctx, req)
 
 	if err := req.bind(r.Model()); err != nil {
 		return nil, fmt.Errorf("cannot bind model %T: %w", r.Model(), err)
@@ -197,6 +197,7 @@ const (
 	ckNextHandler contextKey = iota + 1
 	ckAuthFlag
 	ckServerConn
+	ckRequest
 )
 
 func GetServerConn(ctx context.Context) *serverConn {
@@ -207,3 +208,13 @@ func GetServerConn(ctx context.Context) *serverConn {
 func withServerConn(ctx context.Context, c *serverConn) context.Context {
 	return context.WithValue(ctx, ckServerConn, c)
 }
+
+// GetRequest returns the call being handled, which is useful for interceptors
+func GetRequest(ctx context.Context) *Request {
+	r, _ := ctx.Value(ckRequest).(*Request)
+	return r
+}
+
+func withRequest(ctx context.Context, r *Request) context.Context {
+	return context.WithValue(ctx, ckRequest, r)
+}
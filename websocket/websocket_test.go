@@ -11,7 +11,10 @@ import (
 	"time"
 
 	"github.com/gopub/conv"
+	"github.com/gopub/errors"
 	"github.com/gopub/types"
+	"github.com/gopub/wine/ratelimit"
+	"github.com/gopub/wine/trace"
 	"github.com/gopub/wine/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -146,3 +149,52 @@ func TestRouter_BindModel(t *testing.T) {
 	require.Equal(t, 10, res.Value)
 	time.Sleep(time.Second)
 }
+
+func TestServer_Trace(t *testing.T) {
+	addr := fmt.Sprintf("localhost:%d", 1024+rand.Int()%10000)
+	exporter := trace.NewMemoryExporter()
+	s := websocket.NewServer()
+	s.Tracer = trace.NewTracer(exporter)
+	s.Bind("trace", func(ctx context.Context, req interface{}) (interface{}, error) {
+		return trace.SpanContextFromContext(ctx).TraceID.String(), nil
+	})
+	go func() {
+		err := http.ListenAndServe(addr, s)
+		require.NoError(t, err)
+	}()
+	runtime.Gosched()
+	c := websocket.NewClient("ws://"+addr, nil)
+	ctx, span := trace.NewTracer(nil).Start(context.Background(), "client", trace.SpanKindClient)
+	var traceID string
+	err := c.Call(ctx, "trace", nil, &traceID)
+	require.NoError(t, err)
+	require.Equal(t, span.Context().TraceID.String(), traceID)
+	require.Eventually(t, func() bool {
+		return len(exporter.Spans()) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, "/trace", exporter.Spans()[0].Name)
+	require.Equal(t, span.Context().SpanID, exporter.Spans()[0].ParentSpanID)
+}
+
+func TestRateLimitHandler(t *testing.T) {
+	addr := fmt.Sprintf("localhost:%d", 1024+rand.Int()%10000)
+	s := websocket.NewServer()
+	l := ratelimit.NewLimiter(ratelimit.PerMinute(10), nil)
+	l.SetLimit("ping", ratelimit.PerMinute(1))
+	s.Use(websocket.NewRateLimitHandler(l, websocket.RateLimitByIP)).Bind("ping", func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "pong", nil
+	})
+	go func() {
+		err := http.ListenAndServe(addr, s)
+		require.NoError(t, err)
+	}()
+	runtime.Gosched()
+	c := websocket.NewClient("ws://"+addr, nil)
+	var res string
+	err := c.Call(context.Background(), "ping", nil, &res)
+	require.NoError(t, err)
+	require.Equal(t, "pong", res)
+	err = c.Call(context.Background(), "ping", nil, &res)
+	require.Error(t, err)
+	require.Equal(t, http.StatusTooManyRequests, errors.GetCode(err))
+}